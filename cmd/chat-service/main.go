@@ -1,77 +1,330 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/gabrielmq/chat-service/configs"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+	"github.com/gabrielmq/chat-service/internal/infra/attachmentstore"
+	"github.com/gabrielmq/chat-service/internal/infra/embedder"
+	"github.com/gabrielmq/chat-service/internal/infra/embeddingcache"
+	"github.com/gabrielmq/chat-service/internal/infra/generations"
 	"github.com/gabrielmq/chat-service/internal/infra/grpc/server"
+	"github.com/gabrielmq/chat-service/internal/infra/health"
 	"github.com/gabrielmq/chat-service/internal/infra/repositories"
+	"github.com/gabrielmq/chat-service/internal/infra/summarizer"
+	"github.com/gabrielmq/chat-service/internal/infra/toolregistry"
 	"github.com/gabrielmq/chat-service/internal/infra/web"
 	"github.com/gabrielmq/chat-service/internal/infra/web/webserver"
+	"github.com/gabrielmq/chat-service/internal/tools"
+	"github.com/gabrielmq/chat-service/internal/usecase/attachdocument"
+	"github.com/gabrielmq/chat-service/internal/usecase/cancelcompletion"
 	"github.com/gabrielmq/chat-service/internal/usecase/chatcompletion"
 	"github.com/gabrielmq/chat-service/internal/usecase/chatcompletionstream"
+	"github.com/gabrielmq/chat-service/internal/usecase/chatconfig"
+	"github.com/gabrielmq/chat-service/internal/usecase/chathistory"
+	"github.com/gabrielmq/chat-service/internal/usecase/commitchoice"
+	"github.com/gabrielmq/chat-service/internal/usecase/deleteassistant"
+	"github.com/gabrielmq/chat-service/internal/usecase/deletetemplate"
+	"github.com/gabrielmq/chat-service/internal/usecase/downloadattachment"
+	"github.com/gabrielmq/chat-service/internal/usecase/embeddings"
+	"github.com/gabrielmq/chat-service/internal/usecase/forkchat"
+	"github.com/gabrielmq/chat-service/internal/usecase/getassistant"
+	"github.com/gabrielmq/chat-service/internal/usecase/gettemplate"
+	"github.com/gabrielmq/chat-service/internal/usecase/listassistants"
+	"github.com/gabrielmq/chat-service/internal/usecase/listbranches"
+	"github.com/gabrielmq/chat-service/internal/usecase/listchats"
+	"github.com/gabrielmq/chat-service/internal/usecase/listdocuments"
+	"github.com/gabrielmq/chat-service/internal/usecase/listtemplates"
+	"github.com/gabrielmq/chat-service/internal/usecase/saveassistant"
+	"github.com/gabrielmq/chat-service/internal/usecase/savetemplate"
+	"github.com/gabrielmq/chat-service/internal/usecase/switchbranch"
+	"github.com/gabrielmq/chat-service/internal/usecase/uploadattachment"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/sashabaranov/go-openai"
 )
 
 func main() {
-	configs, err := configs.LoadConfig(".")
+	if len(os.Args) > 2 && os.Args[1] == "config" && os.Args[2] == "validate" {
+		os.Exit(validateConfig(os.Args[3:]))
+	}
+
+	if err := run(os.Args[1:]); err != nil {
+		log.Fatal(configs.Describe(err))
+	}
+}
+
+// run serves the gRPC and HTTP APIs until SIGINT or SIGTERM is received or a
+// server fails, then shuts both down and closes the database.
+func run(args []string) (err error) {
+	cfg, err := configs.LoadConfig(args, tools.Names())
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	conn, err := sql.Open(configs.DBDriver, fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&multiStatements=true",
-		configs.DBUser, configs.DBPassword, configs.DBHost, configs.DBPort, configs.DBName))
+	conn, err := sql.Open(cfg.DB.Driver, fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&multiStatements=true",
+		cfg.DB.User, cfg.DB.Password, cfg.DB.Host, cfg.DB.Port, cfg.DB.Name))
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer conn.Close()
+	// closed once the servers are stopped, no request uses it anymore
+	defer func() {
+		if closeErr := conn.Close(); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("closing the database: %w", closeErr))
+		}
+	}()
 
 	repo := repositories.NewChatRepositoryMySQL(conn)
-	client := openai.NewClient(configs.OpenAIApiKey)
-
-	chatConfig := chatcompletion.ChatCompletionConfigurationInput{
-		Model:                configs.Model,
-		ModelMaxTokens:       configs.ModelMaxTokens,
-		Temperature:          float32(configs.Temperature),
-		TopP:                 float32(configs.TopP),
-		N:                    configs.N,
-		Stop:                 configs.Stop,
-		MaxTokens:            configs.MaxTokens,
-		InitialSystemMessage: configs.InitialChatMessage,
-	}
-
-	chatConfigStream := chatcompletionstream.ChatCompletionConfigurationInput{
-		Model:                configs.Model,
-		ModelMaxTokens:       configs.ModelMaxTokens,
-		Temperature:          float32(configs.Temperature),
-		TopP:                 float32(configs.TopP),
-		N:                    configs.N,
-		Stop:                 configs.Stop,
-		MaxTokens:            configs.MaxTokens,
-		InitialSystemMessage: configs.InitialChatMessage,
-	}
-
-	streamChannel := make(chan chatcompletionstream.ChatCompletionOutput)
-	usecaseStream := chatcompletionstream.NewChatCompletionUseCase(repo, client, streamChannel)
-
-	log.Println("Starting gRPC server on port " + configs.GRPCServerPort)
+	models, err := repositories.NewModelRepositoryFile(cfg.Providers.ModelsFile)
+	if err != nil {
+		return err
+	}
+	client := openai.NewClient(cfg.Providers.OpenAI.APIKey)
+	summarizer := summarizer.NewSummarizerOpenAI(client)
+	documents := repositories.NewDocumentRepositoryMySQL(conn)
+	embedder := embedder.NewEmbedderOpenAI(client, cfg.Embeddings.Model)
+	attachments := repositories.NewAttachmentRepositoryMySQL(conn)
+	templates := repositories.NewPromptTemplateRepositoryMySQL(conn)
+	assistants := repositories.NewAssistantRepositoryMySQL(conn)
+	var storage gateway.AttachmentStorageGateway
+	switch cfg.Attachments.Storage {
+	case "s3":
+		storage, err = attachmentstore.NewAttachmentStoreS3(http.DefaultClient, cfg.Attachments.S3.Endpoint, cfg.Attachments.S3.Region, cfg.Attachments.S3.Bucket, cfg.Attachments.S3.AccessKey, cfg.Attachments.S3.SecretKey)
+	case "filesystem", "":
+		storage, err = attachmentstore.NewAttachmentStoreFilesystem(cfg.Attachments.Dir)
+	default:
+		err = fmt.Errorf("unknown attachment storage %q", cfg.Attachments.Storage)
+	}
+	if err != nil {
+		return err
+	}
+	toolRegistry := toolregistry.NewToolRegistryInMemory()
+	for _, tool := range tools.Builtin(http.DefaultClient, cfg.Tools.FetchAllowedHosts, cfg.Limits.FetchBytes) {
+		if err := toolRegistry.Register(tool); err != nil {
+			return err
+		}
+	}
+
+	chatConfig := chatconfig.NewConfigurationStore(newChatConfig(cfg))
+	embeddingsConfig := embeddings.NewConfigurationStore(newEmbeddingsConfig(cfg))
+	// the model registry and the defaults, limits and system message of new
+	// chats follow the configuration file, everything else needs a restart
+	err = configs.WatchConfig(cfg, func(reloaded, previous *configs.Config) error {
+		if err := models.Reload(reloaded.Providers.ModelsFile); err != nil {
+			return err
+		}
+		chatConfig.Store(newChatConfig(reloaded))
+		embeddingsConfig.Store(newEmbeddingsConfig(reloaded))
+		if changed := reloaded.RestartRequired(previous); len(changed) > 0 {
+			log.Printf("Configuration reloaded, restart to apply %s", strings.Join(changed, ", "))
+			return nil
+		}
+		log.Println("Configuration reloaded")
+		return nil
+	}, func(err error) {
+		log.Printf("Configuration not reloaded: %v", err)
+	})
+	if err != nil {
+		return err
+	}
+
+	generations := generations.NewGenerationRegistryInMemory()
+	usecaseStream := chatcompletionstream.NewChatCompletionUseCase(repo, models, summarizer, generations, toolRegistry, documents, embedder, attachments, storage, templates, assistants, client)
+	usecaseCancel := cancelcompletion.NewCancelCompletionUseCase(generations)
+	usecaseHistory := chathistory.NewChatHistoryUseCase(repo)
+	usecaseListChats := listchats.NewListChatsUseCase(repo)
+	usecaseListBranches := listbranches.NewListBranchesUseCase(repo)
+	usecaseSwitchBranch := switchbranch.NewSwitchBranchUseCase(repo, models, summarizer)
+	usecaseForkChat := forkchat.NewForkChatUseCase(repo, models, summarizer, attachments)
+	usecaseCommitChoice := commitchoice.NewCommitChoiceUseCase(repo, models, summarizer)
+	usecaseAttachDocument := attachdocument.NewAttachDocumentUseCase(repo, documents, embedder)
+	usecaseListDocuments := listdocuments.NewListDocumentsUseCase(repo, documents)
+	usecaseUploadAttachment := uploadattachment.NewUploadAttachmentUseCase(repo, attachments, storage)
+	usecaseDownloadAttachment := downloadattachment.NewDownloadAttachmentUseCase(attachments, storage)
+	usecaseSaveTemplate := savetemplate.NewSaveTemplateUseCase(templates)
+	usecaseGetTemplate := gettemplate.NewGetTemplateUseCase(templates)
+	usecaseListTemplates := listtemplates.NewListTemplatesUseCase(templates)
+	usecaseDeleteTemplate := deletetemplate.NewDeleteTemplateUseCase(templates)
+	usecaseSaveAssistant := saveassistant.NewSaveAssistantUseCase(assistants, models, toolRegistry)
+	usecaseGetAssistant := getassistant.NewGetAssistantUseCase(assistants)
+	usecaseListAssistants := listassistants.NewListAssistantsUseCase(assistants)
+	usecaseDeleteAssistant := deleteassistant.NewDeleteAssistantUseCase(assistants)
+	usecaseEmbeddings := embeddings.NewEmbeddingsUseCase(models, embedder, embeddingcache.NewEmbeddingCacheInMemory(cfg.Embeddings.CacheSize))
+
+	healthChecker := health.NewChecker()
+	healthChecker.Require("database", conn.PingContext)
+	if cfg.Providers.HealthCheckInterval > 0 {
+		healthChecker.Optional("provider", func(ctx context.Context) error {
+			_, err := client.ListModels(ctx)
+			return err
+		}, cfg.Providers.HealthCheckInterval)
+	}
+
 	grpcServer := server.NewGRPCServer(
 		*usecaseStream,
-		chatConfigStream,
-		configs.GRPCServerPort,
-		configs.AuthToken,
-		streamChannel,
+		*usecaseCancel,
+		*usecaseHistory,
+		*usecaseListChats,
+		*usecaseListBranches,
+		*usecaseSwitchBranch,
+		*usecaseForkChat,
+		*usecaseCommitChoice,
+		*usecaseAttachDocument,
+		*usecaseListDocuments,
+		*usecaseEmbeddings,
+		chatConfig,
+		embeddingsConfig,
+		healthChecker,
+		cfg.Server.GRPCPort,
+		cfg.Auth.Token,
 	)
-	go grpcServer.Start()
 
-	usecase := chatcompletion.NewChatCompletionUseCase(repo, client)
-	webserver := webserver.NewWebServer(":" + configs.WebServerPort)
-	webserverChatHandler := web.NewWebChatGPTHandler(*usecase, chatConfig, configs.AuthToken)
+	usecase := chatcompletion.NewChatCompletionUseCase(repo, models, summarizer, generations, toolRegistry, documents, embedder, attachments, storage, templates, assistants, client)
+	webserver := webserver.NewWebServer(":" + cfg.Server.WebPort)
+	webserverHealthHandler := web.NewWebHealthHandler(healthChecker)
+	webserver.AddHandler("/healthz", webserverHealthHandler.HandleLiveness)
+	webserver.AddHandler("/readyz", webserverHealthHandler.HandleReadiness)
+	webserverChatHandler := web.NewWebChatGPTHandler(*usecase, chatConfig, cfg.Auth.Token)
+	webserverCancelHandler := web.NewWebCancelHandler(*usecaseCancel, cfg.Auth.Token)
 	webserver.AddHandler("/chat", webserverChatHandler.Handle)
+	webserver.AddHandler("/chat/regenerate", webserverChatHandler.HandleRegenerate)
+	webserverOpenAIHandler := web.NewWebOpenAIHandler(*usecase, *usecaseStream, *usecaseListChats, chatConfig, *usecaseEmbeddings, embeddingsConfig, cfg.Auth.Token)
+	webserver.AddHandler("/chat/cancel", webserverCancelHandler.Handle)
+	webserver.AddHandler("/v1/chat/completions", webserverOpenAIHandler.HandleChatCompletions)
+	webserver.AddHandler("/v1/models", webserverOpenAIHandler.HandleModels)
+	webserver.AddHandler("/v1/embeddings", webserverOpenAIHandler.HandleEmbeddings)
+	webserverHistoryHandler := web.NewWebChatHistoryHandler(*usecaseHistory, *usecaseListChats, cfg.Auth.Token)
+	webserver.AddHandler("/chats", webserverHistoryHandler.HandleList)
+	webserver.AddHandler("/chats/{id}", webserverHistoryHandler.HandleGet)
+	webserverBranchHandler := web.NewWebBranchHandler(*usecaseListBranches, *usecaseSwitchBranch, cfg.Auth.Token)
+	webserver.AddHandler("/chats/{id}/branches", webserverBranchHandler.HandleList)
+	webserver.AddHandler("/chats/{id}/branches/switch", webserverBranchHandler.HandleSwitch)
+	webserverForkHandler := web.NewWebForkHandler(*usecaseForkChat, cfg.Auth.Token)
+	webserver.AddHandler("/chats/{id}/fork", webserverForkHandler.Handle)
+	webserverChoiceHandler := web.NewWebChoiceHandler(*usecaseCommitChoice, cfg.Auth.Token)
+	webserver.AddHandler("/chats/{id}/choices/commit", webserverChoiceHandler.HandleCommit)
+	webserverDocumentHandler := web.NewWebDocumentHandler(*usecaseAttachDocument, *usecaseListDocuments, cfg.Auth.Token)
+	webserver.AddHandler("/chats/{id}/documents", webserverDocumentHandler.Handle)
+	webserverAttachmentHandler := web.NewWebAttachmentHandler(*usecaseUploadAttachment, *usecaseDownloadAttachment, cfg.Auth.Token)
+	webserver.AddHandler("/chats/{id}/attachments", webserverAttachmentHandler.HandleUpload)
+	webserver.AddHandler("/chats/{id}/attachments/{attachment_id}", webserverAttachmentHandler.HandleDownload)
+	// without an admin token the admin API would accept requests with no
+	// Authorization header, so it is only served when one is set
+	if cfg.Auth.AdminToken != "" {
+		webserverTemplateHandler := web.NewWebTemplateHandler(*usecaseSaveTemplate, *usecaseGetTemplate, *usecaseListTemplates, *usecaseDeleteTemplate, cfg.Auth.AdminToken)
+		webserver.AddHandler("/admin/templates", webserverTemplateHandler.HandleCollection)
+		webserver.AddHandler("/admin/templates/{name}", webserverTemplateHandler.HandleItem)
+		webserverAssistantHandler := web.NewWebAssistantHandler(*usecaseSaveAssistant, *usecaseGetAssistant, *usecaseListAssistants, *usecaseDeleteAssistant, cfg.Auth.AdminToken)
+		webserver.AddHandler("/admin/assistants", webserverAssistantHandler.HandleCollection)
+		webserver.AddHandler("/admin/assistants/{id}", webserverAssistantHandler.HandleItem)
+	} else {
+		log.Println("auth.admin_token is not set, the admin API is disabled")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErrs := make(chan error, 2)
+	log.Println("Starting gRPC server on port " + cfg.Server.GRPCPort)
+	go func() {
+		if err := grpcServer.Start(); err != nil {
+			serveErrs <- fmt.Errorf("gRPC server: %w", err)
+		}
+	}()
+	log.Println("Server running on port " + cfg.Server.WebPort)
+	go func() {
+		if err := webserver.Start(); err != nil {
+			serveErrs <- fmt.Errorf("web server: %w", err)
+		}
+	}()
 
-	log.Println("Server running on port " + configs.WebServerPort)
-	webserver.Start()
+	select {
+	case err = <-serveErrs:
+		log.Printf("Shutting down, %v", err)
+	case <-ctx.Done():
+		log.Println("Shutting down")
+	}
+	// a second signal stops the process without waiting for the requests
+	stop()
+	healthChecker.Shutdown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	shutdownErrs := make(chan error, 2)
+	go func() {
+		if err := grpcServer.Shutdown(shutdownCtx); err != nil {
+			shutdownErrs <- fmt.Errorf("shutting down the gRPC server: %w", err)
+			return
+		}
+		shutdownErrs <- nil
+	}()
+	go func() {
+		if err := webserver.Shutdown(shutdownCtx); err != nil {
+			shutdownErrs <- fmt.Errorf("shutting down the web server: %w", err)
+			return
+		}
+		shutdownErrs <- nil
+	}()
+	for i := 0; i < 2; i++ {
+		err = errors.Join(err, <-shutdownErrs)
+	}
+	// the titles and summaries are still written after the replies
+	usecase.Wait()
+	usecaseStream.Wait()
+	return err
+}
+
+// validateConfig serves "chat-service config validate [flags]", loading the
+// configuration as on startup and exiting with 1 when it is invalid.
+func validateConfig(args []string) int {
+	if _, err := configs.LoadConfig(args, tools.Names()); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, configs.Describe(err))
+		return 1
+	}
+	fmt.Println("configuration is valid")
+	return 0
+}
+
+func newChatConfig(cfg *configs.Config) chatconfig.ConfigurationInput {
+	return chatconfig.ConfigurationInput{
+		Model:                cfg.Chat.Model,
+		Temperature:          float32(cfg.Chat.Temperature),
+		TopP:                 float32(cfg.Chat.TopP),
+		N:                    cfg.Chat.N,
+		Stop:                 cfg.Chat.Stop,
+		MaxTokens:            cfg.Chat.MaxTokens,
+		InitialSystemMessage: cfg.Chat.SystemMessage,
+		ContextStrategy:      cfg.Chat.ContextStrategy,
+		AllowedModels:        cfg.Chat.AllowedModels,
+		MaxTokensLimit:       cfg.Limits.MaxTokens,
+		RunningSummary:       cfg.Chat.RunningSummary,
+		Tools:                cfg.Chat.Tools,
+		SchemaRetries:        cfg.Chat.SchemaRetries,
+		RetrievalTopK:        cfg.Chat.RetrievalTopK,
+	}
+}
+
+func newEmbeddingsConfig(cfg *configs.Config) embeddings.EmbeddingsConfigurationInput {
+	return embeddings.EmbeddingsConfigurationInput{
+		Model:          cfg.Embeddings.Model,
+		AllowedModels:  cfg.Embeddings.AllowedModels,
+		MaxInputs:      cfg.Limits.EmbeddingInputs,
+		MaxTokensLimit: cfg.Limits.EmbeddingTokens,
+	}
 }
@@ -1,42 +1,471 @@
 package configs
 
-import "github.com/spf13/viper"
-
-type conf struct {
-	DBDriver           string   `mapstructure:"DB_DRIVER"`
-	DBHost             string   `mapstructure:"DB_HOST"`
-	DBPort             string   `mapstructure:"DB_PORT"`
-	DBUser             string   `mapstructure:"DB_USER"`
-	DBPassword         string   `mapstructure:"DB_PASSWORD"`
-	DBName             string   `mapstructure:"DB_NAME"`
-	WebServerPort      string   `mapstructure:"WEB_SERVER_PORT"`
-	GRPCServerPort     string   `mapstructure:"GRPC_SERVER_PORT"`
-	InitialChatMessage string   `mapstructure:"INITIAL_CHAT_MESSAGE"`
-	OpenAIApiKey       string   `mapstructure:"OPENAI_API_KEY"`
-	Model              string   `mapstructure:"MODEL"`
-	ModelMaxTokens     int      `mapstructure:"MODEL_MAX_TOKENS"`
-	Temperature        float64  `mapstructure:"TEMPERATURE"`
-	TopP               float64  `mapstructure:"TOP_P"`
-	N                  int      `mapstructure:"N"`
-	Stop               []string `mapstructure:"STOP"`
-	MaxTokens          int      `mapstructure:"MAX_TOKENS"`
-	AuthToken          string   `mapstructure:"AUTH_TOKEN"`
-}
-
-func LoadConfig(path string) (*conf, error) {
-	var cfg *conf
-	viper.SetConfigName("app_config")
-	viper.SetConfigType("env")
-	viper.AddConfigPath(path)
-	viper.SetConfigFile(".env")
-	viper.AutomaticEnv()
-
-	if err := viper.ReadInConfig(); err != nil {
-		panic(err)
-	}
-
-	if err := viper.Unmarshal(&cfg); err != nil {
-		panic(err)
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/infra/repositories"
+	"github.com/spf13/viper"
+)
+
+// DefaultConfigFile is read when neither the -config flag nor CONFIG_FILE
+// name another file.
+const DefaultConfigFile = "configs/config.yaml"
+
+// Config is read from a YAML file, each section below is a top level key of
+// the file. Environment variables override the file and flags override both,
+// see settings.
+type Config struct {
+	Server      ServerConfig      `mapstructure:"server"`
+	DB          DBConfig          `mapstructure:"db"`
+	Providers   ProvidersConfig   `mapstructure:"providers"`
+	Auth        AuthConfig        `mapstructure:"auth"`
+	Chat        ChatConfig        `mapstructure:"chat"`
+	Embeddings  EmbeddingsConfig  `mapstructure:"embeddings"`
+	Limits      LimitsConfig      `mapstructure:"limits"`
+	Tools       ToolsConfig       `mapstructure:"tools"`
+	Attachments AttachmentsConfig `mapstructure:"attachments"`
+}
+
+type ServerConfig struct {
+	WebPort         string        `mapstructure:"web_port"`
+	GRPCPort        string        `mapstructure:"grpc_port"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"` // time given to the running requests to finish on shutdown
+}
+
+type DBConfig struct {
+	Driver   string `mapstructure:"driver"`
+	Host     string `mapstructure:"host"`
+	Port     string `mapstructure:"port"`
+	User     string `mapstructure:"user"`
+	Password string `mapstructure:"password"`
+	Name     string `mapstructure:"name"`
+}
+
+type ProvidersConfig struct {
+	OpenAI              OpenAIConfig  `mapstructure:"openai"`
+	ModelsFile          string        `mapstructure:"models_file"`           // model registry, see configs/models.yaml
+	HealthCheckInterval time.Duration `mapstructure:"health_check_interval"` // how often readiness may probe the provider, 0 skips the probe
+}
+
+type OpenAIConfig struct {
+	APIKey string `mapstructure:"api_key"`
+}
+
+type AuthConfig struct {
+	Token      string `mapstructure:"token"`
+	AdminToken string `mapstructure:"admin_token"` // the admin API is disabled when empty
+}
+
+// ChatConfig holds the defaults of new chats.
+type ChatConfig struct {
+	Model           string   `mapstructure:"model"`
+	AllowedModels   []string `mapstructure:"allowed_models"`
+	SystemMessage   string   `mapstructure:"system_message"`
+	ContextStrategy string   `mapstructure:"context_strategy"`
+	RunningSummary  bool     `mapstructure:"running_summary"`
+	Temperature     float64  `mapstructure:"temperature"`
+	TopP            float64  `mapstructure:"top_p"`
+	N               int      `mapstructure:"n"`
+	Stop            []string `mapstructure:"stop"`
+	MaxTokens       int      `mapstructure:"max_tokens"`
+	Tools           []string `mapstructure:"tools"`
+	SchemaRetries   int      `mapstructure:"schema_retries"`
+	RetrievalTopK   int      `mapstructure:"retrieval_top_k"`
+}
+
+type EmbeddingsConfig struct {
+	Model         string   `mapstructure:"model"`
+	AllowedModels []string `mapstructure:"allowed_models"`
+	CacheSize     int      `mapstructure:"cache_size"`
+}
+
+// LimitsConfig bounds what a single request may ask for.
+type LimitsConfig struct {
+	MaxTokens       int   `mapstructure:"max_tokens"`       // highest max_tokens of a chat, defaults to chat.max_tokens
+	EmbeddingInputs int   `mapstructure:"embedding_inputs"` // 0 means no limit
+	EmbeddingTokens int   `mapstructure:"embedding_tokens"` // 0 means no limit
+	FetchBytes      int64 `mapstructure:"fetch_bytes"`      // bytes read by the fetch_url tool, 0 uses its default
+}
+
+type ToolsConfig struct {
+	FetchAllowedHosts []string `mapstructure:"fetch_allowed_hosts"`
+}
+
+type AttachmentsConfig struct {
+	Storage string   `mapstructure:"storage"` // filesystem or s3
+	Dir     string   `mapstructure:"dir"`
+	S3      S3Config `mapstructure:"s3"`
+}
+
+type S3Config struct {
+	Endpoint  string `mapstructure:"endpoint"`
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+}
+
+// settings lists every key of the file with the environment variable that
+// overrides it, the names used by the .env of earlier versions. Each key is
+// also a flag, e.g. -db.host, lists are separated by commas outside of the
+// file.
+var settings = []struct {
+	key, env     string
+	defaultValue interface{}
+}{
+	{"server.web_port", "WEB_SERVER_PORT", "8080"},
+	{"server.grpc_port", "GRPC_SERVER_PORT", "50051"},
+	{"server.shutdown_timeout", "SHUTDOWN_TIMEOUT", "30s"},
+	{"db.driver", "DB_DRIVER", "mysql"},
+	{"db.host", "DB_HOST", ""},
+	{"db.port", "DB_PORT", "3306"},
+	{"db.user", "DB_USER", ""},
+	{"db.password", "DB_PASSWORD", ""},
+	{"db.name", "DB_NAME", ""},
+	{"providers.openai.api_key", "OPENAI_API_KEY", ""},
+	{"providers.models_file", "MODELS_FILE", "configs/models.yaml"},
+	{"providers.health_check_interval", "PROVIDER_HEALTH_CHECK_INTERVAL", "1m"},
+	{"auth.token", "AUTH_TOKEN", ""},
+	{"auth.admin_token", "ADMIN_AUTH_TOKEN", ""},
+	{"chat.model", "MODEL", ""},
+	{"chat.allowed_models", "ALLOWED_MODELS", []string{}},
+	{"chat.system_message", "INITIAL_CHAT_MESSAGE", ""},
+	{"chat.context_strategy", "CONTEXT_STRATEGY", entity.ContextStrategySlidingWindow},
+	{"chat.running_summary", "RUNNING_SUMMARY", false},
+	{"chat.temperature", "TEMPERATURE", 1.0},
+	{"chat.top_p", "TOP_P", 1.0},
+	{"chat.n", "N", 1},
+	{"chat.stop", "STOP", []string{}},
+	{"chat.max_tokens", "MAX_TOKENS", 0},
+	{"chat.tools", "TOOLS", []string{}},
+	{"chat.schema_retries", "SCHEMA_RETRIES", 0},
+	{"chat.retrieval_top_k", "RETRIEVAL_TOP_K", 0},
+	{"embeddings.model", "EMBEDDING_MODEL", ""},
+	{"embeddings.allowed_models", "EMBEDDING_MODELS", []string{}},
+	{"embeddings.cache_size", "EMBEDDING_CACHE_SIZE", 0},
+	{"limits.max_tokens", "MAX_TOKENS_LIMIT", 0},
+	{"limits.embedding_inputs", "EMBEDDING_MAX_INPUTS", 0},
+	{"limits.embedding_tokens", "EMBEDDING_MAX_TOKENS", 0},
+	{"limits.fetch_bytes", "FETCH_MAX_BYTES", 0},
+	{"tools.fetch_allowed_hosts", "FETCH_ALLOWED_HOSTS", []string{}},
+	{"attachments.storage", "ATTACHMENT_STORAGE", "filesystem"},
+	{"attachments.dir", "ATTACHMENT_DIR", "data/attachments"},
+	{"attachments.s3.endpoint", "S3_ENDPOINT", ""},
+	{"attachments.s3.region", "S3_REGION", ""},
+	{"attachments.s3.bucket", "S3_BUCKET", ""},
+	{"attachments.s3.access_key", "S3_ACCESS_KEY", ""},
+	{"attachments.s3.secret_key", "S3_SECRET_KEY", ""},
+}
+
+// Registry names what the settings refer to outside of the file.
+type Registry struct {
+	Models []string // models of providers.models_file
+	Tools  []string // tools registered on startup
+}
+
+// LoadConfig reads the file named by the -config flag of args, CONFIG_FILE or
+// DefaultConfigFile, applying the environment and the flags of args over it.
+// chat.tools may only enable tools. The error lists every invalid setting,
+// and is flag.ErrHelp when args ask for the usage.
+func LoadConfig(args []string, tools []string) (*Config, error) {
+	flags := flag.NewFlagSet("chat-service", flag.ContinueOnError)
+	file := flags.String("config", "", "YAML configuration file, defaults to CONFIG_FILE or "+DefaultConfigFile)
+	values := make(map[string]*string, len(settings))
+	for _, setting := range settings {
+		values[setting.key] = flags.String(setting.key, "", "overrides "+setting.key+" and $"+setting.env)
 	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	overrides := make(map[string]string)
+	flags.Visit(func(f *flag.Flag) {
+		if value, ok := values[f.Name]; ok {
+			overrides[f.Name] = *value
+		}
+	})
+
+	if *file == "" {
+		*file = os.Getenv("CONFIG_FILE")
+	}
+	if *file == "" {
+		*file = DefaultConfigFile
+	}
+	cfg, err := readConfig(*file, overrides, tools)
+	if err != nil {
+		return nil, err
+	}
+	loaded.file, loaded.overrides, loaded.tools = *file, overrides, tools
 	return cfg, nil
 }
+
+// loaded is what LoadConfig read, WatchConfig reads it again.
+var loaded struct {
+	file      string
+	overrides map[string]string
+	tools     []string
+}
+
+// readConfig reads file into its own viper, so that a reload that fails
+// leaves nothing of it behind.
+func readConfig(file string, overrides map[string]string, tools []string) (*Config, error) {
+	v := viper.New()
+	for _, setting := range settings {
+		v.SetDefault(setting.key, setting.defaultValue)
+		if err := v.BindEnv(setting.key, setting.env); err != nil {
+			return nil, err
+		}
+	}
+	for key, value := range overrides {
+		v.Set(key, value)
+	}
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", file, err)
+	}
+
+	var cfg Config
+	// unknown keys are reported, they are mostly misspelled settings
+	if err := v.UnmarshalExact(&cfg); err != nil {
+		return nil, err
+	}
+	registry := Registry{Tools: tools}
+	if cfg.Providers.ModelsFile != "" {
+		models, err := repositories.NewModelRepositoryFile(cfg.Providers.ModelsFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", cfg.Providers.ModelsFile, err)
+		}
+		list, err := models.List(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		for _, model := range list {
+			registry.Models = append(registry.Models, model.Name)
+		}
+	}
+	if err := cfg.Validate(registry); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate reports every invalid setting at once, named by its key. The
+// models and tools of chat must be in registry.
+func (c *Config) Validate(registry Registry) error {
+	verr := &entity.ValidationError{Entity: "configuration"}
+	for key, value := range map[string]string{
+		"server.web_port":          c.Server.WebPort,
+		"server.grpc_port":         c.Server.GRPCPort,
+		"db.driver":                c.DB.Driver,
+		"db.host":                  c.DB.Host,
+		"db.name":                  c.DB.Name,
+		"providers.openai.api_key": c.Providers.OpenAI.APIKey,
+		"providers.models_file":    c.Providers.ModelsFile,
+		"auth.token":               c.Auth.Token,
+		"chat.model":               c.Chat.Model,
+		"embeddings.model":         c.Embeddings.Model,
+	} {
+		if value == "" {
+			verr.Add(key, "is empty")
+		}
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		verr.Add("server.shutdown_timeout", "must be positive")
+	}
+	if c.Providers.HealthCheckInterval < 0 {
+		verr.Add("providers.health_check_interval", "must not be negative")
+	}
+	if _, err := entity.NewContextStrategy(c.Chat.ContextStrategy); err != nil {
+		verr.Add("chat.context_strategy", err.Error())
+	}
+	if c.Chat.Temperature < 0 || c.Chat.Temperature > 2 {
+		verr.Add("chat.temperature", "must be between 0 and 2")
+	}
+	if c.Chat.TopP < 0 || c.Chat.TopP > 1 {
+		verr.Add("chat.top_p", "must be between 0 and 1")
+	}
+	if c.Chat.N < 1 || c.Chat.N > entity.MaxChoices {
+		verr.Add("chat.n", fmt.Sprintf("must be between 1 and %d", entity.MaxChoices))
+	}
+	if c.Chat.Model != "" && !contains(registry.Models, c.Chat.Model) {
+		verr.Add("chat.model", fmt.Sprintf("%q is not in the model registry", c.Chat.Model))
+	}
+	for _, model := range c.Chat.AllowedModels {
+		if !contains(registry.Models, model) {
+			verr.Add("chat.allowed_models", fmt.Sprintf("%q is not in the model registry", model))
+		}
+	}
+	for _, tool := range c.Chat.Tools {
+		if !contains(registry.Tools, tool) {
+			verr.Add("chat.tools", fmt.Sprintf("%q is not a registered tool", tool))
+		}
+	}
+	if len(c.Chat.Stop) > entity.MaxStopSequences {
+		verr.Add("chat.stop", fmt.Sprintf("must have at most %d sequences", entity.MaxStopSequences))
+	}
+	if c.Chat.MaxTokens < 0 {
+		verr.Add("chat.max_tokens", "must not be negative")
+	}
+	if c.Limits.MaxTokens < 0 {
+		verr.Add("limits.max_tokens", "must not be negative")
+	} else if c.Limits.MaxTokens > 0 && c.Limits.MaxTokens < c.Chat.MaxTokens {
+		verr.Add("limits.max_tokens", "must not be lower than chat.max_tokens")
+	}
+	for key, value := range map[string]int64{
+		"chat.schema_retries":     int64(c.Chat.SchemaRetries),
+		"chat.retrieval_top_k":    int64(c.Chat.RetrievalTopK),
+		"embeddings.cache_size":   int64(c.Embeddings.CacheSize),
+		"limits.embedding_inputs": int64(c.Limits.EmbeddingInputs),
+		"limits.embedding_tokens": int64(c.Limits.EmbeddingTokens),
+		"limits.fetch_bytes":      c.Limits.FetchBytes,
+	} {
+		if value < 0 {
+			verr.Add(key, "must not be negative")
+		}
+	}
+	switch c.Attachments.Storage {
+	case "filesystem":
+		if c.Attachments.Dir == "" {
+			verr.Add("attachments.dir", "is empty")
+		}
+	case "s3":
+		for key, value := range map[string]string{
+			"attachments.s3.endpoint":   c.Attachments.S3.Endpoint,
+			"attachments.s3.region":     c.Attachments.S3.Region,
+			"attachments.s3.bucket":     c.Attachments.S3.Bucket,
+			"attachments.s3.access_key": c.Attachments.S3.AccessKey,
+			"attachments.s3.secret_key": c.Attachments.S3.SecretKey,
+		} {
+			if value == "" {
+				verr.Add(key, "is empty")
+			}
+		}
+	default:
+		verr.Add("attachments.storage", fmt.Sprintf("must be filesystem or s3, got %q", c.Attachments.Storage))
+	}
+	sort.SliceStable(verr.Fields, func(i, j int) bool {
+		return verr.Fields[i].Field < verr.Fields[j].Field
+	})
+	return verr.Err()
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Describe formats err for a terminal, one invalid setting per line.
+func Describe(err error) string {
+	var verr *entity.ValidationError
+	if !errors.As(err, &verr) {
+		return err.Error()
+	}
+	lines := []string{"invalid " + verr.Entity + ":"}
+	for _, field := range verr.Fields {
+		lines = append(lines, "  "+field.Field+": "+field.Message)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RestartRequired lists the settings changed since previous that are only
+// read at startup: connections, listeners, credentials and the storage.
+func (c *Config) RestartRequired(previous *Config) []string {
+	var changed []string
+	for _, setting := range []struct {
+		key     string
+		changed bool
+	}{
+		{"server", c.Server != previous.Server},
+		{"db", c.DB != previous.DB},
+		{"providers.openai", c.Providers.OpenAI != previous.Providers.OpenAI},
+		{"providers.health_check_interval", c.Providers.HealthCheckInterval != previous.Providers.HealthCheckInterval},
+		{"auth", c.Auth != previous.Auth},
+		{"embeddings.model", c.Embeddings.Model != previous.Embeddings.Model}, // documents keep the model they were embedded with
+		{"embeddings.cache_size", c.Embeddings.CacheSize != previous.Embeddings.CacheSize},
+		{"limits.fetch_bytes", c.Limits.FetchBytes != previous.Limits.FetchBytes},
+		{"tools", !reflect.DeepEqual(c.Tools, previous.Tools)},
+		{"attachments", c.Attachments != previous.Attachments},
+	} {
+		if setting.changed {
+			changed = append(changed, setting.key)
+		}
+	}
+	return changed
+}
+
+// WatchConfig reads the configuration again when the file changes or the
+// process receives SIGHUP, handing it to onChange with the one applied before,
+// cfg at first. A configuration that does not load or validate goes to onError
+// instead and the applied one is kept. The environment and flags keep
+// overriding the file. Must be called after LoadConfig.
+func WatchConfig(cfg *Config, onChange func(reloaded, previous *Config) error, onError func(error)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	file := filepath.Clean(loaded.file)
+	// editors often replace the file rather than write it, so its directory
+	// is watched
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Close()
+		return err
+	}
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	// a single goroutine reloads, a change of the file and a signal are never
+	// handled at the same time
+	go func() {
+		defer watcher.Close()
+		applied := cfg
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != file || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				onError(err)
+				continue
+			case <-hangup:
+			}
+
+			reloaded, err := readConfig(loaded.file, loaded.overrides, loaded.tools)
+			if err == nil {
+				err = onChange(reloaded, applied)
+			}
+			if err != nil {
+				onError(err)
+				continue
+			}
+			applied = reloaded
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,155 @@
+package configs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+)
+
+func validConfig() *Config {
+	return &Config{
+		Server:    ServerConfig{WebPort: "8080", GRPCPort: "50051", ShutdownTimeout: 30 * time.Second},
+		DB:        DBConfig{Driver: "mysql", Host: "localhost", Port: "3306", Name: "chat"},
+		Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "key"}, ModelsFile: "configs/models.yaml"},
+		Auth:      AuthConfig{Token: "token"},
+		Chat: ChatConfig{
+			Model:           "gpt-4o",
+			ContextStrategy: entity.ContextStrategySlidingWindow,
+			Temperature:     1,
+			TopP:            1,
+			N:               1,
+			MaxTokens:       500,
+		},
+		Embeddings:  EmbeddingsConfig{Model: "text-embedding-3-small"},
+		Attachments: AttachmentsConfig{Storage: "filesystem", Dir: "data/attachments"},
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		fields []string
+	}{
+		{name: "valid", change: func(c *Config) {}},
+		{
+			name:   "empty required settings",
+			change: func(c *Config) { c.DB.Host, c.Auth.Token, c.Chat.Model = "", "", "" },
+			fields: []string{"auth.token", "chat.model", "db.host"},
+		},
+		{
+			name:   "non positive shutdown timeout",
+			change: func(c *Config) { c.Server.ShutdownTimeout = 0 },
+			fields: []string{"server.shutdown_timeout"},
+		},
+		{
+			name:   "negative health check interval",
+			change: func(c *Config) { c.Providers.HealthCheckInterval = -time.Second },
+			fields: []string{"providers.health_check_interval"},
+		},
+		{
+			name:   "unknown context strategy",
+			change: func(c *Config) { c.Chat.ContextStrategy = "forget" },
+			fields: []string{"chat.context_strategy"},
+		},
+		{
+			name:   "too many choices",
+			change: func(c *Config) { c.Chat.N = entity.MaxChoices + 1 },
+			fields: []string{"chat.n"},
+		},
+		{
+			name: "models and tools outside of the registry",
+			change: func(c *Config) {
+				c.Chat.Model, c.Chat.AllowedModels = "gpt-5", []string{"gpt-4o", "o9"}
+				c.Chat.Tools = []string{"calculator", "shell"}
+			},
+			fields: []string{"chat.allowed_models", "chat.model", "chat.tools"},
+		},
+		{
+			name:   "sampling out of range",
+			change: func(c *Config) { c.Chat.Temperature, c.Chat.TopP, c.Chat.N = 2.5, 1.5, 0 },
+			fields: []string{"chat.n", "chat.temperature", "chat.top_p"},
+		},
+		{
+			name:   "too many stop sequences",
+			change: func(c *Config) { c.Chat.Stop = make([]string, entity.MaxStopSequences+1) },
+			fields: []string{"chat.stop"},
+		},
+		{
+			name:   "limit below the chat max tokens",
+			change: func(c *Config) { c.Limits.MaxTokens = 100 },
+			fields: []string{"limits.max_tokens"},
+		},
+		{
+			name: "negative counts",
+			change: func(c *Config) {
+				c.Chat.MaxTokens, c.Chat.SchemaRetries, c.Chat.RetrievalTopK = -1, -1, -1
+				c.Limits.FetchBytes = -1
+			},
+			fields: []string{"chat.max_tokens", "chat.retrieval_top_k", "chat.schema_retries", "limits.fetch_bytes"},
+		},
+		{
+			name:   "filesystem storage without a directory",
+			change: func(c *Config) { c.Attachments.Dir = "" },
+			fields: []string{"attachments.dir"},
+		},
+		{
+			name: "s3 storage without credentials",
+			change: func(c *Config) {
+				c.Attachments.Storage = "s3"
+				c.Attachments.S3 = S3Config{Endpoint: "http://localhost:9000", Region: "us-east-1", Bucket: "attachments"}
+			},
+			fields: []string{"attachments.s3.access_key", "attachments.s3.secret_key"},
+		},
+		{
+			name:   "unknown storage",
+			change: func(c *Config) { c.Attachments.Storage = "ftp" },
+			fields: []string{"attachments.storage"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.change(cfg)
+			err := cfg.Validate(Registry{Models: []string{"gpt-4o", "gpt-4o-mini"}, Tools: []string{"calculator", "fetch_url"}})
+			if len(tt.fields) == 0 {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+
+			var verr *entity.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("err = %v, want a ValidationError", err)
+			}
+			var fields []string
+			for _, field := range verr.Fields {
+				fields = append(fields, field.Field)
+			}
+			if len(fields) != len(tt.fields) {
+				t.Fatalf("fields = %v, want %v", fields, tt.fields)
+			}
+			for i := range fields {
+				if fields[i] != tt.fields[i] {
+					t.Fatalf("fields = %v, want %v", fields, tt.fields)
+				}
+			}
+		})
+	}
+}
+
+func TestReadConfigMalformedFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte("server: [web_port"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readConfig(file, nil, nil); err == nil {
+		t.Fatal("readConfig of a malformed file succeeded")
+	}
+}
@@ -0,0 +1,88 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MaxAssistantName        = 64
+	MaxAssistantDescription = 255
+)
+
+// Assistant bundles the settings of a bot served by the deployment. Chats
+// created with its ID start with its system message and configuration
+// instead of the server defaults, and keep them when the assistant changes.
+type Assistant struct {
+	ID            string
+	Name          string
+	Description   string
+	SystemMessage string
+	Configuration *ChatConfiguration
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func NewAssistant(name, description, systemMessage string, configuration *ChatConfiguration) (*Assistant, error) {
+	assistant := &Assistant{
+		ID:            uuid.New().String(),
+		Name:          strings.TrimSpace(name),
+		Description:   description,
+		SystemMessage: systemMessage,
+		Configuration: configuration,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	if err := assistant.Validate(); err != nil {
+		return nil, err
+	}
+	return assistant, nil
+}
+
+// Update replaces every setting of the assistant, leaving it untouched when
+// the new settings are invalid.
+func (a *Assistant) Update(name, description, systemMessage string, configuration *ChatConfiguration) error {
+	updated := *a
+	updated.Name = strings.TrimSpace(name)
+	updated.Description = description
+	updated.SystemMessage = systemMessage
+	updated.Configuration = configuration
+	updated.UpdatedAt = time.Now()
+	if err := updated.Validate(); err != nil {
+		return err
+	}
+	*a = updated
+	return nil
+}
+
+// Validate reports the invalid fields of the assistant together with the
+// ones of its configuration.
+func (a *Assistant) Validate() error {
+	verr := &ValidationError{Entity: "assistant"}
+	if a.Name == "" {
+		verr.Add("name", "is empty")
+	} else if utf8.RuneCountInString(a.Name) > MaxAssistantName {
+		verr.Add("name", fmt.Sprintf("must have at most %d characters", MaxAssistantName))
+	}
+	if utf8.RuneCountInString(a.Description) > MaxAssistantDescription {
+		verr.Add("description", fmt.Sprintf("must have at most %d characters", MaxAssistantDescription))
+	}
+	if strings.TrimSpace(a.SystemMessage) == "" {
+		verr.Add("system_message", "is empty")
+	}
+	if a.Configuration == nil {
+		verr.Add("configuration", "is empty")
+	} else if err := a.Configuration.Validate(); err != nil {
+		var configErr *ValidationError
+		if !errors.As(err, &configErr) {
+			return err
+		}
+		verr.Fields = append(verr.Fields, configErr.Fields...)
+	}
+	return verr.Err()
+}
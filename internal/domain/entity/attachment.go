@@ -0,0 +1,116 @@
+package entity
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const MaxAttachmentSize = 10 * 1024 * 1024 // bytes
+
+// attachmentContentTypes are the types accepted on upload, as detected from
+// the content. Text types sniffed as text/plain are refined by the file
+// extension.
+var attachmentContentTypes = map[string]bool{
+	"image/png":        true,
+	"image/jpeg":       true,
+	"image/gif":        true,
+	"image/webp":       true,
+	"application/pdf":  true,
+	"text/plain":       true,
+	"text/markdown":    true,
+	"text/csv":         true,
+	"application/json": true,
+}
+
+var textExtensions = map[string]string{
+	".md":   "text/markdown",
+	".csv":  "text/csv",
+	".json": "application/json",
+}
+
+// Attachment is a file uploaded to a chat. The content lives in the
+// attachment store under StorageKey, messages reference it by ID.
+type Attachment struct {
+	ID          string
+	ChatID      string
+	UserID      string
+	Name        string
+	ContentType string
+	Size        int64
+	StorageKey  string
+	CreatedAt   time.Time
+}
+
+// NewAttachment detects the content type from the content itself, the type
+// claimed by the client is not trusted.
+func NewAttachment(chatID, userID, name string, content []byte) (*Attachment, error) {
+	verr := &ValidationError{Entity: "attachment"}
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		verr.Add("name", "is empty")
+	} else if utf8.RuneCountInString(name) > MaxDocumentName {
+		verr.Add("name", fmt.Sprintf("must have at most %d characters", MaxDocumentName))
+	}
+
+	contentType := ""
+	if len(content) == 0 {
+		verr.Add("content", "is empty")
+	} else if len(content) > MaxAttachmentSize {
+		verr.Add("content", fmt.Sprintf("must have at most %d bytes", MaxAttachmentSize))
+	} else {
+		contentType = sniffContentType(name, content)
+		if !attachmentContentTypes[contentType] {
+			verr.Add("content", fmt.Sprintf("content type %s is not accepted", contentType))
+		}
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	return &Attachment{
+		ID:          id,
+		ChatID:      chatID,
+		UserID:      userID,
+		Name:        name,
+		ContentType: contentType,
+		Size:        int64(len(content)),
+		StorageKey:  chatID + "/" + id,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// CopyTo returns the attachment under a new id in another chat, sharing the
+// stored content.
+func (a *Attachment) CopyTo(chatID, userID string) *Attachment {
+	copied := *a
+	copied.ID = uuid.New().String()
+	copied.ChatID = chatID
+	copied.UserID = userID
+	copied.CreatedAt = time.Now()
+	return &copied
+}
+
+func (a *Attachment) IsImage() bool {
+	return imageMediaTypes[a.ContentType]
+}
+
+func sniffContentType(name string, content []byte) string {
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(content))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	if contentType == "text/plain" {
+		if refined, ok := textExtensions[strings.ToLower(filepath.Ext(name))]; ok {
+			return refined
+		}
+	}
+	return contentType
+}
@@ -1,31 +1,131 @@
 package entity
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	MaxStopSequences = 4
+	MaxChoices       = 8 // highest number of candidate replies generated at once
+	TokensPerMessage = 3 // chat format overhead added to every message of the prompt
+	TokensPerReply   = 3 // every reply is primed with the assistant role
+)
+
+var (
+	ErrNothingToRegenerate = errors.New("chat has no user message to answer again")
+	ErrMessageNotFound     = errors.New("message not found")
+	ErrChoiceNotFound      = errors.New("message is not a candidate reply to the last user message")
+)
+
+// MessageTooLargeError is returned when a message cannot fit in the context window.
+type MessageTooLargeError struct {
+	Tokens int
+	Budget int
+}
+
+func (e *MessageTooLargeError) Error() string {
+	return fmt.Sprintf("message needs %d tokens but the context window only has %d available", e.Tokens, e.Budget)
+}
+
 type ChatConfiguration struct {
 	Model            *Model
-	Temperature      float32  // 0.0 to 1.0
-	TopP             float32  // 0.0 to 1.0 - to a low value, like 0.1, the model will be very conservative in its word choices, and will tend to generate relatively predictable prompts
-	N                int      // number of messages to generate
-	Stop             []string // list of tokens to stop on
-	MaxTokens        int      // number of tokens to generate
-	PresencePenalty  float32  // -2.0 to 2.0 - Number between -2.0 and 2.0. Positive values penalize new tokens based on whether they appear in the text so far, increasing the model's likelihood to talk about new topics.
-	FrequencyPenalty float32  // -2.0 to 2.0 - Number between -2.0 and 2.0. Positive values penalize new tokens based on their existing frequency in the text so far, increasing the model's likelihood to talk about new topics.
+	Temperature      float32         // 0.0 to 1.0
+	TopP             float32         // 0.0 to 1.0 - to a low value, like 0.1, the model will be very conservative in its word choices, and will tend to generate relatively predictable prompts
+	N                int             // number of messages to generate
+	Stop             []string        // list of tokens to stop on
+	MaxTokens        int             // number of tokens to generate
+	PresencePenalty  float32         // -2.0 to 2.0 - Number between -2.0 and 2.0. Positive values penalize new tokens based on whether they appear in the text so far, increasing the model's likelihood to talk about new topics.
+	FrequencyPenalty float32         // -2.0 to 2.0 - Number between -2.0 and 2.0. Positive values penalize new tokens based on their existing frequency in the text so far, increasing the model's likelihood to talk about new topics.
+	ContextStrategy  string          // how messages leave the context window, see NewContextStrategy
+	Tools            []string        // names of the tools the assistant may call
+	ResponseSchema   json.RawMessage // JSON schema every reply must follow, empty for free text
+	RetrievalTopK    int             // chunks of the chat documents added to the prompt, 0 disables retrieval
+}
 
+// Strategy falls back to the sliding window for unknown strategies.
+func (c *ChatConfiguration) Strategy() ContextStrategy {
+	strategy, err := NewContextStrategy(c.ContextStrategy)
+	if err != nil {
+		return SlidingWindowStrategy{}
+	}
+	return strategy
+}
+
+func (c *ChatConfiguration) HasTool(name string) bool {
+	for _, tool := range c.Tools {
+		if tool == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *ChatConfiguration) Validate() error {
+	verr := &ValidationError{Entity: "chat configuration"}
+	if c.Model == nil || c.Model.GetName() == "" || c.Model.GetMaxTokens() <= 0 {
+		verr.Add("model", "unknown model")
+	}
+	if c.Temperature < 0 || c.Temperature > 2 {
+		verr.Add("temperature", "must be between 0 and 2")
+	}
+	if c.TopP < 0 || c.TopP > 1 {
+		verr.Add("top_p", "must be between 0 and 1")
+	}
+	if c.N < 1 || c.N > MaxChoices {
+		verr.Add("n", fmt.Sprintf("must be between 1 and %d", MaxChoices))
+	} else if c.N > 1 && len(c.Tools) > 0 {
+		verr.Add("n", "must be 1 when tools are enabled")
+	} else if c.N > 1 && len(c.ResponseSchema) > 0 {
+		verr.Add("n", "must be 1 when a response schema is set")
+	}
+	if c.MaxTokens < 0 {
+		verr.Add("max_tokens", "must not be negative")
+	} else if c.Model != nil && c.Model.GetMaxTokens() > 0 && c.MaxTokens > c.Model.GetMaxOutputTokens() {
+		verr.Add("max_tokens", fmt.Sprintf("must not exceed the model limit of %d tokens", c.Model.GetMaxOutputTokens()))
+	} else if c.Model != nil && c.Model.GetMaxTokens() > 0 && c.MaxTokens+TokensPerReply >= c.Model.GetMaxTokens() {
+		verr.Add("max_tokens", "must leave room for the prompt in the context window")
+	}
+	if len(c.Stop) > MaxStopSequences {
+		verr.Add("stop", fmt.Sprintf("must have at most %d sequences", MaxStopSequences))
+	}
+	if c.PresencePenalty < -2 || c.PresencePenalty > 2 {
+		verr.Add("presence_penalty", "must be between -2 and 2")
+	}
+	if c.FrequencyPenalty < -2 || c.FrequencyPenalty > 2 {
+		verr.Add("frequency_penalty", "must be between -2 and 2")
+	}
+	if _, err := NewContextStrategy(c.ContextStrategy); err != nil {
+		verr.Add("context_strategy", err.Error())
+	}
+	if len(c.ResponseSchema) > 0 {
+		if _, err := NewResponseSchema(c.ResponseSchema); err != nil {
+			verr.Add("response_schema", err.Error())
+		}
+	}
+	return verr.Err()
 }
 
 type Chat struct {
 	ID                   string
 	UserID               string
+	AssistantID          string // assistant the chat was created with, empty for the server defaults
+	Title                string // short title generated after the first exchange
 	InitialSystemMessage *Message
-	Messages             []*Message
-	ErasedMessages       []*Message
+	Messages             []*Message // context window of the active branch
+	ErasedMessages       []*Message // messages of the active branch out of the context window
+	InactiveMessages     []*Message // messages of the other branches
+	ActiveLeafID         string     // last message of the active branch, new messages follow it
+	PendingSummary       []*Message // messages erased by a summarizing strategy and not yet in Summary
+	Summary              string     // rolling summary of the erased messages, or of the whole chat when the strategy does not summarize
+	SummaryTokens        int
 	Status               string
 	TokenUsage           int
+	Cost                 float64 // accumulated price of every completion of the chat
 	Configuration        *ChatConfiguration
 }
 
@@ -38,11 +138,13 @@ func NewChat(userID string, initialSystemMessage *Message, chatCfg *ChatConfigur
 		Configuration:        chatCfg,
 		TokenUsage:           0,
 	}
-	chat.AddMessage(initialSystemMessage)
-
 	if err := chat.Validate(); err != nil {
 		return nil, err
 	}
+
+	if err := chat.AddMessage(initialSystemMessage); err != nil {
+		return nil, err
+	}
 	return chat, nil
 }
 
@@ -53,27 +155,397 @@ func (c *Chat) Validate() error {
 	if c.Status != "active" && c.Status != "ended" {
 		return errors.New("invalid status")
 	}
-	if c.Configuration.Temperature < 0 || c.Configuration.Temperature > 2 {
-		return errors.New("invalid temperature")
+	if c.Configuration == nil {
+		return errors.New("configuration is empty")
 	}
-	return nil
+	return c.Configuration.Validate()
 }
 
+// AddMessage appends the message to the active branch, erasing older messages until it fits.
 func (c *Chat) AddMessage(message *Message) error {
 	if c.Status == "ended" {
 		return errors.New("chat is ended. no more message allowed")
 	}
 
-	for {
-		if c.Configuration.Model.GetMaxTokens() >= message.GetQtdTokens()+c.TokenUsage {
-			c.Messages = append(c.Messages, message)
-			c.RefreshTokenUsage()
-			break
+	parentID, branch := c.ActiveLeafID, c.branchFor(c.ActiveLeafID)
+	if err := c.fit(message); err != nil {
+		return err
+	}
+	message.ParentID = parentID
+	message.Branch = branch
+	c.ActiveLeafID = message.ID
+	return nil
+}
+
+// fit appends the message to the context window, see AddMessage.
+func (c *Chat) fit(message *Message) error {
+	strategy := c.Configuration.Strategy()
+	budget := c.PromptBudget() - strategy.ReservedTokens()
+	required := message.GetQtdTokens() + TokensPerMessage
+
+	// evict on a copy so the chat is untouched when the message cannot fit
+	window := &Chat{
+		InitialSystemMessage: c.InitialSystemMessage,
+		Messages:             append([]*Message(nil), c.Messages...),
+	}
+	var erased []*Message
+	for window.messagesTokenUsage()+required > budget {
+		i := strategy.NextEviction(window)
+		if i < 0 {
+			return &MessageTooLargeError{Tokens: required, Budget: budget - window.messagesTokenUsage()}
+		}
+		erased = append(erased, window.Messages[i])
+		window.Messages = append(window.Messages[:i:i], window.Messages[i+1:]...)
+	}
+
+	c.Messages = append(window.Messages, message)
+	c.ErasedMessages = append(c.ErasedMessages, erased...)
+	if strategy.Summarizes() {
+		c.PendingSummary = append(c.PendingSummary, erased...)
+	}
+	c.RefreshTokenUsage()
+	return nil
+}
+
+// branchFor returns the branch of a new child of parentID.
+func (c *Chat) branchFor(parentID string) int {
+	parentBranch, next, hasChild := 0, 0, false
+	for _, message := range c.AllMessages() {
+		if message.ID == parentID {
+			parentBranch = message.Branch
+		}
+		if parentID != "" && message.ParentID == parentID {
+			hasChild = true
+		}
+		if message.Branch >= next {
+			next = message.Branch + 1
+		}
+	}
+	if hasChild {
+		return next
+	}
+	return parentBranch
+}
+
+// AllMessages returns every message of the conversation tree.
+func (c *Chat) AllMessages() []*Message {
+	messages := make([]*Message, 0, len(c.ErasedMessages)+len(c.Messages)+len(c.InactiveMessages))
+	messages = append(messages, c.ErasedMessages...)
+	messages = append(messages, c.Messages...)
+	return append(messages, c.InactiveMessages...)
+}
+
+// PathTo returns the messages from the root down to messageID.
+func (c *Chat) PathTo(messageID string) ([]*Message, error) {
+	byID := make(map[string]*Message)
+	for _, message := range c.AllMessages() {
+		byID[message.ID] = message
+	}
+	if _, ok := byID[messageID]; !ok {
+		return nil, ErrMessageNotFound
+	}
+
+	var path []*Message
+	for message := byID[messageID]; message != nil; message = byID[message.ParentID] {
+		path = append([]*Message{message}, path...)
+		if len(path) > len(byID) {
+			return nil, errors.New("conversation tree has a cycle")
+		}
+	}
+	return path, nil
+}
+
+// Leaves returns the last message of every branch.
+func (c *Chat) Leaves() []*Message {
+	parents := make(map[string]bool)
+	for _, message := range c.AllMessages() {
+		parents[message.ParentID] = true
+	}
+
+	var leaves []*Message
+	for _, message := range c.AllMessages() {
+		if !parents[message.ID] {
+			leaves = append(leaves, message)
+		}
+	}
+	return leaves
+}
+
+// SwitchBranch makes the path down to messageID the active branch.
+func (c *Chat) SwitchBranch(messageID string) error {
+	if c.Status == "ended" {
+		return errors.New("chat is ended. no more message allowed")
+	}
+
+	path, err := c.PathTo(messageID)
+	if err != nil {
+		return err
+	}
+	onPath := make(map[string]bool, len(path))
+	for _, message := range path {
+		onPath[message.ID] = true
+	}
+	var inactive []*Message
+	for _, message := range c.AllMessages() {
+		if !onPath[message.ID] {
+			inactive = append(inactive, message)
 		}
+	}
 
-		c.ErasedMessages = append(c.ErasedMessages, c.Messages[0])
-		c.Messages = c.Messages[1:]
-		c.RefreshTokenUsage()
+	// rebuild on a copy so the chat is untouched when the path does not fit
+	switched := *c
+	switched.Messages, switched.ErasedMessages, switched.PendingSummary = nil, nil, nil
+	switched.InactiveMessages = inactive
+	if c.Configuration.Strategy().Summarizes() {
+		switched.Summary, switched.SummaryTokens = "", 0
+	}
+	for _, message := range path {
+		if err := switched.fit(message); err != nil {
+			return err
+		}
+	}
+	switched.ActiveLeafID = messageID
+	*c = switched
+	return nil
+}
+
+// Fork copies the conversation down to messageID into a new chat owned by userID.
+func (c *Chat) Fork(userID, messageID string) (*Chat, error) {
+	path, err := c.PathTo(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	configuration := *c.Configuration
+	configuration.Stop = append([]string(nil), c.Configuration.Stop...)
+	configuration.Tools = append([]string(nil), c.Configuration.Tools...)
+	configuration.ResponseSchema = append(json.RawMessage(nil), c.Configuration.ResponseSchema...)
+	fork := &Chat{
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		AssistantID:   c.AssistantID,
+		Title:         c.Title,
+		Status:        "active",
+		Configuration: &configuration,
+	}
+	if err := fork.Validate(); err != nil {
+		return nil, err
+	}
+
+	for _, message := range path {
+		copied := *message
+		copied.ID = uuid.New().String()
+		if c.InitialSystemMessage != nil && message.ID == c.InitialSystemMessage.ID {
+			fork.InitialSystemMessage = &copied
+		}
+		if err := fork.AddMessage(&copied); err != nil {
+			return nil, err
+		}
+	}
+	return fork, nil
+}
+
+// AddChoices adds the candidate replies, the first one continues the active branch.
+func (c *Chat) AddChoices(choices []*Message) error {
+	parentID := c.ActiveLeafID
+	for i, choice := range choices {
+		if i == 0 {
+			if err := c.AddMessage(choice); err != nil {
+				return err
+			}
+			continue
+		}
+		choice.ParentID = parentID
+		choice.Branch = c.branchFor(parentID)
+		c.InactiveMessages = append(c.InactiveMessages, choice)
+	}
+	return nil
+}
+
+// CommitChoice replaces the last reply with one of its sibling candidates.
+func (c *Chat) CommitChoice(messageID string) error {
+	if c.Status == "ended" {
+		return errors.New("chat is ended. no more message allowed")
+	}
+
+	last := len(c.Messages) - 1
+	if last < 0 || c.Messages[last].Role != "assistant" {
+		return ErrChoiceNotFound
+	}
+	current := c.Messages[last]
+	if current.ID == messageID {
+		return nil
+	}
+	chosen := -1
+	for i, message := range c.InactiveMessages {
+		if message.ID == messageID && message.Role == "assistant" && message.ParentID == current.ParentID {
+			chosen = i
+		}
+	}
+	if chosen < 0 {
+		return ErrChoiceNotFound
+	}
+
+	messages := c.Messages
+	c.Messages = c.Messages[:last:last]
+	if err := c.fit(c.InactiveMessages[chosen]); err != nil {
+		c.Messages = messages
+		return err
+	}
+	c.ActiveLeafID = messageID
+	inactive := append(c.InactiveMessages[:chosen:chosen], c.InactiveMessages[chosen+1:]...)
+	c.InactiveMessages = append(inactive, current)
+	return nil
+}
+
+// DiscardLastReply moves the last reply out of the active branch, returning its user message.
+func (c *Chat) DiscardLastReply() (*Message, error) {
+	if c.Status == "ended" {
+		return nil, errors.New("chat is ended. no more message allowed")
+	}
+
+	last := len(c.Messages) - 1
+	if last >= 0 && c.Messages[last].Role == "user" {
+		return c.Messages[last], nil
+	}
+	question := last
+	if question >= 0 && c.Messages[question].Role == "assistant" && !c.Messages[question].IsToolStep() {
+		question--
+	}
+	for question >= 0 && c.Messages[question].IsToolStep() {
+		question--
+	}
+	if question < 0 || question == last || c.Messages[question].Role != "user" {
+		return nil, ErrNothingToRegenerate
+	}
+
+	c.InactiveMessages = append(c.InactiveMessages, c.Messages[question+1:]...)
+	c.Messages = c.Messages[:question+1]
+	c.ActiveLeafID = c.Messages[question].ID
+	c.RefreshTokenUsage()
+	return c.Messages[question], nil
+}
+
+// PromptBudget is the number of tokens left for the prompt.
+func (c *Chat) PromptBudget() int {
+	return c.Configuration.Model.GetMaxTokens() - c.Configuration.MaxTokens - TokensPerReply
+}
+
+// SetSummary replaces the rolling summary.
+func (c *Chat) SetSummary(summary string) {
+	c.Summary = summary
+	c.SummaryTokens = 0
+	if summary != "" {
+		c.SummaryTokens = CountTokens(c.Configuration.Model, summary)
+	}
+	c.PendingSummary = nil
+	c.RefreshTokenUsage()
+}
+
+// PromptMessages are the context window and summary sent to the provider.
+func (c *Chat) PromptMessages() []*Message {
+	window := completeToolSteps(c.Messages)
+	if c.Summary == "" || !c.Configuration.Strategy().Summarizes() {
+		return window
+	}
+
+	summary := &Message{
+		Role:      "system",
+		Content:   "Summary of the earlier conversation: " + c.Summary,
+		Tokens:    c.SummaryTokens,
+		Model:     c.Configuration.Model,
+		CreatedAt: time.Now(),
+	}
+	messages := make([]*Message, 0, len(window)+1)
+	inserted := false
+	for _, message := range window {
+		messages = append(messages, message)
+		if !inserted && c.InitialSystemMessage != nil && message.ID == c.InitialSystemMessage.ID {
+			messages = append(messages, summary)
+			inserted = true
+		}
+	}
+	if !inserted {
+		messages = append([]*Message{summary}, messages...)
+	}
+	return messages
+}
+
+// completeToolSteps drops the tool calls and results left incomplete by the window.
+func completeToolSteps(messages []*Message) []*Message {
+	answered := make(map[string]bool)
+	for _, message := range messages {
+		if message.Role == "tool" {
+			answered[message.ToolCallID] = true
+		}
+	}
+	kept := make(map[string]bool)
+	for _, message := range messages {
+		complete := true
+		for _, call := range message.ToolCalls {
+			complete = complete && answered[call.ID]
+		}
+		for _, call := range message.ToolCalls {
+			kept[call.ID] = complete
+		}
+	}
+
+	complete := make([]*Message, 0, len(messages))
+	for _, message := range messages {
+		if message.Role == "tool" && !kept[message.ToolCallID] {
+			continue
+		}
+		if len(message.ToolCalls) > 0 && !kept[message.ToolCalls[0].ID] {
+			continue
+		}
+		complete = append(complete, message)
+	}
+	return complete
+}
+
+// AddUsage accounts the price of one completion using the chat model prices.
+func (c *Chat) AddUsage(promptTokens, completionTokens int) {
+	c.Cost += c.Configuration.Model.Cost(promptTokens, completionTokens)
+}
+
+// FirstExchange returns the first user message and its reply.
+func (c *Chat) FirstExchange() []*Message {
+	for i, message := range c.Messages {
+		if message.Role != "user" {
+			continue
+		}
+		for _, reply := range c.Messages[i+1:] {
+			if reply.Role == "assistant" && !reply.IsToolStep() {
+				return []*Message{message, reply}
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// LastExchange returns the last reply and the user message it answers.
+func (c *Chat) LastExchange() []*Message {
+	for i := len(c.Messages) - 1; i >= 0; i-- {
+		if c.Messages[i].Role != "assistant" || c.Messages[i].IsToolStep() {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			if c.Messages[j].Role == "user" {
+				return []*Message{c.Messages[j], c.Messages[i]}
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// LastUserMessage returns the last user message of the active branch.
+func (c *Chat) LastUserMessage() *Message {
+	for i := len(c.Messages) - 1; i >= 0; i-- {
+		if c.Messages[i].Role == "user" {
+			return c.Messages[i]
+		}
 	}
 	return nil
 }
@@ -90,9 +562,18 @@ func (c *Chat) End() {
 	c.Status = "ended"
 }
 
+// RefreshTokenUsage counts the prompt tokens of the context window and the summary.
 func (c *Chat) RefreshTokenUsage() {
-	c.TokenUsage = 0
+	c.TokenUsage = c.messagesTokenUsage()
+	if c.Summary != "" && c.Configuration.Strategy().Summarizes() {
+		c.TokenUsage += c.SummaryTokens + TokensPerMessage
+	}
+}
+
+func (c *Chat) messagesTokenUsage() int {
+	usage := 0
 	for message := range c.Messages {
-		c.TokenUsage += c.Messages[message].GetQtdTokens()
+		usage += c.Messages[message].GetQtdTokens() + TokensPerMessage
 	}
+	return usage
 }
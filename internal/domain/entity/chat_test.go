@@ -0,0 +1,325 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// testMessage builds the message with a fixed token count, so the tests do
+// not depend on the tokenizer.
+func testMessage(role string, tokens int, pinned bool) *Message {
+	return &Message{
+		ID:        uuid.New().String(),
+		Role:      role,
+		Content:   role,
+		Tokens:    tokens,
+		Pinned:    pinned,
+		CreatedAt: time.Now(),
+	}
+}
+
+// testChat has a prompt budget of 1000 - 100 - TokensPerReply = 897 tokens,
+// the initial system message uses 7 + TokensPerMessage of them.
+func testChat(t *testing.T, strategy string) *Chat {
+	t.Helper()
+	chat, err := NewChat("user", testMessage("system", 7, false), &ChatConfiguration{
+		Model:           NewModel("test", 1000),
+		N:               1,
+		MaxTokens:       100,
+		ContextStrategy: strategy,
+	})
+	if err != nil {
+		t.Fatalf("NewChat: %v", err)
+	}
+	return chat
+}
+
+func messageIDs(messages []*Message) []string {
+	ids := make([]string, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+	return ids
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestChatFit(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		pinned   []bool // of the messages added before the last one
+		tokens   []int  // of every message added, the last one triggers the eviction
+		window   []int  // indices of the added messages left in the window after the system message
+		erased   []int
+		pending  []int
+	}{
+		{
+			name:     "keeps every message that fits",
+			strategy: ContextStrategySlidingWindow,
+			pinned:   []bool{false, false},
+			tokens:   []int{300, 300, 100},
+			window:   []int{0, 1, 2},
+		},
+		{
+			name:     "sliding window erases the oldest message even when pinned",
+			strategy: ContextStrategySlidingWindow,
+			pinned:   []bool{true, false},
+			tokens:   []int{400, 400, 100},
+			window:   []int{1, 2},
+			erased:   []int{0},
+		},
+		{
+			name:     "sliding window erases until the message fits",
+			strategy: ContextStrategySlidingWindow,
+			pinned:   []bool{false, false},
+			tokens:   []int{400, 400, 500},
+			window:   []int{2},
+			erased:   []int{0, 1},
+		},
+		{
+			name:     "pinned strategy keeps pinned messages",
+			strategy: ContextStrategyPinned,
+			pinned:   []bool{true, false},
+			tokens:   []int{400, 400, 100},
+			window:   []int{0, 2},
+			erased:   []int{1},
+		},
+		{
+			name:     "summarize reserves the summary tokens and queues the erased messages",
+			strategy: ContextStrategySummarize,
+			pinned:   []bool{false, false},
+			tokens:   []int{300, 300, 100},
+			window:   []int{1, 2},
+			erased:   []int{0},
+			pending:  []int{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat := testChat(t, tt.strategy)
+			var added []*Message
+			for i, tokens := range tt.tokens {
+				message := testMessage("user", tokens, i < len(tt.pinned) && tt.pinned[i])
+				if err := chat.AddMessage(message); err != nil {
+					t.Fatalf("AddMessage %d: %v", i, err)
+				}
+				added = append(added, message)
+			}
+
+			pick := func(indices []int) []*Message {
+				var messages []*Message
+				for _, i := range indices {
+					messages = append(messages, added[i])
+				}
+				return messages
+			}
+			window := append([]*Message{chat.InitialSystemMessage}, pick(tt.window)...)
+			if !equalIDs(messageIDs(chat.Messages), messageIDs(window)) {
+				t.Errorf("window = %v, want %v", messageIDs(chat.Messages), messageIDs(window))
+			}
+			if !equalIDs(messageIDs(chat.ErasedMessages), messageIDs(pick(tt.erased))) {
+				t.Errorf("erased = %v, want %v", messageIDs(chat.ErasedMessages), messageIDs(pick(tt.erased)))
+			}
+			if !equalIDs(messageIDs(chat.PendingSummary), messageIDs(pick(tt.pending))) {
+				t.Errorf("pending summary = %v, want %v", messageIDs(chat.PendingSummary), messageIDs(pick(tt.pending)))
+			}
+			if chat.TokenUsage > chat.PromptBudget()-chat.Configuration.Strategy().ReservedTokens() {
+				t.Errorf("token usage %d exceeds the budget", chat.TokenUsage)
+			}
+		})
+	}
+}
+
+func TestChatFitMessageTooLarge(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		pinned   bool
+		tokens   int
+	}{
+		{name: "larger than the whole window", strategy: ContextStrategySlidingWindow, tokens: 900},
+		{name: "pinned messages cannot make room", strategy: ContextStrategyPinned, pinned: true, tokens: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat := testChat(t, tt.strategy)
+			if err := chat.AddMessage(testMessage("user", 400, tt.pinned)); err != nil {
+				t.Fatalf("AddMessage: %v", err)
+			}
+			before := messageIDs(chat.Messages)
+			usage := chat.TokenUsage
+
+			err := chat.AddMessage(testMessage("user", tt.tokens, false))
+			var tooLarge *MessageTooLargeError
+			if !errors.As(err, &tooLarge) {
+				t.Fatalf("err = %v, want a MessageTooLargeError", err)
+			}
+			if !equalIDs(messageIDs(chat.Messages), before) || chat.TokenUsage != usage || len(chat.ErasedMessages) > 0 {
+				t.Errorf("chat changed by a message that does not fit")
+			}
+		})
+	}
+}
+
+func TestChatBranches(t *testing.T) {
+	chat := testChat(t, ContextStrategySlidingWindow)
+	question := testMessage("user", 10, false)
+	answer := testMessage("assistant", 10, false)
+	for _, message := range []*Message{question, answer} {
+		if err := chat.AddMessage(message); err != nil {
+			t.Fatalf("AddMessage: %v", err)
+		}
+	}
+
+	// a second child of the initial system message starts a new branch
+	if err := chat.SwitchBranch(chat.InitialSystemMessage.ID); err != nil {
+		t.Fatalf("SwitchBranch: %v", err)
+	}
+	edited := testMessage("user", 10, false)
+	if err := chat.AddMessage(edited); err != nil {
+		t.Fatalf("AddMessage: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		message *Message
+		parent  string
+		branch  int
+	}{
+		{name: "first child continues the parent branch", message: question, parent: chat.InitialSystemMessage.ID, branch: 0},
+		{name: "reply follows the question", message: answer, parent: question.ID, branch: 0},
+		{name: "second child starts a new branch", message: edited, parent: chat.InitialSystemMessage.ID, branch: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.message.ParentID != tt.parent || tt.message.Branch != tt.branch {
+				t.Errorf("parent %q branch %d, want parent %q branch %d", tt.message.ParentID, tt.message.Branch, tt.parent, tt.branch)
+			}
+		})
+	}
+
+	want := []string{chat.InitialSystemMessage.ID, edited.ID}
+	if !equalIDs(messageIDs(chat.Messages), want) {
+		t.Errorf("window = %v, want %v", messageIDs(chat.Messages), want)
+	}
+	if !equalIDs(messageIDs(chat.InactiveMessages), []string{question.ID, answer.ID}) {
+		t.Errorf("inactive = %v, want the first branch", messageIDs(chat.InactiveMessages))
+	}
+	if leaves := chat.Leaves(); len(leaves) != 2 {
+		t.Errorf("got %d leaves, want 2", len(leaves))
+	}
+
+	if err := chat.SwitchBranch(answer.ID); err != nil {
+		t.Fatalf("SwitchBranch back: %v", err)
+	}
+	want = []string{chat.InitialSystemMessage.ID, question.ID, answer.ID}
+	if !equalIDs(messageIDs(chat.Messages), want) || chat.ActiveLeafID != answer.ID {
+		t.Errorf("window = %v leaf %q, want %v leaf %q", messageIDs(chat.Messages), chat.ActiveLeafID, want, answer.ID)
+	}
+
+	if err := chat.SwitchBranch("unknown"); !errors.Is(err, ErrMessageNotFound) {
+		t.Errorf("SwitchBranch unknown message: err = %v, want ErrMessageNotFound", err)
+	}
+
+	// a branch that no longer fits the window leaves the chat untouched
+	if err := chat.SwitchBranch(chat.InitialSystemMessage.ID); err != nil {
+		t.Fatalf("SwitchBranch: %v", err)
+	}
+	large := testMessage("user", 300, false)
+	if err := chat.AddMessage(large); err != nil {
+		t.Fatalf("AddMessage: %v", err)
+	}
+	if err := chat.SwitchBranch(answer.ID); err != nil {
+		t.Fatalf("SwitchBranch: %v", err)
+	}
+	chat.Configuration.MaxTokens = 800
+	var tooLarge *MessageTooLargeError
+	if err := chat.SwitchBranch(large.ID); !errors.As(err, &tooLarge) {
+		t.Fatalf("SwitchBranch to a large message: err = %v, want a MessageTooLargeError", err)
+	}
+	if !equalIDs(messageIDs(chat.Messages), want) || chat.ActiveLeafID != answer.ID {
+		t.Errorf("window = %v leaf %q after a failed switch, want %v leaf %q", messageIDs(chat.Messages), chat.ActiveLeafID, want, answer.ID)
+	}
+}
+
+func TestChatFork(t *testing.T) {
+	chat := testChat(t, ContextStrategySlidingWindow)
+	chat.Configuration.Stop = []string{"stop"}
+	question := testMessage("user", 10, false)
+	answer := testMessage("assistant", 10, false)
+	followUp := testMessage("user", 10, false)
+	for _, message := range []*Message{question, answer, followUp} {
+		if err := chat.AddMessage(message); err != nil {
+			t.Fatalf("AddMessage: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		messageID string
+		roles     []string
+		err       error
+	}{
+		{name: "down to a reply", messageID: answer.ID, roles: []string{"system", "user", "assistant"}},
+		{name: "whole conversation", messageID: followUp.ID, roles: []string{"system", "user", "assistant", "user"}},
+		{name: "only the system message", messageID: chat.InitialSystemMessage.ID, roles: []string{"system"}},
+		{name: "unknown message", messageID: "unknown", err: ErrMessageNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fork, err := chat.Fork("other", tt.messageID)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fork: %v", err)
+			}
+
+			if fork.ID == chat.ID || fork.UserID != "other" || fork.Status != "active" {
+				t.Errorf("fork id %q user %q status %q", fork.ID, fork.UserID, fork.Status)
+			}
+			if len(fork.Messages) != len(tt.roles) {
+				t.Fatalf("fork has %d messages, want %d", len(fork.Messages), len(tt.roles))
+			}
+			original := make(map[string]bool)
+			for _, message := range chat.AllMessages() {
+				original[message.ID] = true
+			}
+			for i, message := range fork.Messages {
+				if message.Role != tt.roles[i] {
+					t.Errorf("message %d role %q, want %q", i, message.Role, tt.roles[i])
+				}
+				if original[message.ID] {
+					t.Errorf("message %d keeps the id of the source chat", i)
+				}
+			}
+			if fork.InitialSystemMessage != fork.Messages[0] || fork.ActiveLeafID != fork.Messages[len(fork.Messages)-1].ID {
+				t.Errorf("fork does not start at its system message or continue from its last message")
+			}
+
+			fork.Configuration.Stop[0] = "changed"
+			if chat.Configuration.Stop[0] != "stop" {
+				t.Errorf("fork shares the configuration of the source chat")
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package entity
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	ContentPartText        = "text"
+	ContentPartImageURL    = "image_url"
+	ContentPartImageBase64 = "image_base64"
+	ContentPartAttachment  = "attachment"
+)
+
+const (
+	MaxContentParts = 16
+	MaxImageParts   = 4
+	MaxImageURLSize = 2048            // characters of an image URL
+	MaxImageSize    = 2 * 1024 * 1024 // decoded bytes of a base64 image
+)
+
+// Images are not tokenized as text: the provider bills a fixed amount at low
+// detail and, at high detail, per 512px tile. The size of the image is not
+// known here, so high detail counts a 1024x1024 image.
+const (
+	imageTokensLow  = 85
+	imageTokensHigh = 765
+)
+
+var imageMediaTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// ContentPart is one piece of a multimodal user message: text, an image
+// fetched by the provider from URL, an image sent inline as base64 Data or
+// an image uploaded to the chat, referenced by AttachmentID.
+type ContentPart struct {
+	Type         string `json:"type"`
+	Text         string `json:"text,omitempty"`
+	URL          string `json:"url,omitempty"`
+	MediaType    string `json:"media_type,omitempty"` // of base64 images and attachments, e.g. image/png
+	Data         string `json:"data,omitempty"`
+	AttachmentID string `json:"attachment_id,omitempty"`
+	Detail       string `json:"detail,omitempty"` // low, high or auto, the provider default when empty
+}
+
+func (p ContentPart) IsImage() bool {
+	return p.Type == ContentPartImageURL || p.Type == ContentPartImageBase64 || p.Type == ContentPartAttachment
+}
+
+// ImageURL returns the URL sent to the provider, base64 images are sent as a
+// data URL. Attachments are loaded from the attachment store instead.
+func (p ContentPart) ImageURL() string {
+	if p.Type == ContentPartImageBase64 {
+		return "data:" + p.MediaType + ";base64," + p.Data
+	}
+	return p.URL
+}
+
+// ValidateContentParts checks the parts of a message, reporting each invalid
+// field as content[i].field.
+func ValidateContentParts(parts []ContentPart) error {
+	verr := &ValidationError{Entity: "message"}
+	if len(parts) == 0 {
+		verr.Add("content", "is empty")
+		return verr.Err()
+	}
+	if len(parts) > MaxContentParts {
+		verr.Add("content", fmt.Sprintf("must have at most %d parts", MaxContentParts))
+		return verr.Err()
+	}
+
+	images := 0
+	for i, part := range parts {
+		field := fmt.Sprintf("content[%d]", i)
+		switch part.Type {
+		case ContentPartText:
+			if strings.TrimSpace(part.Text) == "" {
+				verr.Add(field+".text", "is empty")
+			}
+		case ContentPartImageURL:
+			images++
+			parsed, err := url.Parse(part.URL)
+			if part.URL == "" {
+				verr.Add(field+".url", "is empty")
+			} else if len(part.URL) > MaxImageURLSize {
+				verr.Add(field+".url", fmt.Sprintf("must have at most %d characters", MaxImageURLSize))
+			} else if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				verr.Add(field+".url", "must be an http or https URL")
+			}
+		case ContentPartImageBase64:
+			images++
+			if !imageMediaTypes[part.MediaType] {
+				verr.Add(field+".media_type", "must be image/png, image/jpeg, image/gif or image/webp")
+			}
+			if part.Data == "" {
+				verr.Add(field+".data", "is empty")
+			} else if base64.StdEncoding.DecodedLen(len(part.Data)) > MaxImageSize+2 {
+				verr.Add(field+".data", fmt.Sprintf("must have at most %d bytes", MaxImageSize))
+			} else if decoded, err := base64.StdEncoding.DecodeString(part.Data); err != nil {
+				verr.Add(field+".data", "is not valid base64")
+			} else if len(decoded) > MaxImageSize {
+				verr.Add(field+".data", fmt.Sprintf("must have at most %d bytes", MaxImageSize))
+			}
+		case ContentPartAttachment:
+			images++
+			if part.AttachmentID == "" {
+				verr.Add(field+".attachment_id", "is empty")
+			} else if !imageMediaTypes[part.MediaType] {
+				verr.Add(field+".attachment_id", "is not an image")
+			}
+		default:
+			verr.Add(field+".type", fmt.Sprintf("unknown content part type %q", part.Type))
+			continue
+		}
+		if part.Detail != "" && !part.IsImage() {
+			verr.Add(field+".detail", "only applies to images")
+		} else if part.Detail != "" && part.Detail != "low" && part.Detail != "high" && part.Detail != "auto" {
+			verr.Add(field+".detail", "must be low, high or auto")
+		}
+	}
+	if images > MaxImageParts {
+		verr.Add("content", fmt.Sprintf("must have at most %d images", MaxImageParts))
+	}
+	return verr.Err()
+}
+
+// contentPartsText joins the text parts, the content kept for titles,
+// summaries and the other features that only read text.
+func contentPartsText(parts []ContentPart) string {
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Type == ContentPartText {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func contentPartsTokens(model *Model, parts []ContentPart) int {
+	tokens := 0
+	for _, part := range parts {
+		switch {
+		case part.Type == ContentPartText:
+			tokens += CountTokens(model, part.Text)
+		case part.Detail == "low":
+			tokens += imageTokensLow
+		default:
+			tokens += imageTokensHigh
+		}
+	}
+	return tokens
+}
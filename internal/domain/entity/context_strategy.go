@@ -0,0 +1,92 @@
+package entity
+
+import "fmt"
+
+const (
+	ContextStrategySlidingWindow = "sliding_window"
+	ContextStrategyPinned        = "pinned"
+	ContextStrategySummarize     = "summarize"
+
+	SummaryMaxTokens = 256 // tokens kept aside for the rolling summary
+)
+
+// ContextStrategy decides which messages leave the context window when a new
+// message does not fit.
+type ContextStrategy interface {
+	Name() string
+	// NextEviction returns the index in chat.Messages of the next message to
+	// erase, or -1 when no message may be erased.
+	NextEviction(chat *Chat) int
+	// ReservedTokens are kept out of the prompt budget for the strategy own use.
+	ReservedTokens() int
+	// Summarizes reports whether erased messages are folded into the chat
+	// summary instead of being dropped from the prompt.
+	Summarizes() bool
+}
+
+func NewContextStrategy(name string) (ContextStrategy, error) {
+	switch name {
+	case "", ContextStrategySlidingWindow:
+		return SlidingWindowStrategy{}, nil
+	case ContextStrategyPinned:
+		return PinnedMessagesStrategy{}, nil
+	case ContextStrategySummarize:
+		return SummarizingStrategy{}, nil
+	}
+	return nil, fmt.Errorf("unknown context strategy %q", name)
+}
+
+// SlidingWindowStrategy erases the oldest messages first, keeping only the
+// initial system message.
+type SlidingWindowStrategy struct{}
+
+func (SlidingWindowStrategy) Name() string { return ContextStrategySlidingWindow }
+
+func (SlidingWindowStrategy) NextEviction(chat *Chat) int {
+	return oldestMessage(chat, false)
+}
+
+func (SlidingWindowStrategy) ReservedTokens() int { return 0 }
+
+func (SlidingWindowStrategy) Summarizes() bool { return false }
+
+// PinnedMessagesStrategy is a sliding window that never erases pinned
+// messages.
+type PinnedMessagesStrategy struct{}
+
+func (PinnedMessagesStrategy) Name() string { return ContextStrategyPinned }
+
+func (PinnedMessagesStrategy) NextEviction(chat *Chat) int {
+	return oldestMessage(chat, true)
+}
+
+func (PinnedMessagesStrategy) ReservedTokens() int { return 0 }
+
+func (PinnedMessagesStrategy) Summarizes() bool { return false }
+
+// SummarizingStrategy erases like PinnedMessagesStrategy and compresses the
+// erased messages into a rolling summary sent along with the prompt.
+type SummarizingStrategy struct{}
+
+func (SummarizingStrategy) Name() string { return ContextStrategySummarize }
+
+func (SummarizingStrategy) NextEviction(chat *Chat) int {
+	return oldestMessage(chat, true)
+}
+
+func (SummarizingStrategy) ReservedTokens() int { return SummaryMaxTokens + TokensPerMessage }
+
+func (SummarizingStrategy) Summarizes() bool { return true }
+
+func oldestMessage(chat *Chat, keepPinned bool) int {
+	for i, message := range chat.Messages {
+		if chat.InitialSystemMessage != nil && message.ID == chat.InitialSystemMessage.ID {
+			continue
+		}
+		if keepPinned && message.Pinned {
+			continue
+		}
+		return i
+	}
+	return -1
+}
@@ -0,0 +1,194 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MaxDocumentSize      = 512 * 1024 // bytes of text accepted per document
+	MaxDocumentName      = 255
+	DocumentChunkSize    = 1000 // characters per chunk
+	DocumentChunkOverlap = 200  // characters repeated at the start of the next chunk so text cut at a boundary stays whole in one of them
+)
+
+// Document is a text attached to a chat. It is split into chunks that are
+// embedded so the ones related to a question can be added to the prompt.
+type Document struct {
+	ID         string
+	ChatID     string
+	UserID     string
+	Name       string
+	Chunks     []*DocumentChunk
+	ChunkCount int // kept when the document is loaded without its chunks
+	CreatedAt  time.Time
+}
+
+type DocumentChunk struct {
+	ID           string
+	DocumentID   string
+	DocumentName string
+	Position     int // order of the chunk in the document, from 0
+	Content      string
+	Embedding    []float32
+}
+
+// Source names the chunk in citations, e.g. report.txt#3 for the third chunk.
+func (c *DocumentChunk) Source() string {
+	return fmt.Sprintf("%s#%d", c.DocumentName, c.Position+1)
+}
+
+// ScoredChunk is a chunk found for a query, Score is the cosine similarity
+// of their embeddings.
+type ScoredChunk struct {
+	Chunk *DocumentChunk
+	Score float64
+}
+
+func NewDocument(chatID, userID, name, content string) (*Document, error) {
+	verr := &ValidationError{Entity: "document"}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		verr.Add("name", "is empty")
+	} else if utf8.RuneCountInString(name) > MaxDocumentName {
+		verr.Add("name", fmt.Sprintf("must have at most %d characters", MaxDocumentName))
+	}
+	if strings.TrimSpace(content) == "" {
+		verr.Add("content", "is empty")
+	} else if len(content) > MaxDocumentSize {
+		verr.Add("content", fmt.Sprintf("must have at most %d bytes", MaxDocumentSize))
+	} else if !utf8.ValidString(content) {
+		verr.Add("content", "is not UTF-8 text")
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	document := &Document{
+		ID:        uuid.New().String(),
+		ChatID:    chatID,
+		UserID:    userID,
+		Name:      name,
+		CreatedAt: time.Now(),
+	}
+	for i, text := range splitChunks(content, DocumentChunkSize, DocumentChunkOverlap) {
+		document.Chunks = append(document.Chunks, &DocumentChunk{
+			ID:           uuid.New().String(),
+			DocumentID:   document.ID,
+			DocumentName: name,
+			Position:     i,
+			Content:      text,
+		})
+	}
+	document.ChunkCount = len(document.Chunks)
+	return document, nil
+}
+
+// ChunkContents returns the text of every chunk, in the order expected by
+// SetEmbeddings.
+func (d *Document) ChunkContents() []string {
+	contents := make([]string, 0, len(d.Chunks))
+	for _, chunk := range d.Chunks {
+		contents = append(contents, chunk.Content)
+	}
+	return contents
+}
+
+func (d *Document) SetEmbeddings(embeddings [][]float32) error {
+	if len(embeddings) != len(d.Chunks) {
+		return fmt.Errorf("got %d embeddings for %d chunks", len(embeddings), len(d.Chunks))
+	}
+	for i, embedding := range embeddings {
+		if len(embedding) == 0 {
+			return errors.New("embedding of chunk " + d.Chunks[i].Source() + " is empty")
+		}
+		d.Chunks[i].Embedding = embedding
+	}
+	return nil
+}
+
+// TopChunks ranks the chunks by similarity to the query embedding and
+// returns the k best. Chunks embedded with another dimension, by a previous
+// embedding model, are skipped.
+func TopChunks(chunks []*DocumentChunk, query []float32, k int) []ScoredChunk {
+	scored := make([]ScoredChunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		if len(chunk.Embedding) != len(query) {
+			continue
+		}
+		scored = append(scored, ScoredChunk{Chunk: chunk, Score: cosineSimilarity(chunk.Embedding, query)})
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].Score > scored[j].Score
+	})
+	if len(scored) > k {
+		scored = scored[:k]
+	}
+	return scored
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// splitChunks cuts content into chunks of at most size characters, ending
+// them at a paragraph, sentence or word boundary when one is found in their
+// second half. Each chunk starts overlap characters before the end of the
+// previous one.
+func splitChunks(content string, size, overlap int) []string {
+	runes := []rune(content)
+	var chunks []string
+	for start := 0; start < len(runes); {
+		end := start + size
+		if end >= len(runes) {
+			end = len(runes)
+		} else {
+			end = chunkBoundary(runes, start+size/2, end)
+		}
+		if text := strings.TrimSpace(string(runes[start:end])); text != "" {
+			chunks = append(chunks, text)
+		}
+		if end == len(runes) {
+			break
+		}
+
+		next := end - overlap
+		if next <= start {
+			next = end
+		}
+		// start the overlap at a word so chunks do not begin mid-word
+		for next < end && !unicode.IsSpace(runes[next-1]) {
+			next++
+		}
+		start = next
+	}
+	return chunks
+}
+
+// chunkBoundary returns the best place to cut runes between from and to.
+func chunkBoundary(runes []rune, from, to int) int {
+	text := string(runes[from:to])
+	for _, separator := range []string{"\n\n", "\n", ". ", " "} {
+		if i := strings.LastIndex(text, separator); i >= 0 {
+			return from + utf8.RuneCountInString(text[:i+len(separator)])
+		}
+	}
+	return to
+}
@@ -2,23 +2,36 @@ package entity
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	tiktoken_go "github.com/j178/tiktoken-go"
 )
 
+const (
+	FinishReasonStop      = "stop"
+	FinishReasonCancelled = "cancelled"
+)
+
 type Message struct {
-	ID        string
-	Role      string
-	Content   string
-	Tokens    int
-	Model     *Model
-	CreatedAt time.Time
+	ID           string
+	Role         string
+	Content      string
+	Tokens       int
+	Model        *Model
+	FinishReason string        // why the generation of an assistant message ended, e.g. stop or cancelled
+	Pinned       bool          // pinned messages are kept in the context window by the pinned and summarize strategies
+	ParentID     string        // message this one follows in the conversation tree, empty for the initial system message
+	Branch       int           // messages written one after the other share a branch, a second child of a message starts a new one
+	ToolCalls    []ToolCall    // tools an assistant message asks to run, its content may then be empty
+	ToolCallID   string        // call answered by a tool message
+	Parts        []ContentPart // text and images of a multimodal user message, Content then holds the text parts
+	CreatedAt    time.Time
 }
 
 func NewMessage(role, content string, model *Model) (*Message, error) {
-	totalTokens := tiktoken_go.CountTokens(model.GetName(), content)
+	totalTokens := CountTokens(model, content)
 	msg := &Message{
 		ID:        uuid.New().String(),
 		Role:      role,
@@ -34,12 +47,96 @@ func NewMessage(role, content string, model *Model) (*Message, error) {
 	return msg, nil
 }
 
+// NewMultimodalMessage is a user message made of content parts. Images are
+// only accepted by models that declare vision support.
+func NewMultimodalMessage(parts []ContentPart, model *Model) (*Message, error) {
+	if err := ValidateContentParts(parts); err != nil {
+		return nil, err
+	}
+	for _, part := range parts {
+		if part.IsImage() && !model.Vision {
+			verr := &ValidationError{Entity: "message"}
+			verr.Add("content", fmt.Sprintf("model %q does not accept images", model.GetName()))
+			return nil, verr
+		}
+	}
+
+	msg := &Message{
+		ID:        uuid.New().String(),
+		Role:      "user",
+		Content:   contentPartsText(parts),
+		Tokens:    contentPartsTokens(model, parts),
+		Model:     model,
+		Parts:     parts,
+		CreatedAt: time.Now(),
+	}
+
+	if err := msg.Validate(); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// NewToolCallMessage is the assistant message asking to run the calls. The
+// calls count towards its tokens as they are sent back in the prompt.
+func NewToolCallMessage(content string, calls []ToolCall, model *Model) (*Message, error) {
+	totalTokens := CountTokens(model, content)
+	for _, call := range calls {
+		totalTokens += CountTokens(model, call.Name) + CountTokens(model, call.Arguments)
+	}
+	msg := &Message{
+		ID:        uuid.New().String(),
+		Role:      "assistant",
+		Content:   content,
+		Tokens:    totalTokens,
+		Model:     model,
+		ToolCalls: calls,
+		CreatedAt: time.Now(),
+	}
+
+	if err := msg.Validate(); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// NewToolResultMessage answers the call toolCallID with the tool output.
+func NewToolResultMessage(toolCallID, content string, model *Model) (*Message, error) {
+	msg := &Message{
+		ID:         uuid.New().String(),
+		Role:       "tool",
+		Content:    content,
+		Tokens:     CountTokens(model, content),
+		Model:      model,
+		ToolCallID: toolCallID,
+		CreatedAt:  time.Now(),
+	}
+
+	if err := msg.Validate(); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
 func (m *Message) Validate() error {
-	if m.Role != "user" && m.Role != "system" && m.Role != "assistant" {
+	if m.Role != "user" && m.Role != "system" && m.Role != "assistant" && m.Role != "tool" {
 		return errors.New("invalid role")
 	}
 
-	if m.Content == "" {
+	if len(m.ToolCalls) > 0 && m.Role != "assistant" {
+		return errors.New("only assistant messages call tools")
+	}
+
+	if len(m.Parts) > 0 && m.Role != "user" {
+		return errors.New("only user messages have content parts")
+	}
+
+	if m.Role == "tool" && m.ToolCallID == "" {
+		return errors.New("tool call id is empty")
+	}
+
+	// tools may return nothing and tool calls may come without text
+	if m.Content == "" && m.Role != "tool" && len(m.ToolCalls) == 0 && len(m.Parts) == 0 {
 		return errors.New("content is empty")
 	}
 
@@ -49,6 +146,16 @@ func (m *Message) Validate() error {
 	return nil
 }
 
+func CountTokens(model *Model, content string) int {
+	return tiktoken_go.CountTokens(model.GetTokenizerModel(), content)
+}
+
+// IsToolStep reports whether the message is a tool call or a tool result,
+// the steps taken by the assistant before its reply.
+func (m *Message) IsToolStep() bool {
+	return m.Role == "tool" || len(m.ToolCalls) > 0
+}
+
 func (m *Message) GetQtdTokens() int {
 	return m.Tokens
 }
@@ -0,0 +1,23 @@
+package entity
+
+import "testing"
+
+func TestCountTokens(t *testing.T) {
+	tests := []struct {
+		name  string
+		model *Model
+		want  int
+	}{
+		{name: "gpt-4o by name", model: &Model{Name: "gpt-4o"}, want: 2},
+		{name: "o200k_base encoding", model: &Model{Name: "gpt-4o-mini-2024-07-18", Encoding: "o200k_base"}, want: 2},
+		{name: "cl100k_base encoding", model: &Model{Name: "gpt-4-turbo", Encoding: "cl100k_base"}, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CountTokens(tt.model, "hello world"); got != tt.want {
+				t.Errorf("CountTokens = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,23 @@
 package entity
 
 type Model struct {
-	Name      string
-	MaxTokens int
+	Name             string
+	Provider         string
+	MaxTokens        int     // context window, prompt plus completion
+	MaxOutputTokens  int     // highest number of tokens the model generates in one completion
+	Encoding         string  // tokenizer encoding, e.g. cl100k_base
+	InputPricePer1K  float64 // price of 1000 prompt tokens
+	OutputPricePer1K float64 // price of 1000 completion tokens
+	Vision           bool    // accepts images in user messages
+}
+
+// encodingModels maps a tokenizer encoding to a model tiktoken knows about,
+// so models with other names can still be counted with the right encoding.
+var encodingModels = map[string]string{
+	"o200k_base":  "gpt-4o",
+	"cl100k_base": "gpt-3.5-turbo",
+	"p50k_base":   "text-davinci-003",
+	"r50k_base":   "davinci",
 }
 
 func NewModel(name string, maxTokens int) *Model {
@@ -16,6 +31,27 @@ func (m *Model) GetMaxTokens() int {
 	return m.MaxTokens
 }
 
+// GetMaxOutputTokens falls back to the context window when the model does
+// not declare a separate completion limit.
+func (m *Model) GetMaxOutputTokens() int {
+	if m.MaxOutputTokens > 0 {
+		return m.MaxOutputTokens
+	}
+	return m.MaxTokens
+}
+
 func (m *Model) GetName() string {
 	return m.Name
 }
+
+// GetTokenizerModel returns the model name used to count tokens.
+func (m *Model) GetTokenizerModel() string {
+	if tokenizer, ok := encodingModels[m.Encoding]; ok {
+		return tokenizer
+	}
+	return m.Name
+}
+
+func (m *Model) Cost(promptTokens, completionTokens int) float64 {
+	return float64(promptTokens)/1000*m.InputPricePer1K + float64(completionTokens)/1000*m.OutputPricePer1K
+}
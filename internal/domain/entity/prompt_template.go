@@ -0,0 +1,176 @@
+package entity
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MaxTemplateName        = 64
+	MaxTemplateDescription = 255
+	MaxTemplateSize        = 32 * 1024 // bytes of the template text
+)
+
+var templateNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// PromptTemplate is a system message written with text/template, e.g.
+// "You answer questions about {{.product}}". Saving a template again under
+// the same Name stores a new Version, chats keep the text rendered when they
+// were created.
+type PromptTemplate struct {
+	ID          string
+	Name        string
+	Version     int
+	Description string
+	Content     string
+	CreatedAt   time.Time
+}
+
+func NewPromptTemplate(name, description, content string, version int) (*PromptTemplate, error) {
+	verr := &ValidationError{Entity: "prompt template"}
+	if name == "" {
+		verr.Add("name", "is empty")
+	} else if len(name) > MaxTemplateName {
+		verr.Add("name", fmt.Sprintf("must have at most %d characters", MaxTemplateName))
+	} else if !templateNamePattern.MatchString(name) {
+		verr.Add("name", "must have only lowercase letters, digits, - and _")
+	}
+	if utf8.RuneCountInString(description) > MaxTemplateDescription {
+		verr.Add("description", fmt.Sprintf("must have at most %d characters", MaxTemplateDescription))
+	}
+	if strings.TrimSpace(content) == "" {
+		verr.Add("content", "is empty")
+	} else if len(content) > MaxTemplateSize {
+		verr.Add("content", fmt.Sprintf("must have at most %d bytes", MaxTemplateSize))
+	} else if _, err := parseTemplate(name, content); err != nil {
+		verr.Add("content", err.Error())
+	}
+	if version < 1 {
+		verr.Add("version", "must be at least 1")
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	return &PromptTemplate{
+		ID:          uuid.New().String(),
+		Name:        name,
+		Version:     version,
+		Description: description,
+		Content:     content,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// Variables lists the names the template reads from the top level, e.g.
+// product for {{.product}}, sorted.
+func (t *PromptTemplate) Variables() []string {
+	tmpl, err := parseTemplate(t.Name, t.Content)
+	if err != nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	collectVariables(tmpl.Tree.Root, seen)
+	variables := make([]string, 0, len(seen))
+	for name := range seen {
+		variables = append(variables, name)
+	}
+	sort.Strings(variables)
+	return variables
+}
+
+// Render fills the template with variables. Every variable the template
+// reads must be given and no other may be, so a typo in a name is reported
+// instead of rendering an empty value.
+func (t *PromptTemplate) Render(variables map[string]string) (string, error) {
+	tmpl, err := parseTemplate(t.Name, t.Content)
+	if err != nil {
+		return "", err
+	}
+
+	known := map[string]bool{}
+	var missing, unknown []string
+	for _, name := range t.Variables() {
+		known[name] = true
+		if _, ok := variables[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	for name := range variables {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing variables %s", strings.Join(missing, ", "))
+	}
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("unknown variables %s", strings.Join(unknown, ", "))
+	}
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, variables); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(rendered.String()) == "" {
+		return "", fmt.Errorf("template %q renders an empty text", t.Name)
+	}
+	return rendered.String(), nil
+}
+
+// parseTemplate fails on variables that were not given when executed, a
+// missing map key would otherwise render as "<no value>".
+func parseTemplate(name, content string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(content)
+}
+
+// collectVariables walks the parse tree collecting the fields read from the
+// top level data. The bodies of range and with are skipped, their fields are
+// read from the value the dot was moved to.
+func collectVariables(node parse.Node, seen map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectVariables(child, seen)
+		}
+	case *parse.ActionNode:
+		collectVariables(n.Pipe, seen)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				collectVariables(arg, seen)
+			}
+		}
+	case *parse.FieldNode:
+		seen[n.Ident[0]] = true
+	case *parse.ChainNode:
+		collectVariables(n.Node, seen)
+	case *parse.IfNode:
+		collectVariables(n.Pipe, seen)
+		collectVariables(n.List, seen)
+		collectVariables(n.ElseList, seen)
+	case *parse.RangeNode:
+		collectVariables(n.Pipe, seen)
+		collectVariables(n.ElseList, seen)
+	case *parse.WithNode:
+		collectVariables(n.Pipe, seen)
+		collectVariables(n.ElseList, seen)
+	case *parse.TemplateNode:
+		collectVariables(n.Pipe, seen)
+	}
+}
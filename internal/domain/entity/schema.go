@@ -0,0 +1,272 @@
+package entity
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"unicode/utf8"
+)
+
+// ResponseSchema is the JSON Schema replies must follow when a chat or a
+// request asks for structured output. Only the keywords below are checked,
+// the others are still sent to the provider.
+type ResponseSchema struct {
+	Raw  json.RawMessage
+	root *schemaNode
+}
+
+// StructuredOutputError is returned when no reply matched the response
+// schema, even after the corrective attempts.
+type StructuredOutputError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *StructuredOutputError) Error() string {
+	return fmt.Sprintf("no reply matched the response schema after %d attempts: %s", e.Attempts, e.Err)
+}
+
+func (e *StructuredOutputError) Unwrap() error {
+	return e.Err
+}
+
+type schemaNode struct {
+	Type                 schemaTypes            `json:"type"`
+	Properties           map[string]*schemaNode `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *bool                  `json:"-"`
+	Items                *schemaNode            `json:"items"`
+	Enum                 []interface{}          `json:"enum"`
+	AnyOf                []*schemaNode          `json:"anyOf"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+}
+
+// schemaTypes accepts both "type": "string" and "type": ["string", "null"].
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return errors.New("type must be a string or an array of strings")
+	}
+	*t = many
+	return nil
+}
+
+func (n *schemaNode) UnmarshalJSON(data []byte) error {
+	type plain schemaNode
+	if err := json.Unmarshal(data, (*plain)(n)); err != nil {
+		return err
+	}
+	// additionalProperties may also be a schema, which is not checked
+	var keywords struct {
+		AdditionalProperties json.RawMessage `json:"additionalProperties"`
+	}
+	if err := json.Unmarshal(data, &keywords); err != nil {
+		return err
+	}
+	var allowed bool
+	if json.Unmarshal(keywords.AdditionalProperties, &allowed) == nil {
+		n.AdditionalProperties = &allowed
+	}
+	return nil
+}
+
+func NewResponseSchema(raw json.RawMessage) (*ResponseSchema, error) {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return nil, errors.New("invalid JSON schema: " + err.Error())
+	}
+	if compact.Bytes()[0] != '{' {
+		return nil, errors.New("JSON schema must be an object")
+	}
+	var root schemaNode
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return nil, errors.New("invalid JSON schema: " + err.Error())
+	}
+	return &ResponseSchema{Raw: compact.Bytes(), root: &root}, nil
+}
+
+// Parse checks that content is a JSON document matching the schema and
+// returns it compacted.
+func (s *ResponseSchema) Parse(content string) (json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(content)))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, errors.New("reply is not valid JSON: " + err.Error())
+	}
+	if decoder.More() {
+		return nil, errors.New("reply has more than one JSON document")
+	}
+	if err := s.root.validate(value, "$"); err != nil {
+		return nil, err
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(content)); err != nil {
+		return nil, err
+	}
+	return compact.Bytes(), nil
+}
+
+func (n *schemaNode) validate(value interface{}, path string) error {
+	if len(n.Type) > 0 && !n.matchesType(value) {
+		return fmt.Errorf("%s: expected %s", path, joinTypes(n.Type))
+	}
+	if len(n.Enum) > 0 && !n.inEnum(value) {
+		return fmt.Errorf("%s: must be one of the enum values", path)
+	}
+	if len(n.AnyOf) > 0 {
+		matched := false
+		for _, option := range n.AnyOf {
+			if option.validate(value, path) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: matches none of the anyOf schemas", path)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return n.validateObject(v, path)
+	case []interface{}:
+		if n.MinItems != nil && len(v) < *n.MinItems {
+			return fmt.Errorf("%s: must have at least %d items", path, *n.MinItems)
+		}
+		if n.MaxItems != nil && len(v) > *n.MaxItems {
+			return fmt.Errorf("%s: must have at most %d items", path, *n.MaxItems)
+		}
+		if n.Items != nil {
+			for i, item := range v {
+				if err := n.Items.validate(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if n.MinLength != nil && length < *n.MinLength {
+			return fmt.Errorf("%s: must have at least %d characters", path, *n.MinLength)
+		}
+		if n.MaxLength != nil && length > *n.MaxLength {
+			return fmt.Errorf("%s: must have at most %d characters", path, *n.MaxLength)
+		}
+	case json.Number:
+		number, _ := v.Float64()
+		if n.Minimum != nil && number < *n.Minimum {
+			return fmt.Errorf("%s: must be at least %v", path, *n.Minimum)
+		}
+		if n.Maximum != nil && number > *n.Maximum {
+			return fmt.Errorf("%s: must be at most %v", path, *n.Maximum)
+		}
+	}
+	return nil
+}
+
+func (n *schemaNode) validateObject(object map[string]interface{}, path string) error {
+	for _, name := range n.Required {
+		if _, ok := object[name]; !ok {
+			return fmt.Errorf("%s: missing required property %q", path, name)
+		}
+	}
+
+	// sorted so the same reply always reports the same error
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property, ok := n.Properties[name]
+		if !ok {
+			if n.AdditionalProperties != nil && !*n.AdditionalProperties {
+				return fmt.Errorf("%s: unexpected property %q", path, name)
+			}
+			continue
+		}
+		if err := property.validate(object[name], path+"."+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *schemaNode) matchesType(value interface{}) bool {
+	for _, name := range n.Type {
+		switch v := value.(type) {
+		case nil:
+			if name == "null" {
+				return true
+			}
+		case bool:
+			if name == "boolean" {
+				return true
+			}
+		case string:
+			if name == "string" {
+				return true
+			}
+		case json.Number:
+			number, err := v.Float64()
+			if name == "number" && err == nil {
+				return true
+			}
+			if name == "integer" && err == nil && number == math.Trunc(number) {
+				return true
+			}
+		case []interface{}:
+			if name == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if name == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (n *schemaNode) inEnum(value interface{}) bool {
+	for _, allowed := range n.Enum {
+		if number, ok := value.(json.Number); ok {
+			if allowedNumber, ok := allowed.(float64); ok {
+				parsed, err := number.Float64()
+				if err == nil && parsed == allowedNumber {
+					return true
+				}
+			}
+			continue
+		}
+		if reflect.DeepEqual(allowed, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinTypes(types schemaTypes) string {
+	joined := types[0]
+	for _, name := range types[1:] {
+		joined += " or " + name
+	}
+	return joined
+}
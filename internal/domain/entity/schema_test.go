@@ -0,0 +1,104 @@
+package entity
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewResponseSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr string
+	}{
+		{name: "object schema", schema: `{"type": "object", "properties": {"name": {"type": "string"}}}`},
+		{name: "union type", schema: `{"type": ["string", "null"]}`},
+		{name: "additional properties as a schema", schema: `{"type": "object", "additionalProperties": {"type": "string"}}`},
+		{name: "invalid JSON", schema: `{"type": `, wantErr: "invalid JSON schema"},
+		{name: "not an object", schema: `["string"]`, wantErr: "must be an object"},
+		{name: "invalid type keyword", schema: `{"type": 1}`, wantErr: "type must be a string or an array of strings"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := NewResponseSchema(json.RawMessage(tt.schema))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewResponseSchema: %v", err)
+			}
+			if strings.ContainsAny(string(schema.Raw), " \n") {
+				t.Errorf("Raw = %s, want it compacted", schema.Raw)
+			}
+		})
+	}
+}
+
+func TestResponseSchemaParse(t *testing.T) {
+	const person = `{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "minLength": 2, "maxLength": 10},
+			"age": {"type": "integer", "minimum": 0, "maximum": 150},
+			"role": {"enum": ["admin", "user", 1]},
+			"tags": {"type": "array", "items": {"type": "string"}, "minItems": 1, "maxItems": 2},
+			"email": {"anyOf": [{"type": "string"}, {"type": "null"}]}
+		},
+		"required": ["name"],
+		"additionalProperties": false
+	}`
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{name: "valid reply is compacted", content: "{\n  \"name\": \"Ana\",\n  \"age\": 30\n}", want: `{"name":"Ana","age":30}`},
+		{name: "every keyword satisfied", content: `{"name": "Ana", "role": "admin", "tags": ["a"], "email": null}`, want: `{"name":"Ana","role":"admin","tags":["a"],"email":null}`},
+		{name: "numeric enum value", content: `{"name": "Ana", "role": 1}`, want: `{"name":"Ana","role":1}`},
+		{name: "not JSON", content: `Sure! {"name": "Ana"}`, wantErr: "reply is not valid JSON"},
+		{name: "two documents", content: `{"name": "Ana"} {"name": "Bia"}`, wantErr: "more than one JSON document"},
+		{name: "wrong root type", content: `["Ana"]`, wantErr: "$: expected object"},
+		{name: "missing required property", content: `{"age": 30}`, wantErr: `$: missing required property "name"`},
+		{name: "unexpected property", content: `{"name": "Ana", "nickname": "A"}`, wantErr: `$: unexpected property "nickname"`},
+		{name: "integer with a fraction", content: `{"name": "Ana", "age": 30.5}`, wantErr: "$.age: expected integer"},
+		{name: "below minimum", content: `{"name": "Ana", "age": -1}`, wantErr: "$.age: must be at least 0"},
+		{name: "above maximum", content: `{"name": "Ana", "age": 200}`, wantErr: "$.age: must be at most 150"},
+		{name: "too short", content: `{"name": "A"}`, wantErr: "$.name: must have at least 2 characters"},
+		{name: "length counts characters", content: `{"name": "Ænæøåéíóúü"}`, want: `{"name":"Ænæøåéíóúü"}`},
+		{name: "too long", content: `{"name": "Anastasia Maria"}`, wantErr: "$.name: must have at most 10 characters"},
+		{name: "not in enum", content: `{"name": "Ana", "role": "guest"}`, wantErr: "$.role: must be one of the enum values"},
+		{name: "too few items", content: `{"name": "Ana", "tags": []}`, wantErr: "$.tags: must have at least 1 items"},
+		{name: "too many items", content: `{"name": "Ana", "tags": ["a", "b", "c"]}`, wantErr: "$.tags: must have at most 2 items"},
+		{name: "invalid item", content: `{"name": "Ana", "tags": ["a", 2]}`, wantErr: "$.tags[1]: expected string"},
+		{name: "no anyOf match", content: `{"name": "Ana", "email": 1}`, wantErr: "$.email: matches none of the anyOf schemas"},
+	}
+
+	schema, err := NewResponseSchema(json.RawMessage(person))
+	if err != nil {
+		t.Fatalf("NewResponseSchema: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, err := schema.Parse(tt.content)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if string(object) != tt.want {
+				t.Errorf("object = %s, want %s", object, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// MaxToolRounds bounds the completions requested for one reply while the
+// assistant keeps calling tools. The last one is asked to answer without
+// tools.
+const MaxToolRounds = 5
+
+// ToolHandler runs a tool with the JSON arguments chosen by the assistant and
+// returns the result sent back to it.
+type ToolHandler func(ctx context.Context, arguments string) (string, error)
+
+// Tool is a function the assistant may ask the service to run. Parameters is
+// the JSON schema of the arguments object.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  json.RawMessage
+	Handler     ToolHandler
+}
+
+func (t *Tool) Validate() error {
+	if t.Name == "" {
+		return errors.New("tool name is empty")
+	}
+	if t.Handler == nil {
+		return errors.New("tool " + t.Name + " has no handler")
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(t.Parameters, &schema); err != nil {
+		return errors.New("tool " + t.Name + " parameters are not a JSON schema object: " + err.Error())
+	}
+	return nil
+}
+
+// ToolCall is one call requested by the assistant, kept in its message so
+// the results can be matched by ID.
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
@@ -0,0 +1,16 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+)
+
+type AssistantGateway interface {
+	Create(ctx context.Context, assistant *entity.Assistant) error
+	Save(ctx context.Context, assistant *entity.Assistant) error
+	FindByID(ctx context.Context, id string) (*entity.Assistant, error)
+	// FindAll lists every assistant, sorted by name.
+	FindAll(ctx context.Context) ([]*entity.Assistant, error)
+	Delete(ctx context.Context, id string) error
+}
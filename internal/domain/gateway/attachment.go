@@ -0,0 +1,20 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+)
+
+type AttachmentGateway interface {
+	Create(ctx context.Context, attachment *entity.Attachment) error
+	FindByID(ctx context.Context, id string) (*entity.Attachment, error)
+}
+
+// AttachmentStorageGateway keeps the content of the attachments, the
+// metadata is kept by AttachmentGateway.
+type AttachmentStorageGateway interface {
+	Put(ctx context.Context, key, contentType string, content []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
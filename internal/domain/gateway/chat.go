@@ -9,5 +9,10 @@ import (
 type ChatGateway interface {
 	Create(ctx context.Context, chat *entity.Chat) error
 	FindByID(ctx context.Context, chatID string) (*entity.Chat, error)
+	// FindByUserID lists the chats of the user without their messages, most
+	// recently updated first.
+	FindByUserID(ctx context.Context, userID string) ([]*entity.Chat, error)
 	Save(ctx context.Context, chat *entity.Chat) error
+	UpdateTitle(ctx context.Context, chatID string, title string) error
+	UpdateSummary(ctx context.Context, chatID string, summary string) error
 }
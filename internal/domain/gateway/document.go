@@ -0,0 +1,16 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+)
+
+type DocumentGateway interface {
+	// Create stores the document with its chunks and their embeddings.
+	Create(ctx context.Context, document *entity.Document) error
+	// FindByChatID lists the documents of the chat without their chunks.
+	FindByChatID(ctx context.Context, chatID string) ([]*entity.Document, error)
+	// FindChunksByChatID returns every chunk of the documents of the chat.
+	FindChunksByChatID(ctx context.Context, chatID string) ([]*entity.DocumentChunk, error)
+}
@@ -0,0 +1,17 @@
+package gateway
+
+import "context"
+
+type EmbeddingGateway interface {
+	// Embed returns one embedding per input, in the same order.
+	Embed(ctx context.Context, inputs []string) ([][]float32, error)
+	// EmbedWithModel is Embed with a model other than the default one.
+	EmbedWithModel(ctx context.Context, model string, inputs []string) ([][]float32, error)
+}
+
+// EmbeddingCacheGateway keeps embeddings already computed, keyed by the model
+// and the hash of the input.
+type EmbeddingCacheGateway interface {
+	Get(ctx context.Context, key string) ([]float32, bool)
+	Set(ctx context.Context, key string, embedding []float32)
+}
@@ -0,0 +1,17 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrRequestIDInUse is returned by Register while another generation runs
+// under the same request id.
+var ErrRequestIDInUse = errors.New("request id already in use")
+
+type GenerationGateway interface {
+	Register(requestID, userID string, cancel context.CancelFunc) error
+	Unregister(requestID string)
+	// Cancel stops the generation only when it was started by the user.
+	Cancel(requestID, userID string) bool
+}
@@ -0,0 +1,12 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+)
+
+type ModelGateway interface {
+	FindByName(ctx context.Context, name string) (*entity.Model, error)
+	List(ctx context.Context) ([]*entity.Model, error)
+}
@@ -0,0 +1,19 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+)
+
+type PromptTemplateGateway interface {
+	Create(ctx context.Context, template *entity.PromptTemplate) error
+	// FindByName returns the latest version of the template.
+	FindByName(ctx context.Context, name string) (*entity.PromptTemplate, error)
+	FindByNameAndVersion(ctx context.Context, name string, version int) (*entity.PromptTemplate, error)
+	// FindAll lists the latest version of every template, sorted by name.
+	FindAll(ctx context.Context) ([]*entity.PromptTemplate, error)
+	// DeleteByName removes every version of the template, returning how many
+	// there were.
+	DeleteByName(ctx context.Context, name string) (int, error)
+}
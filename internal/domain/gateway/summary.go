@@ -0,0 +1,14 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+)
+
+type SummaryGateway interface {
+	// Summarize folds the messages into the previous summary.
+	Summarize(ctx context.Context, model *entity.Model, summary string, messages []*entity.Message) (string, error)
+	// Title names a chat after its first messages.
+	Title(ctx context.Context, model *entity.Model, messages []*entity.Message) (string, error)
+}
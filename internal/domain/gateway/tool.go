@@ -0,0 +1,12 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+)
+
+type ToolGateway interface {
+	FindByName(ctx context.Context, name string) (*entity.Tool, error)
+	List(ctx context.Context) ([]*entity.Tool, error)
+}
@@ -0,0 +1,82 @@
+package attachmentstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrObjectNotFound = errors.New("attachment content not found")
+
+// AttachmentStoreFilesystem keeps every attachment in a file under Root,
+// named after its storage key.
+type AttachmentStoreFilesystem struct {
+	Root string
+}
+
+func NewAttachmentStoreFilesystem(root string) (*AttachmentStoreFilesystem, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, err
+	}
+	return &AttachmentStoreFilesystem{Root: root}, nil
+}
+
+// Put writes to a temporary file first, so a failed upload never leaves a
+// truncated attachment behind.
+func (s *AttachmentStoreFilesystem) Put(ctx context.Context, key, contentType string, content []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *AttachmentStoreFilesystem) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	return content, err
+}
+
+func (s *AttachmentStoreFilesystem) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path rejects keys that would resolve outside of Root.
+func (s *AttachmentStoreFilesystem) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", errors.New("invalid attachment key " + key)
+	}
+	return filepath.Join(s.Root, clean), nil
+}
@@ -8,6 +8,37 @@ import (
 	"time"
 )
 
+type Assistant struct {
+	ID               string
+	Name             string
+	Description      string
+	SystemMessage    string
+	Model            string
+	Temperature      float64
+	TopP             float64
+	N                int32
+	Stop             string
+	MaxTokens        int32
+	PresencePenalty  float64
+	FrequencyPenalty float64
+	ContextStrategy  string
+	Tools            string
+	ResponseSchema   string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+type Attachment struct {
+	ID          string
+	ChatID      string
+	UserID      string
+	Name        string
+	ContentType string
+	Size        int64
+	StorageKey  string
+	CreatedAt   time.Time
+}
+
 type Chat struct {
 	ID               string
 	UserID           string
@@ -25,16 +56,58 @@ type Chat struct {
 	FrequencyPenalty float64
 	CreatedAt        time.Time
 	UpdatedAt        time.Time
+	Cost             float64
+	ContextStrategy  string
+	Summary          string
+	Title            string
+	ActiveLeafID     string
+	Tools            string
+	ResponseSchema   string
+	AssistantID      string
+	RetrievalTopK    int32
 }
 
-type Message struct {
+type Document struct {
 	ID        string
 	ChatID    string
-	Role      string
-	Content   string
-	Tokens    int32
-	Model     string
-	Erased    bool
-	OrderMsg  int32
+	UserID    string
+	Name      string
+	Chunks    int32
 	CreatedAt time.Time
 }
+
+type DocumentChunk struct {
+	ID         string
+	DocumentID string
+	ChatID     string
+	Position   int32
+	Content    string
+	Embedding  []byte
+}
+
+type Message struct {
+	ID           string
+	ChatID       string
+	Role         string
+	Content      string
+	Tokens       int32
+	Model        string
+	Erased       bool
+	CreatedAt    time.Time
+	FinishReason string
+	Pinned       bool
+	ParentID     string
+	Branch       int32
+	ToolCalls    string
+	ToolCallID   string
+	ContentParts string
+}
+
+type PromptTemplate struct {
+	ID          string
+	Name        string
+	Version     int32
+	Description string
+	Content     string
+	CreatedAt   time.Time
+}
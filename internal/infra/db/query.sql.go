@@ -10,22 +10,55 @@ import (
 	"time"
 )
 
+const addDocumentChunk = `-- name: AddDocumentChunk :exec
+INSERT INTO document_chunks
+(id,document_id,chat_id,position,content,embedding)
+VALUES(?,?,?,?,?,?)
+`
+
+type AddDocumentChunkParams struct {
+	ID         string
+	DocumentID string
+	ChatID     string
+	Position   int32
+	Content    string
+	Embedding  []byte
+}
+
+func (q *Queries) AddDocumentChunk(ctx context.Context, arg AddDocumentChunkParams) error {
+	_, err := q.db.ExecContext(ctx, addDocumentChunk,
+		arg.ID,
+		arg.DocumentID,
+		arg.ChatID,
+		arg.Position,
+		arg.Content,
+		arg.Embedding,
+	)
+	return err
+}
+
 const addMessage = `-- name: AddMessage :exec
 INSERT INTO messages
-(id,chat_id,role,content,tokens,model,erased,order_msg,created_at)
-VALUES(?,?,?,?,?,?,?,?,?)
+(id,chat_id,role,content,tokens,model,erased,created_at,finish_reason,pinned,parent_id,branch,tool_calls,tool_call_id,content_parts)
+VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
 `
 
 type AddMessageParams struct {
-	ID        string
-	ChatID    string
-	Role      string
-	Content   string
-	Tokens    int32
-	Model     string
-	Erased    bool
-	OrderMsg  int32
-	CreatedAt time.Time
+	ID           string
+	ChatID       string
+	Role         string
+	Content      string
+	Tokens       int32
+	Model        string
+	Erased       bool
+	CreatedAt    time.Time
+	FinishReason string
+	Pinned       bool
+	ParentID     string
+	Branch       int32
+	ToolCalls    string
+	ToolCallID   string
+	ContentParts string
 }
 
 func (q *Queries) AddMessage(ctx context.Context, arg AddMessageParams) error {
@@ -37,16 +70,22 @@ func (q *Queries) AddMessage(ctx context.Context, arg AddMessageParams) error {
 		arg.Tokens,
 		arg.Model,
 		arg.Erased,
-		arg.OrderMsg,
 		arg.CreatedAt,
+		arg.FinishReason,
+		arg.Pinned,
+		arg.ParentID,
+		arg.Branch,
+		arg.ToolCalls,
+		arg.ToolCallID,
+		arg.ContentParts,
 	)
 	return err
 }
 
 const create = `-- name: Create :exec
 INSERT INTO chats
-(id,user_id,initial_message_id,status,token_usage,model,model_max_tokens,temperature,top_p,n,stop,max_tokens,presence_penalty,frequency_penalty,created_at,updated_at)
-VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
+(id,user_id,initial_message_id,status,token_usage,model,model_max_tokens,temperature,top_p,n,stop,max_tokens,presence_penalty,frequency_penalty,created_at,updated_at,cost,context_strategy,summary,active_leaf_id,tools,response_schema,assistant_id,retrieval_top_k)
+VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
 `
 
 type CreateParams struct {
@@ -66,6 +105,14 @@ type CreateParams struct {
 	FrequencyPenalty float64
 	CreatedAt        time.Time
 	UpdatedAt        time.Time
+	Cost             float64
+	ContextStrategy  string
+	Summary          string
+	ActiveLeafID     string
+	Tools            string
+	ResponseSchema   string
+	AssistantID      string
+	RetrievalTopK    int32
 }
 
 func (q *Queries) Create(ctx context.Context, arg CreateParams) error {
@@ -86,10 +133,164 @@ func (q *Queries) Create(ctx context.Context, arg CreateParams) error {
 		arg.FrequencyPenalty,
 		arg.CreatedAt,
 		arg.UpdatedAt,
+		arg.Cost,
+		arg.ContextStrategy,
+		arg.Summary,
+		arg.ActiveLeafID,
+		arg.Tools,
+		arg.ResponseSchema,
+		arg.AssistantID,
+		arg.RetrievalTopK,
+	)
+	return err
+}
+
+const createAssistant = `-- name: CreateAssistant :exec
+INSERT INTO assistants
+(id,name,description,system_message,model,temperature,top_p,n,stop,max_tokens,presence_penalty,frequency_penalty,context_strategy,tools,response_schema,created_at,updated_at)
+VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
+`
+
+type CreateAssistantParams struct {
+	ID               string
+	Name             string
+	Description      string
+	SystemMessage    string
+	Model            string
+	Temperature      float64
+	TopP             float64
+	N                int32
+	Stop             string
+	MaxTokens        int32
+	PresencePenalty  float64
+	FrequencyPenalty float64
+	ContextStrategy  string
+	Tools            string
+	ResponseSchema   string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+func (q *Queries) CreateAssistant(ctx context.Context, arg CreateAssistantParams) error {
+	_, err := q.db.ExecContext(ctx, createAssistant,
+		arg.ID,
+		arg.Name,
+		arg.Description,
+		arg.SystemMessage,
+		arg.Model,
+		arg.Temperature,
+		arg.TopP,
+		arg.N,
+		arg.Stop,
+		arg.MaxTokens,
+		arg.PresencePenalty,
+		arg.FrequencyPenalty,
+		arg.ContextStrategy,
+		arg.Tools,
+		arg.ResponseSchema,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const createAttachment = `-- name: CreateAttachment :exec
+INSERT INTO attachments
+(id,chat_id,user_id,name,content_type,size,storage_key,created_at)
+VALUES(?,?,?,?,?,?,?,?)
+`
+
+type CreateAttachmentParams struct {
+	ID          string
+	ChatID      string
+	UserID      string
+	Name        string
+	ContentType string
+	Size        int64
+	StorageKey  string
+	CreatedAt   time.Time
+}
+
+func (q *Queries) CreateAttachment(ctx context.Context, arg CreateAttachmentParams) error {
+	_, err := q.db.ExecContext(ctx, createAttachment,
+		arg.ID,
+		arg.ChatID,
+		arg.UserID,
+		arg.Name,
+		arg.ContentType,
+		arg.Size,
+		arg.StorageKey,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const createDocument = `-- name: CreateDocument :exec
+INSERT INTO documents
+(id,chat_id,user_id,name,chunks,created_at)
+VALUES(?,?,?,?,?,?)
+`
+
+type CreateDocumentParams struct {
+	ID        string
+	ChatID    string
+	UserID    string
+	Name      string
+	Chunks    int32
+	CreatedAt time.Time
+}
+
+func (q *Queries) CreateDocument(ctx context.Context, arg CreateDocumentParams) error {
+	_, err := q.db.ExecContext(ctx, createDocument,
+		arg.ID,
+		arg.ChatID,
+		arg.UserID,
+		arg.Name,
+		arg.Chunks,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const createPromptTemplate = `-- name: CreatePromptTemplate :exec
+INSERT INTO prompt_templates
+(id,name,version,description,content,created_at)
+VALUES(?,?,?,?,?,?)
+`
+
+type CreatePromptTemplateParams struct {
+	ID          string
+	Name        string
+	Version     int32
+	Description string
+	Content     string
+	CreatedAt   time.Time
+}
+
+func (q *Queries) CreatePromptTemplate(ctx context.Context, arg CreatePromptTemplateParams) error {
+	_, err := q.db.ExecContext(ctx, createPromptTemplate,
+		arg.ID,
+		arg.Name,
+		arg.Version,
+		arg.Description,
+		arg.Content,
+		arg.CreatedAt,
 	)
 	return err
 }
 
+const deleteAssistant = `-- name: DeleteAssistant :execrows
+DELETE FROM assistants WHERE id = ?
+`
+
+func (q *Queries) DeleteAssistant(ctx context.Context, id string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAssistant, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteChatMessages = `-- name: DeleteChatMessages :exec
 DELETE FROM messages WHERE chat_id = ?
 `
@@ -108,8 +309,69 @@ func (q *Queries) DeleteErasedChatMessages(ctx context.Context, chatID string) e
 	return err
 }
 
+const deletePromptTemplates = `-- name: DeletePromptTemplates :execrows
+DELETE FROM prompt_templates WHERE name = ?
+`
+
+func (q *Queries) DeletePromptTemplates(ctx context.Context, name string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePromptTemplates, name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const findAssistantByID = `-- name: FindAssistantByID :one
+SELECT id, name, description, system_message, model, temperature, top_p, n, stop, max_tokens, presence_penalty, frequency_penalty, context_strategy, tools, response_schema, created_at, updated_at FROM assistants WHERE id = ?
+`
+
+func (q *Queries) FindAssistantByID(ctx context.Context, id string) (Assistant, error) {
+	row := q.db.QueryRowContext(ctx, findAssistantByID, id)
+	var i Assistant
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.SystemMessage,
+		&i.Model,
+		&i.Temperature,
+		&i.TopP,
+		&i.N,
+		&i.Stop,
+		&i.MaxTokens,
+		&i.PresencePenalty,
+		&i.FrequencyPenalty,
+		&i.ContextStrategy,
+		&i.Tools,
+		&i.ResponseSchema,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const findAttachmentByID = `-- name: FindAttachmentByID :one
+SELECT id, chat_id, user_id, name, content_type, size, storage_key, created_at FROM attachments WHERE id = ?
+`
+
+func (q *Queries) FindAttachmentByID(ctx context.Context, id string) (Attachment, error) {
+	row := q.db.QueryRowContext(ctx, findAttachmentByID, id)
+	var i Attachment
+	err := row.Scan(
+		&i.ID,
+		&i.ChatID,
+		&i.UserID,
+		&i.Name,
+		&i.ContentType,
+		&i.Size,
+		&i.StorageKey,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const findByID = `-- name: FindByID :one
-SELECT id, user_id, initial_message_id, status, token_usage, model, model_max_tokens, temperature, top_p, n, stop, max_tokens, presence_penalty, frequency_penalty, created_at, updated_at FROM chats WHERE id = ?
+SELECT id, user_id, initial_message_id, status, token_usage, model, model_max_tokens, temperature, top_p, n, stop, max_tokens, presence_penalty, frequency_penalty, created_at, updated_at, cost, context_strategy, summary, title, active_leaf_id, tools, response_schema, assistant_id, retrieval_top_k FROM chats WHERE id = ?
 `
 
 func (q *Queries) FindByID(ctx context.Context, id string) (Chat, error) {
@@ -132,32 +394,136 @@ func (q *Queries) FindByID(ctx context.Context, id string) (Chat, error) {
 		&i.FrequencyPenalty,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Cost,
+		&i.ContextStrategy,
+		&i.Summary,
+		&i.Title,
+		&i.ActiveLeafID,
+		&i.Tools,
+		&i.ResponseSchema,
+		&i.AssistantID,
+		&i.RetrievalTopK,
 	)
 	return i, err
 }
 
-const findErasedMessagesByChatID = `-- name: FindErasedMessagesByChatID :many
-SELECT id, chat_id, role, content, tokens, model, erased, order_msg, created_at FROM messages WHERE erased=1 and chat_id = ? order by order_msg asc
+const findByUserID = `-- name: FindByUserID :many
+SELECT id, user_id, initial_message_id, status, token_usage, model, model_max_tokens, temperature, top_p, n, stop, max_tokens, presence_penalty, frequency_penalty, created_at, updated_at, cost, context_strategy, summary, title, active_leaf_id, tools, response_schema, assistant_id, retrieval_top_k FROM chats WHERE user_id = ? order by updated_at desc
 `
 
-func (q *Queries) FindErasedMessagesByChatID(ctx context.Context, chatID string) ([]Message, error) {
-	rows, err := q.db.QueryContext(ctx, findErasedMessagesByChatID, chatID)
+func (q *Queries) FindByUserID(ctx context.Context, userID string) ([]Chat, error) {
+	rows, err := q.db.QueryContext(ctx, findByUserID, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Message
+	var items []Chat
 	for rows.Next() {
-		var i Message
+		var i Chat
 		if err := rows.Scan(
 			&i.ID,
-			&i.ChatID,
-			&i.Role,
-			&i.Content,
-			&i.Tokens,
+			&i.UserID,
+			&i.InitialMessageID,
+			&i.Status,
+			&i.TokenUsage,
 			&i.Model,
-			&i.Erased,
-			&i.OrderMsg,
+			&i.ModelMaxTokens,
+			&i.Temperature,
+			&i.TopP,
+			&i.N,
+			&i.Stop,
+			&i.MaxTokens,
+			&i.PresencePenalty,
+			&i.FrequencyPenalty,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Cost,
+			&i.ContextStrategy,
+			&i.Summary,
+			&i.Title,
+			&i.ActiveLeafID,
+			&i.Tools,
+			&i.ResponseSchema,
+			&i.AssistantID,
+			&i.RetrievalTopK,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findDocumentChunksByChatID = `-- name: FindDocumentChunksByChatID :many
+SELECT document_chunks.id, document_chunks.document_id, documents.name, document_chunks.position, document_chunks.content, document_chunks.embedding
+FROM document_chunks JOIN documents ON documents.id = document_chunks.document_id
+WHERE document_chunks.chat_id = ? order by documents.created_at asc, document_chunks.position asc
+`
+
+type FindDocumentChunksByChatIDRow struct {
+	ID         string
+	DocumentID string
+	Name       string
+	Position   int32
+	Content    string
+	Embedding  []byte
+}
+
+func (q *Queries) FindDocumentChunksByChatID(ctx context.Context, chatID string) ([]FindDocumentChunksByChatIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, findDocumentChunksByChatID, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindDocumentChunksByChatIDRow
+	for rows.Next() {
+		var i FindDocumentChunksByChatIDRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.DocumentID,
+			&i.Name,
+			&i.Position,
+			&i.Content,
+			&i.Embedding,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findDocumentsByChatID = `-- name: FindDocumentsByChatID :many
+SELECT id, chat_id, user_id, name, chunks, created_at FROM documents WHERE chat_id = ? order by created_at asc
+`
+
+func (q *Queries) FindDocumentsByChatID(ctx context.Context, chatID string) ([]Document, error) {
+	rows, err := q.db.QueryContext(ctx, findDocumentsByChatID, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Document
+	for rows.Next() {
+		var i Document
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChatID,
+			&i.UserID,
+			&i.Name,
+			&i.Chunks,
 			&i.CreatedAt,
 		); err != nil {
 			return nil, err
@@ -173,8 +539,26 @@ func (q *Queries) FindErasedMessagesByChatID(ctx context.Context, chatID string)
 	return items, nil
 }
 
+const findLatestPromptTemplate = `-- name: FindLatestPromptTemplate :one
+SELECT id, name, version, description, content, created_at FROM prompt_templates WHERE name = ? order by version desc limit 1
+`
+
+func (q *Queries) FindLatestPromptTemplate(ctx context.Context, name string) (PromptTemplate, error) {
+	row := q.db.QueryRowContext(ctx, findLatestPromptTemplate, name)
+	var i PromptTemplate
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Version,
+		&i.Description,
+		&i.Content,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const findMessagesByChatID = `-- name: FindMessagesByChatID :many
-SELECT id, chat_id, role, content, tokens, model, erased, order_msg, created_at FROM messages WHERE erased=0 and chat_id = ? order by order_msg asc
+SELECT id, chat_id, role, content, tokens, model, erased, created_at, finish_reason, pinned, parent_id, branch, tool_calls, tool_call_id, content_parts FROM messages WHERE chat_id = ? order by created_at asc
 `
 
 func (q *Queries) FindMessagesByChatID(ctx context.Context, chatID string) ([]Message, error) {
@@ -194,7 +578,118 @@ func (q *Queries) FindMessagesByChatID(ctx context.Context, chatID string) ([]Me
 			&i.Tokens,
 			&i.Model,
 			&i.Erased,
-			&i.OrderMsg,
+			&i.CreatedAt,
+			&i.FinishReason,
+			&i.Pinned,
+			&i.ParentID,
+			&i.Branch,
+			&i.ToolCalls,
+			&i.ToolCallID,
+			&i.ContentParts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findPromptTemplateByVersion = `-- name: FindPromptTemplateByVersion :one
+SELECT id, name, version, description, content, created_at FROM prompt_templates WHERE name = ? AND version = ?
+`
+
+type FindPromptTemplateByVersionParams struct {
+	Name    string
+	Version int32
+}
+
+func (q *Queries) FindPromptTemplateByVersion(ctx context.Context, arg FindPromptTemplateByVersionParams) (PromptTemplate, error) {
+	row := q.db.QueryRowContext(ctx, findPromptTemplateByVersion, arg.Name, arg.Version)
+	var i PromptTemplate
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Version,
+		&i.Description,
+		&i.Content,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listAssistants = `-- name: ListAssistants :many
+SELECT id, name, description, system_message, model, temperature, top_p, n, stop, max_tokens, presence_penalty, frequency_penalty, context_strategy, tools, response_schema, created_at, updated_at FROM assistants order by name asc
+`
+
+func (q *Queries) ListAssistants(ctx context.Context) ([]Assistant, error) {
+	rows, err := q.db.QueryContext(ctx, listAssistants)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Assistant
+	for rows.Next() {
+		var i Assistant
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.SystemMessage,
+			&i.Model,
+			&i.Temperature,
+			&i.TopP,
+			&i.N,
+			&i.Stop,
+			&i.MaxTokens,
+			&i.PresencePenalty,
+			&i.FrequencyPenalty,
+			&i.ContextStrategy,
+			&i.Tools,
+			&i.ResponseSchema,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLatestPromptTemplates = `-- name: ListLatestPromptTemplates :many
+SELECT prompt_templates.id, prompt_templates.name, prompt_templates.version, prompt_templates.description, prompt_templates.content, prompt_templates.created_at FROM prompt_templates
+JOIN (SELECT name, MAX(version) AS version FROM prompt_templates GROUP BY name) latest
+ON latest.name = prompt_templates.name AND latest.version = prompt_templates.version
+order by prompt_templates.name asc
+`
+
+func (q *Queries) ListLatestPromptTemplates(ctx context.Context) ([]PromptTemplate, error) {
+	rows, err := q.db.QueryContext(ctx, listLatestPromptTemplates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PromptTemplate
+	for rows.Next() {
+		var i PromptTemplate
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Version,
+			&i.Description,
+			&i.Content,
 			&i.CreatedAt,
 		); err != nil {
 			return nil, err
@@ -211,7 +706,7 @@ func (q *Queries) FindMessagesByChatID(ctx context.Context, chatID string) ([]Me
 }
 
 const save = `-- name: Save :exec
-UPDATE chats SET user_id = ?, initial_message_id = ?, status = ?, token_usage = ?, model = ?, model_max_tokens=?, temperature = ?, top_p = ?, n = ?, stop = ?, max_tokens = ?, presence_penalty = ?, frequency_penalty = ?, updated_at = ? WHERE id = ?
+UPDATE chats SET user_id = ?, initial_message_id = ?, status = ?, token_usage = ?, model = ?, model_max_tokens=?, temperature = ?, top_p = ?, n = ?, stop = ?, max_tokens = ?, presence_penalty = ?, frequency_penalty = ?, cost = ?, context_strategy = ?, active_leaf_id = ?, tools = ?, response_schema = ?, updated_at = ? WHERE id = ?
 `
 
 type SaveParams struct {
@@ -228,6 +723,11 @@ type SaveParams struct {
 	MaxTokens        int32
 	PresencePenalty  float64
 	FrequencyPenalty float64
+	Cost             float64
+	ContextStrategy  string
+	ActiveLeafID     string
+	Tools            string
+	ResponseSchema   string
 	UpdatedAt        time.Time
 	ID               string
 }
@@ -247,8 +747,87 @@ func (q *Queries) Save(ctx context.Context, arg SaveParams) error {
 		arg.MaxTokens,
 		arg.PresencePenalty,
 		arg.FrequencyPenalty,
+		arg.Cost,
+		arg.ContextStrategy,
+		arg.ActiveLeafID,
+		arg.Tools,
+		arg.ResponseSchema,
 		arg.UpdatedAt,
 		arg.ID,
 	)
 	return err
 }
+
+const updateAssistant = `-- name: UpdateAssistant :exec
+UPDATE assistants SET name = ?, description = ?, system_message = ?, model = ?, temperature = ?, top_p = ?, n = ?, stop = ?, max_tokens = ?, presence_penalty = ?, frequency_penalty = ?, context_strategy = ?, tools = ?, response_schema = ?, updated_at = ?
+WHERE id = ?
+`
+
+type UpdateAssistantParams struct {
+	Name             string
+	Description      string
+	SystemMessage    string
+	Model            string
+	Temperature      float64
+	TopP             float64
+	N                int32
+	Stop             string
+	MaxTokens        int32
+	PresencePenalty  float64
+	FrequencyPenalty float64
+	ContextStrategy  string
+	Tools            string
+	ResponseSchema   string
+	UpdatedAt        time.Time
+	ID               string
+}
+
+func (q *Queries) UpdateAssistant(ctx context.Context, arg UpdateAssistantParams) error {
+	_, err := q.db.ExecContext(ctx, updateAssistant,
+		arg.Name,
+		arg.Description,
+		arg.SystemMessage,
+		arg.Model,
+		arg.Temperature,
+		arg.TopP,
+		arg.N,
+		arg.Stop,
+		arg.MaxTokens,
+		arg.PresencePenalty,
+		arg.FrequencyPenalty,
+		arg.ContextStrategy,
+		arg.Tools,
+		arg.ResponseSchema,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const updateSummary = `-- name: UpdateSummary :exec
+UPDATE chats SET summary = ? WHERE id = ?
+`
+
+type UpdateSummaryParams struct {
+	Summary string
+	ID      string
+}
+
+func (q *Queries) UpdateSummary(ctx context.Context, arg UpdateSummaryParams) error {
+	_, err := q.db.ExecContext(ctx, updateSummary, arg.Summary, arg.ID)
+	return err
+}
+
+const updateTitle = `-- name: UpdateTitle :exec
+UPDATE chats SET title = ? WHERE id = ?
+`
+
+type UpdateTitleParams struct {
+	Title string
+	ID    string
+}
+
+func (q *Queries) UpdateTitle(ctx context.Context, arg UpdateTitleParams) error {
+	_, err := q.db.ExecContext(ctx, updateTitle, arg.Title, arg.ID)
+	return err
+}
@@ -0,0 +1,59 @@
+package embedder
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// batchSize bounds the inputs sent in one embeddings request.
+const batchSize = 100
+
+type EmbedderOpenAI struct {
+	OpenAIClient *openai.Client
+	Model        string
+}
+
+func NewEmbedderOpenAI(openAIClient *openai.Client, model string) *EmbedderOpenAI {
+	return &EmbedderOpenAI{
+		OpenAIClient: openAIClient,
+		Model:        model,
+	}
+}
+
+func (e *EmbedderOpenAI) Embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	return e.EmbedWithModel(ctx, e.Model, inputs)
+}
+
+func (e *EmbedderOpenAI) EmbedWithModel(ctx context.Context, model string, inputs []string) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(inputs))
+	for start := 0; start < len(inputs); start += batchSize {
+		end := start + batchSize
+		if end > len(inputs) {
+			end = len(inputs)
+		}
+
+		resp, err := e.OpenAIClient.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+			Input: inputs[start:end],
+			Model: openai.EmbeddingModel(model),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Data) != end-start {
+			return nil, fmt.Errorf("got %d embeddings for %d inputs", len(resp.Data), end-start)
+		}
+
+		// the data is not guaranteed to follow the order of the inputs
+		batch := make([][]float32, end-start)
+		for _, data := range resp.Data {
+			if data.Index < 0 || data.Index >= len(batch) {
+				return nil, fmt.Errorf("embedding index %d is out of range", data.Index)
+			}
+			batch[data.Index] = data.Embedding
+		}
+		embeddings = append(embeddings, batch...)
+	}
+	return embeddings, nil
+}
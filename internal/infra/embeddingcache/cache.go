@@ -0,0 +1,61 @@
+package embeddingcache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+type entry struct {
+	key       string
+	embedding []float32
+}
+
+// EmbeddingCacheInMemory keeps the most recently used embeddings, evicting
+// the least recently used one once it holds size entries.
+type EmbeddingCacheInMemory struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // front is the most recently used
+	entries map[string]*list.Element
+}
+
+func NewEmbeddingCacheInMemory(size int) *EmbeddingCacheInMemory {
+	return &EmbeddingCacheInMemory{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *EmbeddingCacheInMemory) Get(ctx context.Context, key string) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*entry).embedding, true
+}
+
+func (c *EmbeddingCacheInMemory) Set(ctx context.Context, key string, embedding []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.size <= 0 {
+		return
+	}
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*entry).embedding = embedding
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&entry{key: key, embedding: embedding})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+	}
+}
@@ -0,0 +1,59 @@
+package generations
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+// GenerationRegistryInMemory keeps the cancel functions of the completions
+// that are still running and the user who started them, indexed by the
+// request id chosen by the caller. A
+// cancelled completion keeps its id until it returns and unregisters, so the
+// id cannot be reused while it is still saving its partial answers.
+type GenerationRegistryInMemory struct {
+	mu          sync.Mutex
+	generations map[string]generation
+}
+
+type generation struct {
+	userID string
+	cancel context.CancelFunc
+}
+
+func NewGenerationRegistryInMemory() *GenerationRegistryInMemory {
+	return &GenerationRegistryInMemory{
+		generations: make(map[string]generation),
+	}
+}
+
+func (g *GenerationRegistryInMemory) Register(requestID, userID string, cancel context.CancelFunc) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.generations[requestID]; ok {
+		return gateway.ErrRequestIDInUse
+	}
+	g.generations[requestID] = generation{userID: userID, cancel: cancel}
+	return nil
+}
+
+func (g *GenerationRegistryInMemory) Unregister(requestID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.generations, requestID)
+}
+
+func (g *GenerationRegistryInMemory) Cancel(requestID, userID string) bool {
+	g.mu.Lock()
+	generation, ok := g.generations[requestID]
+	g.mu.Unlock()
+
+	if !ok || generation.userID != userID {
+		return false
+	}
+	generation.cancel()
+	return true
+}
@@ -19,7 +19,18 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	ChatService_ChatStream_FullMethodName = "/pb.ChatService/ChatStream"
+	ChatService_ChatStream_FullMethodName       = "/pb.ChatService/ChatStream"
+	ChatService_RegenerateStream_FullMethodName = "/pb.ChatService/RegenerateStream"
+	ChatService_CancelChat_FullMethodName       = "/pb.ChatService/CancelChat"
+	ChatService_ListChats_FullMethodName        = "/pb.ChatService/ListChats"
+	ChatService_GetChat_FullMethodName          = "/pb.ChatService/GetChat"
+	ChatService_ListBranches_FullMethodName     = "/pb.ChatService/ListBranches"
+	ChatService_SwitchBranch_FullMethodName     = "/pb.ChatService/SwitchBranch"
+	ChatService_ForkChat_FullMethodName         = "/pb.ChatService/ForkChat"
+	ChatService_CommitChoice_FullMethodName     = "/pb.ChatService/CommitChoice"
+	ChatService_AttachDocument_FullMethodName   = "/pb.ChatService/AttachDocument"
+	ChatService_ListDocuments_FullMethodName    = "/pb.ChatService/ListDocuments"
+	ChatService_Embeddings_FullMethodName       = "/pb.ChatService/Embeddings"
 )
 
 // ChatServiceClient is the client API for ChatService service.
@@ -27,6 +38,17 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ChatServiceClient interface {
 	ChatStream(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (ChatService_ChatStreamClient, error)
+	RegenerateStream(ctx context.Context, in *RegenerateRequest, opts ...grpc.CallOption) (ChatService_RegenerateStreamClient, error)
+	CancelChat(ctx context.Context, in *CancelChatRequest, opts ...grpc.CallOption) (*CancelChatResponse, error)
+	ListChats(ctx context.Context, in *ListChatsRequest, opts ...grpc.CallOption) (*ListChatsResponse, error)
+	GetChat(ctx context.Context, in *GetChatRequest, opts ...grpc.CallOption) (*GetChatResponse, error)
+	ListBranches(ctx context.Context, in *ListBranchesRequest, opts ...grpc.CallOption) (*ListBranchesResponse, error)
+	SwitchBranch(ctx context.Context, in *SwitchBranchRequest, opts ...grpc.CallOption) (*SwitchBranchResponse, error)
+	ForkChat(ctx context.Context, in *ForkChatRequest, opts ...grpc.CallOption) (*ForkChatResponse, error)
+	CommitChoice(ctx context.Context, in *CommitChoiceRequest, opts ...grpc.CallOption) (*CommitChoiceResponse, error)
+	AttachDocument(ctx context.Context, in *AttachDocumentRequest, opts ...grpc.CallOption) (*AttachDocumentResponse, error)
+	ListDocuments(ctx context.Context, in *ListDocumentsRequest, opts ...grpc.CallOption) (*ListDocumentsResponse, error)
+	Embeddings(ctx context.Context, in *EmbeddingsRequest, opts ...grpc.CallOption) (*EmbeddingsResponse, error)
 }
 
 type chatServiceClient struct {
@@ -69,11 +91,144 @@ func (x *chatServiceChatStreamClient) Recv() (*ChatResponse, error) {
 	return m, nil
 }
 
+func (c *chatServiceClient) RegenerateStream(ctx context.Context, in *RegenerateRequest, opts ...grpc.CallOption) (ChatService_RegenerateStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ChatService_ServiceDesc.Streams[1], ChatService_RegenerateStream_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &chatServiceRegenerateStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ChatService_RegenerateStreamClient interface {
+	Recv() (*ChatResponse, error)
+	grpc.ClientStream
+}
+
+type chatServiceRegenerateStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *chatServiceRegenerateStreamClient) Recv() (*ChatResponse, error) {
+	m := new(ChatResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *chatServiceClient) CancelChat(ctx context.Context, in *CancelChatRequest, opts ...grpc.CallOption) (*CancelChatResponse, error) {
+	out := new(CancelChatResponse)
+	err := c.cc.Invoke(ctx, ChatService_CancelChat_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) ListChats(ctx context.Context, in *ListChatsRequest, opts ...grpc.CallOption) (*ListChatsResponse, error) {
+	out := new(ListChatsResponse)
+	err := c.cc.Invoke(ctx, ChatService_ListChats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) GetChat(ctx context.Context, in *GetChatRequest, opts ...grpc.CallOption) (*GetChatResponse, error) {
+	out := new(GetChatResponse)
+	err := c.cc.Invoke(ctx, ChatService_GetChat_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) ListBranches(ctx context.Context, in *ListBranchesRequest, opts ...grpc.CallOption) (*ListBranchesResponse, error) {
+	out := new(ListBranchesResponse)
+	err := c.cc.Invoke(ctx, ChatService_ListBranches_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) SwitchBranch(ctx context.Context, in *SwitchBranchRequest, opts ...grpc.CallOption) (*SwitchBranchResponse, error) {
+	out := new(SwitchBranchResponse)
+	err := c.cc.Invoke(ctx, ChatService_SwitchBranch_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) ForkChat(ctx context.Context, in *ForkChatRequest, opts ...grpc.CallOption) (*ForkChatResponse, error) {
+	out := new(ForkChatResponse)
+	err := c.cc.Invoke(ctx, ChatService_ForkChat_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) CommitChoice(ctx context.Context, in *CommitChoiceRequest, opts ...grpc.CallOption) (*CommitChoiceResponse, error) {
+	out := new(CommitChoiceResponse)
+	err := c.cc.Invoke(ctx, ChatService_CommitChoice_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) AttachDocument(ctx context.Context, in *AttachDocumentRequest, opts ...grpc.CallOption) (*AttachDocumentResponse, error) {
+	out := new(AttachDocumentResponse)
+	err := c.cc.Invoke(ctx, ChatService_AttachDocument_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) ListDocuments(ctx context.Context, in *ListDocumentsRequest, opts ...grpc.CallOption) (*ListDocumentsResponse, error) {
+	out := new(ListDocumentsResponse)
+	err := c.cc.Invoke(ctx, ChatService_ListDocuments_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) Embeddings(ctx context.Context, in *EmbeddingsRequest, opts ...grpc.CallOption) (*EmbeddingsResponse, error) {
+	out := new(EmbeddingsResponse)
+	err := c.cc.Invoke(ctx, ChatService_Embeddings_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ChatServiceServer is the server API for ChatService service.
 // All implementations must embed UnimplementedChatServiceServer
 // for forward compatibility
 type ChatServiceServer interface {
 	ChatStream(*ChatRequest, ChatService_ChatStreamServer) error
+	RegenerateStream(*RegenerateRequest, ChatService_RegenerateStreamServer) error
+	CancelChat(context.Context, *CancelChatRequest) (*CancelChatResponse, error)
+	ListChats(context.Context, *ListChatsRequest) (*ListChatsResponse, error)
+	GetChat(context.Context, *GetChatRequest) (*GetChatResponse, error)
+	ListBranches(context.Context, *ListBranchesRequest) (*ListBranchesResponse, error)
+	SwitchBranch(context.Context, *SwitchBranchRequest) (*SwitchBranchResponse, error)
+	ForkChat(context.Context, *ForkChatRequest) (*ForkChatResponse, error)
+	CommitChoice(context.Context, *CommitChoiceRequest) (*CommitChoiceResponse, error)
+	AttachDocument(context.Context, *AttachDocumentRequest) (*AttachDocumentResponse, error)
+	ListDocuments(context.Context, *ListDocumentsRequest) (*ListDocumentsResponse, error)
+	Embeddings(context.Context, *EmbeddingsRequest) (*EmbeddingsResponse, error)
 	mustEmbedUnimplementedChatServiceServer()
 }
 
@@ -84,6 +239,39 @@ type UnimplementedChatServiceServer struct {
 func (UnimplementedChatServiceServer) ChatStream(*ChatRequest, ChatService_ChatStreamServer) error {
 	return status.Errorf(codes.Unimplemented, "method ChatStream not implemented")
 }
+func (UnimplementedChatServiceServer) RegenerateStream(*RegenerateRequest, ChatService_RegenerateStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method RegenerateStream not implemented")
+}
+func (UnimplementedChatServiceServer) CancelChat(context.Context, *CancelChatRequest) (*CancelChatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelChat not implemented")
+}
+func (UnimplementedChatServiceServer) ListChats(context.Context, *ListChatsRequest) (*ListChatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListChats not implemented")
+}
+func (UnimplementedChatServiceServer) GetChat(context.Context, *GetChatRequest) (*GetChatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetChat not implemented")
+}
+func (UnimplementedChatServiceServer) ListBranches(context.Context, *ListBranchesRequest) (*ListBranchesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBranches not implemented")
+}
+func (UnimplementedChatServiceServer) SwitchBranch(context.Context, *SwitchBranchRequest) (*SwitchBranchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SwitchBranch not implemented")
+}
+func (UnimplementedChatServiceServer) ForkChat(context.Context, *ForkChatRequest) (*ForkChatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ForkChat not implemented")
+}
+func (UnimplementedChatServiceServer) CommitChoice(context.Context, *CommitChoiceRequest) (*CommitChoiceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CommitChoice not implemented")
+}
+func (UnimplementedChatServiceServer) AttachDocument(context.Context, *AttachDocumentRequest) (*AttachDocumentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AttachDocument not implemented")
+}
+func (UnimplementedChatServiceServer) ListDocuments(context.Context, *ListDocumentsRequest) (*ListDocumentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDocuments not implemented")
+}
+func (UnimplementedChatServiceServer) Embeddings(context.Context, *EmbeddingsRequest) (*EmbeddingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Embeddings not implemented")
+}
 func (UnimplementedChatServiceServer) mustEmbedUnimplementedChatServiceServer() {}
 
 // UnsafeChatServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -118,19 +306,266 @@ func (x *chatServiceChatStreamServer) Send(m *ChatResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _ChatService_RegenerateStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RegenerateRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ChatServiceServer).RegenerateStream(m, &chatServiceRegenerateStreamServer{stream})
+}
+
+type ChatService_RegenerateStreamServer interface {
+	Send(*ChatResponse) error
+	grpc.ServerStream
+}
+
+type chatServiceRegenerateStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *chatServiceRegenerateStreamServer) Send(m *ChatResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ChatService_CancelChat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelChatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).CancelChat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_CancelChat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).CancelChat(ctx, req.(*CancelChatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_ListChats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListChatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).ListChats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_ListChats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).ListChats(ctx, req.(*ListChatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_GetChat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetChatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).GetChat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_GetChat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).GetChat(ctx, req.(*GetChatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_ListBranches_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBranchesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).ListBranches(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_ListBranches_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).ListBranches(ctx, req.(*ListBranchesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_SwitchBranch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SwitchBranchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).SwitchBranch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_SwitchBranch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).SwitchBranch(ctx, req.(*SwitchBranchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_ForkChat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForkChatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).ForkChat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_ForkChat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).ForkChat(ctx, req.(*ForkChatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_CommitChoice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommitChoiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).CommitChoice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_CommitChoice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).CommitChoice(ctx, req.(*CommitChoiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_AttachDocument_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AttachDocumentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).AttachDocument(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_AttachDocument_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).AttachDocument(ctx, req.(*AttachDocumentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_ListDocuments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDocumentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).ListDocuments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_ListDocuments_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).ListDocuments(ctx, req.(*ListDocumentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_Embeddings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmbeddingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).Embeddings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_Embeddings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).Embeddings(ctx, req.(*EmbeddingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ChatService_ServiceDesc is the grpc.ServiceDesc for ChatService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var ChatService_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "pb.ChatService",
 	HandlerType: (*ChatServiceServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CancelChat",
+			Handler:    _ChatService_CancelChat_Handler,
+		},
+		{
+			MethodName: "ListChats",
+			Handler:    _ChatService_ListChats_Handler,
+		},
+		{
+			MethodName: "GetChat",
+			Handler:    _ChatService_GetChat_Handler,
+		},
+		{
+			MethodName: "ListBranches",
+			Handler:    _ChatService_ListBranches_Handler,
+		},
+		{
+			MethodName: "SwitchBranch",
+			Handler:    _ChatService_SwitchBranch_Handler,
+		},
+		{
+			MethodName: "ForkChat",
+			Handler:    _ChatService_ForkChat_Handler,
+		},
+		{
+			MethodName: "CommitChoice",
+			Handler:    _ChatService_CommitChoice_Handler,
+		},
+		{
+			MethodName: "AttachDocument",
+			Handler:    _ChatService_AttachDocument_Handler,
+		},
+		{
+			MethodName: "ListDocuments",
+			Handler:    _ChatService_ListDocuments_Handler,
+		},
+		{
+			MethodName: "Embeddings",
+			Handler:    _ChatService_Embeddings_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "ChatStream",
 			Handler:       _ChatService_ChatStream_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "RegenerateStream",
+			Handler:       _ChatService_RegenerateStream_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "proto/chat.proto",
 }
@@ -1,46 +1,137 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"net"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gabrielmq/chat-service/internal/infra/grpc/pb"
 	"github.com/gabrielmq/chat-service/internal/infra/grpc/service"
+	"github.com/gabrielmq/chat-service/internal/infra/health"
+	"github.com/gabrielmq/chat-service/internal/usecase/attachdocument"
+	"github.com/gabrielmq/chat-service/internal/usecase/cancelcompletion"
 	"github.com/gabrielmq/chat-service/internal/usecase/chatcompletionstream"
+	"github.com/gabrielmq/chat-service/internal/usecase/chatconfig"
+	"github.com/gabrielmq/chat-service/internal/usecase/chathistory"
+	"github.com/gabrielmq/chat-service/internal/usecase/commitchoice"
+	"github.com/gabrielmq/chat-service/internal/usecase/embeddings"
+	"github.com/gabrielmq/chat-service/internal/usecase/forkchat"
+	"github.com/gabrielmq/chat-service/internal/usecase/listbranches"
+	"github.com/gabrielmq/chat-service/internal/usecase/listchats"
+	"github.com/gabrielmq/chat-service/internal/usecase/listdocuments"
+	"github.com/gabrielmq/chat-service/internal/usecase/switchbranch"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 )
 
+// healthInterval is how often the serving status follows the readiness
+// checks.
+const healthInterval = 5 * time.Second
+
 type GRPCServer struct {
 	ChatCompletionStreamUseCase chatcompletionstream.ChatCompletionUseCase
-	ChatConfigStream            chatcompletionstream.ChatCompletionConfigurationInput
+	CancelCompletionUseCase     cancelcompletion.CancelCompletionUseCase
+	ChatHistoryUseCase          chathistory.ChatHistoryUseCase
+	ListChatsUseCase            listchats.ListChatsUseCase
+	ListBranchesUseCase         listbranches.ListBranchesUseCase
+	SwitchBranchUseCase         switchbranch.SwitchBranchUseCase
+	ForkChatUseCase             forkchat.ForkChatUseCase
+	CommitChoiceUseCase         commitchoice.CommitChoiceUseCase
+	AttachDocumentUseCase       attachdocument.AttachDocumentUseCase
+	ListDocumentsUseCase        listdocuments.ListDocumentsUseCase
+	EmbeddingsUseCase           embeddings.EmbeddingsUseCase
+	ChatConfig                  *chatconfig.ConfigurationStore
+	EmbeddingsConfig            *embeddings.ConfigurationStore
 	ChatService                 service.ChatService
+	HealthChecker               *health.Checker
 	Port                        string
 	AuthToken                   string
-	StreamChannel               chan chatcompletionstream.ChatCompletionOutput
+	server                      *grpc.Server
+	health                      *grpchealth.Server
+	stopHealth                  chan struct{}
+	stopOnce                    sync.Once
 }
 
 func NewGRPCServer(
 	chatCompletionStreamUseCase chatcompletionstream.ChatCompletionUseCase,
-	chatConfigStream chatcompletionstream.ChatCompletionConfigurationInput,
+	cancelCompletionUseCase cancelcompletion.CancelCompletionUseCase,
+	chatHistoryUseCase chathistory.ChatHistoryUseCase,
+	listChatsUseCase listchats.ListChatsUseCase,
+	listBranchesUseCase listbranches.ListBranchesUseCase,
+	switchBranchUseCase switchbranch.SwitchBranchUseCase,
+	forkChatUseCase forkchat.ForkChatUseCase,
+	commitChoiceUseCase commitchoice.CommitChoiceUseCase,
+	attachDocumentUseCase attachdocument.AttachDocumentUseCase,
+	listDocumentsUseCase listdocuments.ListDocumentsUseCase,
+	embeddingsUseCase embeddings.EmbeddingsUseCase,
+	chatConfig *chatconfig.ConfigurationStore,
+	embeddingsConfig *embeddings.ConfigurationStore,
+	healthChecker *health.Checker,
 	port, authToken string,
-	streamChannel chan chatcompletionstream.ChatCompletionOutput,
 ) *GRPCServer {
-	chatService := service.NewChatService(chatCompletionStreamUseCase, chatConfigStream, streamChannel)
-	return &GRPCServer{
+	chatService := service.NewChatService(chatCompletionStreamUseCase, cancelCompletionUseCase, chatHistoryUseCase, listChatsUseCase, listBranchesUseCase, switchBranchUseCase, forkChatUseCase, commitChoiceUseCase, attachDocumentUseCase, listDocumentsUseCase, embeddingsUseCase, chatConfig, embeddingsConfig)
+	g := &GRPCServer{
 		ChatCompletionStreamUseCase: chatCompletionStreamUseCase,
-		ChatConfigStream:            chatConfigStream,
+		CancelCompletionUseCase:     cancelCompletionUseCase,
+		ChatHistoryUseCase:          chatHistoryUseCase,
+		ListChatsUseCase:            listChatsUseCase,
+		ListBranchesUseCase:         listBranchesUseCase,
+		SwitchBranchUseCase:         switchBranchUseCase,
+		ForkChatUseCase:             forkChatUseCase,
+		CommitChoiceUseCase:         commitChoiceUseCase,
+		AttachDocumentUseCase:       attachDocumentUseCase,
+		ListDocumentsUseCase:        listDocumentsUseCase,
+		EmbeddingsUseCase:           embeddingsUseCase,
+		ChatConfig:                  chatConfig,
+		EmbeddingsConfig:            embeddingsConfig,
 		Port:                        port,
 		AuthToken:                   authToken,
-		StreamChannel:               streamChannel,
 		ChatService:                 *chatService,
+		HealthChecker:               healthChecker,
+		health:                      grpchealth.NewServer(),
+		stopHealth:                  make(chan struct{}),
 	}
+	g.server = grpc.NewServer(
+		grpc.StreamInterceptor(g.AuthInterceptor),
+		grpc.UnaryInterceptor(g.UnaryAuthInterceptor),
+	)
+	pb.RegisterChatServiceServer(g.server, &g.ChatService)
+	healthpb.RegisterHealthServer(g.server, g.health)
+	reflection.Register(g.server)
+	return g
 }
 
 func (g *GRPCServer) AuthInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-	ctx := ss.Context()
+	if isHealthCheck(info.FullMethod) {
+		return handler(srv, ss)
+	}
+	if err := g.authorize(ss.Context()); err != nil {
+		return err
+	}
+
+	return handler(srv, ss)
+}
+
+func (g *GRPCServer) UnaryAuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if isHealthCheck(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	if err := g.authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (g *GRPCServer) authorize(ctx context.Context) error {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
 		return status.Error(codes.Unauthenticated, "metadata is not provided")
@@ -54,25 +145,71 @@ func (g *GRPCServer) AuthInterceptor(srv interface{}, ss grpc.ServerStream, info
 	if token[0] != g.AuthToken {
 		return status.Error(codes.Unauthenticated, "authorization token is invalid")
 	}
+	return nil
+}
 
-	return handler(srv, ss)
+// isHealthCheck reports the rpcs of grpc.health.v1, the orchestrator probes
+// them without a token.
+func isHealthCheck(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/")
 }
 
+// Start serves the rpcs until Shutdown is called, it returns nil once the
+// server was shut down.
 func (g *GRPCServer) Start() error {
-	opts := []grpc.ServerOption{
-		grpc.StreamInterceptor(g.AuthInterceptor),
-	}
-	grpcServer := grpc.NewServer(opts...)
-	pb.RegisterChatServiceServer(grpcServer, &g.ChatService)
-	reflection.Register(grpcServer)
-
 	lis, err := net.Listen("tcp", ":"+g.Port)
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
-	if err := grpcServer.Serve(lis); err != nil {
-		panic(err.Error())
+	go g.watchHealth()
+	if err := g.server.Serve(lis); !errors.Is(err, grpc.ErrServerStopped) {
+		return err
 	}
-
 	return nil
 }
+
+// Shutdown stops accepting rpcs and waits for the running ones, streams
+// included, to finish. The rpcs still running when ctx is done are
+// cancelled.
+func (g *GRPCServer) Shutdown(ctx context.Context) error {
+	// NOT_SERVING from now on, the later updates are ignored
+	g.stopOnce.Do(func() { close(g.stopHealth) })
+	g.health.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		g.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		g.server.Stop()
+		<-stopped
+		return ctx.Err()
+	}
+}
+
+// watchHealth sets the serving status of the server and of pb.ChatService
+// from the readiness checks until Shutdown. A degraded service is SERVING, as
+// /readyz answers 200.
+func (g *GRPCServer) watchHealth() {
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+	for {
+		status := healthpb.HealthCheckResponse_SERVING
+		if g.HealthChecker.Check(context.Background()).Status == health.StatusUnavailable {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		g.health.SetServingStatus("", status)
+		g.health.SetServingStatus(pb.ChatService_ServiceDesc.ServiceName, status)
+
+		select {
+		case <-g.stopHealth:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,58 +1,447 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"time"
+
 	"github.com/gabrielmq/chat-service/internal/infra/grpc/pb"
+	"github.com/gabrielmq/chat-service/internal/usecase/attachdocument"
+	"github.com/gabrielmq/chat-service/internal/usecase/cancelcompletion"
 	"github.com/gabrielmq/chat-service/internal/usecase/chatcompletionstream"
+	"github.com/gabrielmq/chat-service/internal/usecase/chatconfig"
+	"github.com/gabrielmq/chat-service/internal/usecase/chathistory"
+	"github.com/gabrielmq/chat-service/internal/usecase/commitchoice"
+	"github.com/gabrielmq/chat-service/internal/usecase/embeddings"
+	"github.com/gabrielmq/chat-service/internal/usecase/forkchat"
+	"github.com/gabrielmq/chat-service/internal/usecase/listbranches"
+	"github.com/gabrielmq/chat-service/internal/usecase/listchats"
+	"github.com/gabrielmq/chat-service/internal/usecase/listdocuments"
+	"github.com/gabrielmq/chat-service/internal/usecase/switchbranch"
 )
 
 type ChatService struct {
 	pb.UnimplementedChatServiceServer
 	ChatCompletionStreamUseCase chatcompletionstream.ChatCompletionUseCase
-	ChatConfigStream            chatcompletionstream.ChatCompletionConfigurationInput
-	StreamChannel               chan chatcompletionstream.ChatCompletionOutput
+	CancelCompletionUseCase     cancelcompletion.CancelCompletionUseCase
+	ChatHistoryUseCase          chathistory.ChatHistoryUseCase
+	ListChatsUseCase            listchats.ListChatsUseCase
+	ListBranchesUseCase         listbranches.ListBranchesUseCase
+	SwitchBranchUseCase         switchbranch.SwitchBranchUseCase
+	ForkChatUseCase             forkchat.ForkChatUseCase
+	CommitChoiceUseCase         commitchoice.CommitChoiceUseCase
+	AttachDocumentUseCase       attachdocument.AttachDocumentUseCase
+	ListDocumentsUseCase        listdocuments.ListDocumentsUseCase
+	EmbeddingsUseCase           embeddings.EmbeddingsUseCase
+	ChatConfig                  *chatconfig.ConfigurationStore
+	EmbeddingsConfig            *embeddings.ConfigurationStore
 }
 
-func NewChatService(chatCompletionStreamUseCase chatcompletionstream.ChatCompletionUseCase, chatConfigStream chatcompletionstream.ChatCompletionConfigurationInput, streamChannel chan chatcompletionstream.ChatCompletionOutput) *ChatService {
+func NewChatService(chatCompletionStreamUseCase chatcompletionstream.ChatCompletionUseCase, cancelCompletionUseCase cancelcompletion.CancelCompletionUseCase, chatHistoryUseCase chathistory.ChatHistoryUseCase, listChatsUseCase listchats.ListChatsUseCase, listBranchesUseCase listbranches.ListBranchesUseCase, switchBranchUseCase switchbranch.SwitchBranchUseCase, forkChatUseCase forkchat.ForkChatUseCase, commitChoiceUseCase commitchoice.CommitChoiceUseCase, attachDocumentUseCase attachdocument.AttachDocumentUseCase, listDocumentsUseCase listdocuments.ListDocumentsUseCase, embeddingsUseCase embeddings.EmbeddingsUseCase, chatConfig *chatconfig.ConfigurationStore, embeddingsConfig *embeddings.ConfigurationStore) *ChatService {
 	return &ChatService{
 		ChatCompletionStreamUseCase: chatCompletionStreamUseCase,
-		ChatConfigStream:            chatConfigStream,
-		StreamChannel:               streamChannel,
+		CancelCompletionUseCase:     cancelCompletionUseCase,
+		ChatHistoryUseCase:          chatHistoryUseCase,
+		ListChatsUseCase:            listChatsUseCase,
+		ListBranchesUseCase:         listBranchesUseCase,
+		SwitchBranchUseCase:         switchBranchUseCase,
+		ForkChatUseCase:             forkChatUseCase,
+		CommitChoiceUseCase:         commitChoiceUseCase,
+		AttachDocumentUseCase:       attachDocumentUseCase,
+		ListDocumentsUseCase:        listDocumentsUseCase,
+		EmbeddingsUseCase:           embeddingsUseCase,
+		ChatConfig:                  chatConfig,
+		EmbeddingsConfig:            embeddingsConfig,
 	}
 }
 
 func (c *ChatService) ChatStream(req *pb.ChatRequest, stream pb.ChatService_ChatStreamServer) error {
-	chatConfig := chatcompletionstream.ChatCompletionConfigurationInput{
-		Model:                c.ChatConfigStream.Model,
-		ModelMaxTokens:       c.ChatConfigStream.ModelMaxTokens,
-		Temperature:          c.ChatConfigStream.Temperature,
-		TopP:                 c.ChatConfigStream.TopP,
-		N:                    c.ChatConfigStream.N,
-		Stop:                 c.ChatConfigStream.Stop,
-		MaxTokens:            c.ChatConfigStream.MaxTokens,
-		InitialSystemMessage: c.ChatConfigStream.InitialSystemMessage,
+	input := chatcompletionstream.ChatCompletionInput{
+		RequestID:         req.GetRequestId(),
+		UserMessage:       req.GetUserMessage(),
+		UserContent:       toContentPartsInput(req.GetUserContent()),
+		UserID:            req.GetUserId(),
+		ChatID:            req.GetChatId(),
+		PinMessage:        req.GetPinMessage(),
+		ParentMessageID:   req.GetParentId(),
+		ResponseSchema:    toRawSchema(req.GetResponseSchema()),
+		Template:          req.GetTemplate(),
+		TemplateVersion:   int(req.GetTemplateVersion()),
+		TemplateVariables: req.GetTemplateVariables(),
+		AssistantID:       req.GetAssistantId(),
+		Overrides:         toOverridesInput(req.GetConfiguration()),
+		Configuration:     c.ChatConfig.Load(),
 	}
+	return c.stream(input, stream)
+}
+
+func (c *ChatService) RegenerateStream(req *pb.RegenerateRequest, stream pb.ChatService_RegenerateStreamServer) error {
 	input := chatcompletionstream.ChatCompletionInput{
-		UserMessage: req.GetUserMessage(),
-		UserID:      req.GetUserId(),
-		ChatID:      req.GetChatId(),
-		Config:      chatConfig,
+		RequestID:     req.GetRequestId(),
+		UserID:        req.GetUserId(),
+		ChatID:        req.GetChatId(),
+		Regenerate:    true,
+		Configuration: c.ChatConfig.Load(),
 	}
+	return c.stream(input, stream)
+}
+
+// chatResponseStream is implemented by the server side of every rpc that
+// streams ChatResponse frames.
+type chatResponseStream interface {
+	Context() context.Context
+	Send(*pb.ChatResponse) error
+}
 
+// stream runs the completion, sending every partial response followed by a
+// final frame with the finish reason and every candidate generated.
+func (c *ChatService) stream(input chatcompletionstream.ChatCompletionInput, stream chatResponseStream) error {
 	ctx := stream.Context()
+	streamChannel := make(chan chatcompletionstream.ChatCompletionOutput)
+	done := make(chan struct{})
 	go func() {
-		for msg := range c.StreamChannel {
+		defer close(done)
+		for msg := range streamChannel {
 			stream.Send(&pb.ChatResponse{
-				ChatId:  msg.ChatID,
-				UserId:  msg.UserID,
-				Content: msg.Content,
+				RequestId:   msg.RequestID,
+				ChatId:      msg.ChatID,
+				UserId:      msg.UserID,
+				Content:     msg.Content,
+				Index:       int32(msg.Index),
+				Provisional: msg.Provisional,
 			})
 		}
 	}()
 
-	_, err := c.ChatCompletionStreamUseCase.Execute(ctx, input)
+	output, err := c.ChatCompletionStreamUseCase.Execute(ctx, input, streamChannel)
+	close(streamChannel)
+	<-done
 	if err != nil {
-		return err
+		return toStatusError(err)
 	}
 
-	return nil
+	if ctx.Err() != nil {
+		// the client went away, there is nobody left to receive the last frame
+		return nil
+	}
+	response := &pb.ChatResponse{
+		RequestId:    output.RequestID,
+		ChatId:       output.ChatID,
+		UserId:       output.UserID,
+		Content:      output.Content,
+		Object:       string(output.Object),
+		FinishReason: output.FinishReason,
+	}
+	for _, choice := range output.Choices {
+		response.Choices = append(response.Choices, &pb.Choice{
+			Index:        int32(choice.Index),
+			MessageId:    choice.MessageID,
+			Content:      choice.Content,
+			FinishReason: choice.FinishReason,
+		})
+	}
+	for _, source := range output.Sources {
+		response.Sources = append(response.Sources, &pb.Source{
+			DocumentId: source.DocumentID,
+			Source:     source.Source,
+			Score:      source.Score,
+		})
+	}
+	return stream.Send(response)
+}
+
+func (c *ChatService) CancelChat(ctx context.Context, req *pb.CancelChatRequest) (*pb.CancelChatResponse, error) {
+	output, err := c.CancelCompletionUseCase.Execute(ctx, cancelcompletion.CancelCompletionInput{
+		RequestID: req.GetRequestId(),
+		UserID:    req.GetUserId(),
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.CancelChatResponse{
+		RequestId: output.RequestID,
+		Cancelled: output.Cancelled,
+	}, nil
+}
+
+func (c *ChatService) ListChats(ctx context.Context, req *pb.ListChatsRequest) (*pb.ListChatsResponse, error) {
+	output, err := c.ListChatsUseCase.Execute(ctx, listchats.ListChatsInput{
+		UserID: req.GetUserId(),
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	response := &pb.ListChatsResponse{}
+	for _, chat := range output.Chats {
+		response.Chats = append(response.Chats, &pb.ChatSummary{
+			ChatId:      chat.ChatID,
+			Title:       chat.Title,
+			Summary:     chat.Summary,
+			Status:      chat.Status,
+			Model:       chat.Model,
+			Cost:        chat.Cost,
+			AssistantId: chat.AssistantID,
+		})
+	}
+	return response, nil
+}
+
+func (c *ChatService) GetChat(ctx context.Context, req *pb.GetChatRequest) (*pb.GetChatResponse, error) {
+	output, err := c.ChatHistoryUseCase.Execute(ctx, chathistory.ChatHistoryInput{
+		ChatID: req.GetChatId(),
+		UserID: req.GetUserId(),
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	response := &pb.GetChatResponse{
+		ChatId:       output.ChatID,
+		UserId:       output.UserID,
+		Title:        output.Title,
+		Summary:      output.Summary,
+		Status:       output.Status,
+		Model:        output.Model,
+		Cost:         output.Cost,
+		ActiveLeafId: output.ActiveLeafID,
+		AssistantId:  output.AssistantID,
+	}
+	for _, message := range output.Messages {
+		chatMessage := &pb.ChatMessage{
+			Id:           message.ID,
+			Role:         message.Role,
+			Content:      message.Content,
+			FinishReason: message.FinishReason,
+			Pinned:       message.Pinned,
+			Erased:       message.Erased,
+			CreatedAt:    message.CreatedAt.Format(time.RFC3339),
+			ParentId:     message.ParentID,
+			Branch:       int32(message.Branch),
+			Active:       message.Active,
+			ToolCallId:   message.ToolCallID,
+		}
+		for _, call := range message.ToolCalls {
+			chatMessage.ToolCalls = append(chatMessage.ToolCalls, &pb.ToolCall{
+				Id:        call.ID,
+				Name:      call.Name,
+				Arguments: call.Arguments,
+			})
+		}
+		for _, part := range message.ContentParts {
+			chatMessage.ContentParts = append(chatMessage.ContentParts, &pb.ContentPart{
+				Type:         part.Type,
+				Text:         part.Text,
+				Url:          part.URL,
+				MediaType:    part.MediaType,
+				Data:         part.Data,
+				AttachmentId: part.AttachmentID,
+				Detail:       part.Detail,
+			})
+		}
+		response.Messages = append(response.Messages, chatMessage)
+	}
+	return response, nil
+}
+
+func (c *ChatService) ListBranches(ctx context.Context, req *pb.ListBranchesRequest) (*pb.ListBranchesResponse, error) {
+	output, err := c.ListBranchesUseCase.Execute(ctx, listbranches.ListBranchesInput{
+		ChatID: req.GetChatId(),
+		UserID: req.GetUserId(),
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	response := &pb.ListBranchesResponse{
+		ChatId:       output.ChatID,
+		ActiveLeafId: output.ActiveLeafID,
+	}
+	for _, branch := range output.Branches {
+		response.Branches = append(response.Branches, &pb.Branch{
+			LeafId:    branch.LeafID,
+			Branch:    int32(branch.Branch),
+			Role:      branch.Role,
+			Content:   branch.Content,
+			Messages:  int32(branch.Messages),
+			Active:    branch.Active,
+			CreatedAt: branch.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return response, nil
+}
+
+func (c *ChatService) SwitchBranch(ctx context.Context, req *pb.SwitchBranchRequest) (*pb.SwitchBranchResponse, error) {
+	output, err := c.SwitchBranchUseCase.Execute(ctx, switchbranch.SwitchBranchInput{
+		ChatID:    req.GetChatId(),
+		UserID:    req.GetUserId(),
+		MessageID: req.GetMessageId(),
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.SwitchBranchResponse{
+		ChatId:       output.ChatID,
+		ActiveLeafId: output.ActiveLeafID,
+	}, nil
+}
+
+func (c *ChatService) ForkChat(ctx context.Context, req *pb.ForkChatRequest) (*pb.ForkChatResponse, error) {
+	output, err := c.ForkChatUseCase.Execute(ctx, forkchat.ForkChatInput{
+		ChatID:    req.GetChatId(),
+		UserID:    req.GetUserId(),
+		OwnerID:   req.GetOwnerId(),
+		MessageID: req.GetMessageId(),
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.ForkChatResponse{
+		ChatId:       output.ChatID,
+		SourceChatId: output.SourceChatID,
+		UserId:       output.UserID,
+		ActiveLeafId: output.ActiveLeafID,
+	}, nil
+}
+
+func (c *ChatService) CommitChoice(ctx context.Context, req *pb.CommitChoiceRequest) (*pb.CommitChoiceResponse, error) {
+	output, err := c.CommitChoiceUseCase.Execute(ctx, commitchoice.CommitChoiceInput{
+		ChatID:    req.GetChatId(),
+		UserID:    req.GetUserId(),
+		MessageID: req.GetMessageId(),
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.CommitChoiceResponse{
+		ChatId:       output.ChatID,
+		ActiveLeafId: output.ActiveLeafID,
+		Content:      output.Content,
+	}, nil
+}
+
+func (c *ChatService) AttachDocument(ctx context.Context, req *pb.AttachDocumentRequest) (*pb.AttachDocumentResponse, error) {
+	output, err := c.AttachDocumentUseCase.Execute(ctx, attachdocument.AttachDocumentInput{
+		ChatID:  req.GetChatId(),
+		UserID:  req.GetUserId(),
+		Name:    req.GetName(),
+		Content: req.GetContent(),
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.AttachDocumentResponse{
+		DocumentId: output.DocumentID,
+		ChatId:     output.ChatID,
+		Name:       output.Name,
+		Chunks:     int32(output.Chunks),
+	}, nil
+}
+
+func (c *ChatService) ListDocuments(ctx context.Context, req *pb.ListDocumentsRequest) (*pb.ListDocumentsResponse, error) {
+	output, err := c.ListDocumentsUseCase.Execute(ctx, listdocuments.ListDocumentsInput{
+		ChatID: req.GetChatId(),
+		UserID: req.GetUserId(),
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	response := &pb.ListDocumentsResponse{
+		ChatId: output.ChatID,
+	}
+	for _, document := range output.Documents {
+		response.Documents = append(response.Documents, &pb.Document{
+			DocumentId: document.DocumentID,
+			Name:       document.Name,
+			Chunks:     int32(document.Chunks),
+			CreatedAt:  document.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return response, nil
+}
+
+func (c *ChatService) Embeddings(ctx context.Context, req *pb.EmbeddingsRequest) (*pb.EmbeddingsResponse, error) {
+	output, err := c.EmbeddingsUseCase.Execute(ctx, embeddings.EmbeddingsInput{
+		Model:  req.GetModel(),
+		Inputs: req.GetInputs(),
+		Config: c.EmbeddingsConfig.Load(),
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	response := &pb.EmbeddingsResponse{
+		Model:        output.Model,
+		PromptTokens: int32(output.PromptTokens),
+		CachedInputs: int32(output.CachedInputs),
+		Cost:         output.Cost,
+	}
+	for _, item := range output.Data {
+		response.Data = append(response.Data, &pb.Embedding{
+			Index:  int32(item.Index),
+			Values: item.Embedding,
+		})
+	}
+	return response, nil
+}
+
+func toOverridesInput(cfg *pb.ChatConfiguration) *chatconfig.OverridesInput {
+	if cfg == nil {
+		return nil
+	}
+
+	overrides := &chatconfig.OverridesInput{
+		Model:            cfg.Model,
+		Temperature:      cfg.Temperature,
+		TopP:             cfg.TopP,
+		Stop:             cfg.Stop,
+		PresencePenalty:  cfg.PresencePenalty,
+		FrequencyPenalty: cfg.FrequencyPenalty,
+		SystemMessage:    cfg.SystemMessage,
+		ContextStrategy:  cfg.ContextStrategy,
+		Tools:            cfg.Tools,
+	}
+	if cfg.MaxTokens != nil {
+		maxTokens := int(*cfg.MaxTokens)
+		overrides.MaxTokens = &maxTokens
+	}
+	if cfg.N != nil {
+		n := int(*cfg.N)
+		overrides.N = &n
+	}
+	if cfg.ResponseSchema != nil {
+		overrides.ResponseSchema = toRawSchema(*cfg.ResponseSchema)
+	}
+	return overrides
+}
+
+func toContentPartsInput(parts []*pb.ContentPart) []chatcompletionstream.ChatCompletionContentPartInput {
+	converted := make([]chatcompletionstream.ChatCompletionContentPartInput, 0, len(parts))
+	for _, part := range parts {
+		converted = append(converted, chatcompletionstream.ChatCompletionContentPartInput{
+			Type:         part.GetType(),
+			Text:         part.GetText(),
+			URL:          part.GetUrl(),
+			MediaType:    part.GetMediaType(),
+			Data:         part.GetData(),
+			AttachmentID: part.GetAttachmentId(),
+			Detail:       part.GetDetail(),
+		})
+	}
+	return converted
+}
+
+// toRawSchema carries the JSON schema sent as a string, leaving it nil when
+// empty so the chat schema applies.
+func toRawSchema(schema string) json.RawMessage {
+	if schema == "" {
+		return nil
+	}
+	return json.RawMessage(schema)
 }
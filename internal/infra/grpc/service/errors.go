@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+	"github.com/gabrielmq/chat-service/internal/usecase/attachdocument"
+	"github.com/gabrielmq/chat-service/internal/usecase/cancelcompletion"
+	"github.com/gabrielmq/chat-service/internal/usecase/chatcompletionstream"
+	"github.com/gabrielmq/chat-service/internal/usecase/chathistory"
+	"github.com/gabrielmq/chat-service/internal/usecase/commitchoice"
+	"github.com/gabrielmq/chat-service/internal/usecase/forkchat"
+	"github.com/gabrielmq/chat-service/internal/usecase/listbranches"
+	"github.com/gabrielmq/chat-service/internal/usecase/listdocuments"
+	"github.com/gabrielmq/chat-service/internal/usecase/switchbranch"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// notFoundErrors are the missing chats, messages, candidate replies and
+// generations reported by the use cases.
+var notFoundErrors = []error{
+	chatcompletionstream.ErrChatNotFound,
+	chathistory.ErrChatNotFound,
+	listbranches.ErrChatNotFound,
+	switchbranch.ErrChatNotFound,
+	forkchat.ErrChatNotFound,
+	commitchoice.ErrChatNotFound,
+	attachdocument.ErrChatNotFound,
+	listdocuments.ErrChatNotFound,
+	cancelcompletion.ErrGenerationNotFound,
+	entity.ErrMessageNotFound,
+	entity.ErrChoiceNotFound,
+}
+
+// toStatusError turns errors caused by the request into InvalidArgument,
+// adding a BadRequest detail per invalid field for validation failures.
+// Missing chats, messages, candidate replies and generations are NotFound
+// and chats that cannot be regenerated are FailedPrecondition. Request ids
+// of running generations are AlreadyExists. Replies that
+// never matched the response schema are Unavailable, as asking again may
+// succeed. Status errors are returned untouched, cancelled requests are
+// Canceled or DeadlineExceeded and any other error is Internal.
+func toStatusError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	for _, notFound := range notFoundErrors {
+		if errors.Is(err, notFound) {
+			return status.Error(codes.NotFound, err.Error())
+		}
+	}
+	if errors.Is(err, gateway.ErrRequestIDInUse) {
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	if errors.Is(err, entity.ErrNothingToRegenerate) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	var tooLarge *entity.MessageTooLargeError
+	if errors.As(err, &tooLarge) {
+		return status.Error(codes.InvalidArgument, tooLarge.Error())
+	}
+
+	var outputErr *entity.StructuredOutputError
+	if errors.As(err, &outputErr) {
+		return status.Error(codes.Unavailable, outputErr.Error())
+	}
+
+	var verr *entity.ValidationError
+	if !errors.As(err, &verr) {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	badRequest := &errdetails.BadRequest{}
+	for _, field := range verr.Fields {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field.Field,
+			Description: field.Message,
+		})
+	}
+
+	st, detailsErr := status.New(codes.InvalidArgument, verr.Error()).WithDetails(badRequest)
+	if detailsErr != nil {
+		return status.Error(codes.InvalidArgument, verr.Error())
+	}
+	return st.Err()
+}
@@ -0,0 +1,128 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusDegraded    = "degraded"    // an optional dependency fails, the requests needing it fail too
+	StatusUnavailable = "unavailable" // a required dependency fails, the service should not get traffic
+)
+
+// checkTimeout bounds every probe, a dependency that hangs is reported as
+// failing instead of hanging the orchestrator probe.
+const checkTimeout = 2 * time.Second
+
+// Probe reports whether a dependency can be reached.
+type Probe func(ctx context.Context) error
+
+type check struct {
+	name     string
+	required bool
+	probe    Probe
+	interval time.Duration
+
+	mu      sync.Mutex
+	checked time.Time
+	err     error
+}
+
+// Checker probes the dependencies of the service for the readiness endpoints.
+type Checker struct {
+	checks       []*check
+	shuttingDown atomic.Bool
+}
+
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+// Require adds a dependency the service cannot serve without.
+func (c *Checker) Require(name string, probe Probe) {
+	c.checks = append(c.checks, &check{name: name, required: true, probe: probe})
+}
+
+// Optional adds a dependency whose failure degrades the service. It is probed
+// at most once per interval, the result is reused in between, so probes that
+// cost requests to a third party are not sent on every check.
+func (c *Checker) Optional(name string, probe Probe, interval time.Duration) {
+	c.checks = append(c.checks, &check{name: name, probe: probe, interval: interval})
+}
+
+// Shutdown reports the service as unavailable from now on, so it gets no new
+// traffic while the running requests finish.
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+}
+
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Check probes every dependency concurrently.
+func (c *Checker) Check(ctx context.Context) Report {
+	if c.shuttingDown.Load() {
+		return Report{
+			Status: StatusUnavailable,
+			Checks: map[string]CheckResult{"server": {Status: StatusUnavailable, Error: "shutting down"}},
+		}
+	}
+
+	errs := make([]error, len(c.checks))
+	var wg sync.WaitGroup
+	for i := range c.checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = c.checks[i].run(ctx)
+		}(i)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(c.checks))}
+	for i, check := range c.checks {
+		if errs[i] == nil {
+			report.Checks[check.name] = CheckResult{Status: StatusOK}
+			continue
+		}
+		if check.required {
+			report.Status = StatusUnavailable
+			report.Checks[check.name] = CheckResult{Status: StatusUnavailable, Error: errs[i].Error()}
+			continue
+		}
+		if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+		report.Checks[check.name] = CheckResult{Status: StatusDegraded, Error: errs[i].Error()}
+	}
+	return report
+}
+
+func (c *check) run(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.interval > 0 && !c.checked.IsZero() && time.Since(c.checked) < c.interval {
+		return c.err
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	err := c.probe(probeCtx)
+	if ctx.Err() != nil {
+		// the caller went away, the failure says nothing of the dependency
+		return err
+	}
+	c.err = err
+	c.checked = time.Now()
+	return err
+}
@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/infra/db"
+)
+
+type AssistantRepositoryMySQL struct {
+	DB      *sql.DB
+	Queries *db.Queries
+}
+
+func NewAssistantRepositoryMySQL(dbt *sql.DB) *AssistantRepositoryMySQL {
+	return &AssistantRepositoryMySQL{
+		DB:      dbt,
+		Queries: db.New(dbt),
+	}
+}
+
+func (r *AssistantRepositoryMySQL) Create(ctx context.Context, assistant *entity.Assistant) error {
+	configuration := assistant.Configuration
+	return r.Queries.CreateAssistant(ctx, db.CreateAssistantParams{
+		ID:               assistant.ID,
+		Name:             assistant.Name,
+		Description:      assistant.Description,
+		SystemMessage:    assistant.SystemMessage,
+		Model:            configuration.Model.GetName(),
+		Temperature:      float64(configuration.Temperature),
+		TopP:             float64(configuration.TopP),
+		N:                int32(configuration.N),
+		Stop:             encodeStop(configuration.Stop),
+		MaxTokens:        int32(configuration.MaxTokens),
+		PresencePenalty:  float64(configuration.PresencePenalty),
+		FrequencyPenalty: float64(configuration.FrequencyPenalty),
+		ContextStrategy:  configuration.Strategy().Name(),
+		Tools:            encodeTools(configuration.Tools),
+		ResponseSchema:   string(configuration.ResponseSchema),
+		CreatedAt:        assistant.CreatedAt,
+		UpdatedAt:        assistant.UpdatedAt,
+	})
+}
+
+func (r *AssistantRepositoryMySQL) Save(ctx context.Context, assistant *entity.Assistant) error {
+	configuration := assistant.Configuration
+	return r.Queries.UpdateAssistant(ctx, db.UpdateAssistantParams{
+		Name:             assistant.Name,
+		Description:      assistant.Description,
+		SystemMessage:    assistant.SystemMessage,
+		Model:            configuration.Model.GetName(),
+		Temperature:      float64(configuration.Temperature),
+		TopP:             float64(configuration.TopP),
+		N:                int32(configuration.N),
+		Stop:             encodeStop(configuration.Stop),
+		MaxTokens:        int32(configuration.MaxTokens),
+		PresencePenalty:  float64(configuration.PresencePenalty),
+		FrequencyPenalty: float64(configuration.FrequencyPenalty),
+		ContextStrategy:  configuration.Strategy().Name(),
+		Tools:            encodeTools(configuration.Tools),
+		ResponseSchema:   string(configuration.ResponseSchema),
+		UpdatedAt:        assistant.UpdatedAt,
+		ID:               assistant.ID,
+	})
+}
+
+// FindByID returns the assistant with only the name of its model, callers
+// resolve it in the model registry.
+func (r *AssistantRepositoryMySQL) FindByID(ctx context.Context, id string) (*entity.Assistant, error) {
+	assistantResult, err := r.Queries.FindAssistantByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("assistant not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toAssistant(assistantResult), nil
+}
+
+func (r *AssistantRepositoryMySQL) FindAll(ctx context.Context) ([]*entity.Assistant, error) {
+	assistantsResult, err := r.Queries.ListAssistants(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	assistants := make([]*entity.Assistant, 0, len(assistantsResult))
+	for _, assistantResult := range assistantsResult {
+		assistants = append(assistants, toAssistant(assistantResult))
+	}
+	return assistants, nil
+}
+
+func (r *AssistantRepositoryMySQL) Delete(ctx context.Context, id string) error {
+	deleted, err := r.Queries.DeleteAssistant(ctx, id)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return errors.New("assistant not found")
+	}
+	return nil
+}
+
+func toAssistant(assistantResult db.Assistant) *entity.Assistant {
+	return &entity.Assistant{
+		ID:            assistantResult.ID,
+		Name:          assistantResult.Name,
+		Description:   assistantResult.Description,
+		SystemMessage: assistantResult.SystemMessage,
+		Configuration: &entity.ChatConfiguration{
+			Model:            &entity.Model{Name: assistantResult.Model},
+			Temperature:      float32(assistantResult.Temperature),
+			TopP:             float32(assistantResult.TopP),
+			N:                int(assistantResult.N),
+			Stop:             decodeStop(assistantResult.Stop),
+			MaxTokens:        int(assistantResult.MaxTokens),
+			PresencePenalty:  float32(assistantResult.PresencePenalty),
+			FrequencyPenalty: float32(assistantResult.FrequencyPenalty),
+			ContextStrategy:  assistantResult.ContextStrategy,
+			Tools:            decodeTools(assistantResult.Tools),
+			ResponseSchema:   decodeResponseSchema(assistantResult.ResponseSchema),
+		},
+		CreatedAt: assistantResult.CreatedAt,
+		UpdatedAt: assistantResult.UpdatedAt,
+	}
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/infra/db"
+)
+
+type AttachmentRepositoryMySQL struct {
+	DB      *sql.DB
+	Queries *db.Queries
+}
+
+func NewAttachmentRepositoryMySQL(dbt *sql.DB) *AttachmentRepositoryMySQL {
+	return &AttachmentRepositoryMySQL{
+		DB:      dbt,
+		Queries: db.New(dbt),
+	}
+}
+
+func (r *AttachmentRepositoryMySQL) Create(ctx context.Context, attachment *entity.Attachment) error {
+	return r.Queries.CreateAttachment(ctx, db.CreateAttachmentParams{
+		ID:          attachment.ID,
+		ChatID:      attachment.ChatID,
+		UserID:      attachment.UserID,
+		Name:        attachment.Name,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		StorageKey:  attachment.StorageKey,
+		CreatedAt:   attachment.CreatedAt,
+	})
+}
+
+func (r *AttachmentRepositoryMySQL) FindByID(ctx context.Context, id string) (*entity.Attachment, error) {
+	attachmentResult, err := r.Queries.FindAttachmentByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("attachment not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.Attachment{
+		ID:          attachmentResult.ID,
+		ChatID:      attachmentResult.ChatID,
+		UserID:      attachmentResult.UserID,
+		Name:        attachmentResult.Name,
+		ContentType: attachmentResult.ContentType,
+		Size:        attachmentResult.Size,
+		StorageKey:  attachmentResult.StorageKey,
+		CreatedAt:   attachmentResult.CreatedAt,
+	}, nil
+}
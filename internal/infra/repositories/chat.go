@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"time"
 
@@ -32,16 +33,24 @@ func (c *ChatRepositoryMySQL) Create(ctx context.Context, chat *entity.Chat) err
 			Status:           chat.Status,
 			TokenUsage:       int32(chat.TokenUsage),
 			Model:            chat.Configuration.Model.GetName(),
-			ModelMaxTokens:   int32(chat.Configuration.MaxTokens),
+			ModelMaxTokens:   int32(chat.Configuration.Model.MaxTokens),
 			Temperature:      float64(chat.Configuration.Temperature),
 			TopP:             float64(chat.Configuration.TopP),
 			N:                int32(chat.Configuration.N),
-			Stop:             chat.Configuration.Stop[0],
+			Stop:             encodeStop(chat.Configuration.Stop),
 			MaxTokens:        int32(chat.Configuration.MaxTokens),
-			PresencePenalty:  float64(chat.Configuration.FrequencyPenalty),
+			PresencePenalty:  float64(chat.Configuration.PresencePenalty),
 			FrequencyPenalty: float64(chat.Configuration.FrequencyPenalty),
 			CreatedAt:        time.Now(),
 			UpdatedAt:        time.Now(),
+			Cost:             chat.Cost,
+			ContextStrategy:  chat.Configuration.Strategy().Name(),
+			Summary:          chat.Summary,
+			ActiveLeafID:     chat.ActiveLeafID,
+			Tools:            encodeTools(chat.Configuration.Tools),
+			ResponseSchema:   string(chat.Configuration.ResponseSchema),
+			AssistantID:      chat.AssistantID,
+			RetrievalTopK:    int32(chat.Configuration.RetrievalTopK),
 		},
 	)
 	if err != nil {
@@ -66,68 +75,89 @@ func (c *ChatRepositoryMySQL) Create(ctx context.Context, chat *entity.Chat) err
 }
 
 func (c *ChatRepositoryMySQL) FindByID(ctx context.Context, chatID string) (*entity.Chat, error) {
-	chat := &entity.Chat{}
 	chatResult, err := c.Queries.FindByID(ctx, chatID)
 	if err != nil {
 		return nil, errors.New("chat not found")
 	}
 
-	chat.ID = chatResult.ID
-	chat.UserID = chatResult.UserID
-	chat.Status = chatResult.Status
-	chat.TokenUsage = int(chatResult.TokenUsage)
-	chat.Configuration = &entity.ChatConfiguration{
-		Model: &entity.Model{
-			Name:      chatResult.Model,
-			MaxTokens: int(chatResult.ModelMaxTokens),
-		},
-		Temperature:      float32(chatResult.Temperature),
-		TopP:             float32(chatResult.TopP),
-		N:                int(chatResult.N),
-		Stop:             []string{chatResult.Stop},
-		MaxTokens:        int(chatResult.MaxTokens),
-		PresencePenalty:  float32(chatResult.PresencePenalty),
-		FrequencyPenalty: float32(chatResult.FrequencyPenalty),
-	}
+	chat := toChat(chatResult)
 
 	messages, err := c.Queries.FindMessagesByChatID(ctx, chatID)
 	if err != nil {
 		return nil, err
 	}
 
+	erased := make(map[string]bool, len(messages))
 	for _, message := range messages {
-		chat.Messages = append(chat.Messages, &entity.Message{
-			ID:        message.ID,
-			Content:   message.Content,
-			Role:      message.Role,
-			Tokens:    int(message.Tokens),
-			Model:     &entity.Model{Name: message.Model},
-			CreatedAt: message.CreatedAt,
-		})
+		chat.InactiveMessages = append(chat.InactiveMessages, toMessage(message))
+		erased[message.ID] = message.Erased
 	}
 
-	erasedMessages, err := c.Queries.FindErasedMessagesByChatID(ctx, chatID)
+	// the active branch follows the parent links up from the active leaf,
+	// the erased flag tells whether a message is still in the context window
+	path, err := chat.PathTo(chatResult.ActiveLeafID)
+	if err != nil {
+		return nil, errors.New("error loading active branch: " + err.Error())
+	}
+	onPath := make(map[string]bool, len(path))
+	for _, message := range path {
+		onPath[message.ID] = true
+		if erased[message.ID] {
+			chat.ErasedMessages = append(chat.ErasedMessages, message)
+		} else {
+			chat.Messages = append(chat.Messages, message)
+		}
+		if message.ID == chatResult.InitialMessageID {
+			chat.InitialSystemMessage = message
+		}
+	}
+	inactive := chat.InactiveMessages
+	chat.InactiveMessages = nil
+	for _, message := range inactive {
+		if !onPath[message.ID] {
+			chat.InactiveMessages = append(chat.InactiveMessages, message)
+		}
+	}
+	if chat.Summary != "" {
+		chat.SummaryTokens = entity.CountTokens(chat.Configuration.Model, chat.Summary)
+	}
+	chat.RefreshTokenUsage()
+	return chat, nil
+}
+
+func (c *ChatRepositoryMySQL) FindByUserID(ctx context.Context, userID string) ([]*entity.Chat, error) {
+	chatsResult, err := c.Queries.FindByUserID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, message := range erasedMessages {
-		chat.ErasedMessages = append(chat.Messages, &entity.Message{
-			ID:        message.ID,
-			Content:   message.Content,
-			Role:      message.Role,
-			Tokens:    int(message.Tokens),
-			Model:     &entity.Model{Name: message.Model},
-			CreatedAt: message.CreatedAt,
-		})
+	chats := make([]*entity.Chat, 0, len(chatsResult))
+	for _, chatResult := range chatsResult {
+		chats = append(chats, toChat(chatResult))
 	}
-	return chat, nil
+	return chats, nil
+}
+
+func (c *ChatRepositoryMySQL) UpdateTitle(ctx context.Context, chatID string, title string) error {
+	return c.Queries.UpdateTitle(ctx, db.UpdateTitleParams{Title: title, ID: chatID})
+}
+
+func (c *ChatRepositoryMySQL) UpdateSummary(ctx context.Context, chatID string, summary string) error {
+	return c.Queries.UpdateSummary(ctx, db.UpdateSummaryParams{Summary: summary, ID: chatID})
 }
 
+// Save rewrites the chat and all of its messages in one transaction, so a
+// failure never leaves the chat without part of its messages.
 func (r *ChatRepositoryMySQL) Save(ctx context.Context, chat *entity.Chat) error {
+	initialMessageID := ""
+	if chat.InitialSystemMessage != nil {
+		initialMessageID = chat.InitialSystemMessage.ID
+	}
+
 	params := db.SaveParams{
 		ID:               chat.ID,
 		UserID:           chat.UserID,
+		InitialMessageID: initialMessageID,
 		Status:           chat.Status,
 		TokenUsage:       int32(chat.TokenUsage),
 		Model:            chat.Configuration.Model.Name,
@@ -135,73 +165,244 @@ func (r *ChatRepositoryMySQL) Save(ctx context.Context, chat *entity.Chat) error
 		Temperature:      float64(chat.Configuration.Temperature),
 		TopP:             float64(chat.Configuration.TopP),
 		N:                int32(chat.Configuration.N),
-		Stop:             chat.Configuration.Stop[0],
+		Stop:             encodeStop(chat.Configuration.Stop),
 		MaxTokens:        int32(chat.Configuration.MaxTokens),
 		PresencePenalty:  float64(chat.Configuration.PresencePenalty),
 		FrequencyPenalty: float64(chat.Configuration.FrequencyPenalty),
+		Cost:             chat.Cost,
+		ContextStrategy:  chat.Configuration.Strategy().Name(),
+		ActiveLeafID:     chat.ActiveLeafID,
+		Tools:            encodeTools(chat.Configuration.Tools),
+		ResponseSchema:   string(chat.Configuration.ResponseSchema),
 		UpdatedAt:        time.Now(),
 	}
 
-	err := r.Queries.Save(
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	queries := r.Queries.WithTx(tx)
+
+	err = queries.Save(
 		ctx,
 		params,
 	)
 	if err != nil {
 		return err
 	}
+	// the title and the running summary of the other strategies are written
+	// in the background, only the summarizing strategy owns the summary here
+	if chat.Configuration.Strategy().Summarizes() {
+		err = queries.UpdateSummary(ctx, db.UpdateSummaryParams{Summary: chat.Summary, ID: chat.ID})
+		if err != nil {
+			return err
+		}
+	}
 	// delete messages
-	err = r.Queries.DeleteChatMessages(ctx, chat.ID)
+	err = queries.DeleteChatMessages(ctx, chat.ID)
 	if err != nil {
 		return err
 	}
 	// delete erased messages
-	err = r.Queries.DeleteErasedChatMessages(ctx, chat.ID)
+	err = queries.DeleteErasedChatMessages(ctx, chat.ID)
 	if err != nil {
 		return err
 	}
 	// save messages
-	i := 0
 	for _, message := range chat.Messages {
-		err = r.Queries.AddMessage(
-			ctx,
-			db.AddMessageParams{
-				ID:        message.ID,
-				ChatID:    chat.ID,
-				Content:   message.Content,
-				Role:      message.Role,
-				Tokens:    int32(message.Tokens),
-				Model:     chat.Configuration.Model.Name,
-				CreatedAt: message.CreatedAt,
-				OrderMsg:  int32(i),
-				Erased:    false,
-			},
-		)
-		if err != nil {
+		if err = addMessage(ctx, queries, chat, message, false); err != nil {
 			return err
 		}
-		i++
 	}
 	// save erased messages
-	i = 0
 	for _, message := range chat.ErasedMessages {
-		err = r.Queries.AddMessage(
-			ctx,
-			db.AddMessageParams{
-				ID:        message.ID,
-				ChatID:    chat.ID,
-				Content:   message.Content,
-				Role:      message.Role,
-				Tokens:    int32(message.Tokens),
-				Model:     chat.Configuration.Model.Name,
-				CreatedAt: message.CreatedAt,
-				OrderMsg:  int32(i),
-				Erased:    true,
-			},
-		)
-		if err != nil {
+		if err = addMessage(ctx, queries, chat, message, true); err != nil {
 			return err
 		}
-		i++
 	}
-	return nil
+	// save the other branches
+	for _, message := range chat.InactiveMessages {
+		if err = addMessage(ctx, queries, chat, message, false); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func addMessage(ctx context.Context, queries *db.Queries, chat *entity.Chat, message *entity.Message, erased bool) error {
+	return queries.AddMessage(
+		ctx,
+		db.AddMessageParams{
+			ID:           message.ID,
+			ChatID:       chat.ID,
+			Content:      message.Content,
+			Role:         message.Role,
+			Tokens:       int32(message.Tokens),
+			Model:        chat.Configuration.Model.Name,
+			CreatedAt:    message.CreatedAt,
+			Erased:       erased,
+			FinishReason: message.FinishReason,
+			Pinned:       message.Pinned,
+			ParentID:     message.ParentID,
+			Branch:       int32(message.Branch),
+			ToolCalls:    encodeToolCalls(message.ToolCalls),
+			ToolCallID:   message.ToolCallID,
+			ContentParts: encodeContentParts(message.Parts),
+		},
+	)
+}
+
+func toMessage(message db.Message) *entity.Message {
+	return &entity.Message{
+		ID:           message.ID,
+		Content:      message.Content,
+		Role:         message.Role,
+		Tokens:       int(message.Tokens),
+		Model:        &entity.Model{Name: message.Model},
+		FinishReason: message.FinishReason,
+		Pinned:       message.Pinned,
+		ParentID:     message.ParentID,
+		Branch:       int(message.Branch),
+		ToolCalls:    decodeToolCalls(message.ToolCalls),
+		ToolCallID:   message.ToolCallID,
+		Parts:        decodeContentParts(message.ContentParts),
+		CreatedAt:    message.CreatedAt,
+	}
+}
+
+// toChat maps a chats row, the messages are loaded apart.
+func toChat(chatResult db.Chat) *entity.Chat {
+	return &entity.Chat{
+		ID:           chatResult.ID,
+		UserID:       chatResult.UserID,
+		AssistantID:  chatResult.AssistantID,
+		Title:        chatResult.Title,
+		Summary:      chatResult.Summary,
+		Status:       chatResult.Status,
+		ActiveLeafID: chatResult.ActiveLeafID,
+		TokenUsage:   int(chatResult.TokenUsage),
+		Cost:         chatResult.Cost,
+		Configuration: &entity.ChatConfiguration{
+			Model: &entity.Model{
+				Name:      chatResult.Model,
+				MaxTokens: int(chatResult.ModelMaxTokens),
+			},
+			Temperature:      float32(chatResult.Temperature),
+			TopP:             float32(chatResult.TopP),
+			N:                decodeN(chatResult.N),
+			Stop:             decodeStop(chatResult.Stop),
+			MaxTokens:        int(chatResult.MaxTokens),
+			PresencePenalty:  float32(chatResult.PresencePenalty),
+			FrequencyPenalty: float32(chatResult.FrequencyPenalty),
+			ContextStrategy:  chatResult.ContextStrategy,
+			Tools:            decodeTools(chatResult.Tools),
+			ResponseSchema:   decodeResponseSchema(chatResult.ResponseSchema),
+			RetrievalTopK:    int(chatResult.RetrievalTopK),
+		},
+	}
+}
+
+// decodeN reads the number of replies generated per message. Chats stored
+// before it was validated may hold 0, they get a single reply.
+func decodeN(n int32) int {
+	if n < 1 {
+		return 1
+	}
+	return int(n)
+}
+
+// encodeStop stores the stop sequences as a JSON array in the stop column.
+func encodeStop(stop []string) string {
+	if len(stop) == 0 {
+		return ""
+	}
+	encoded, err := json.Marshal(stop)
+	if err != nil {
+		return stop[0]
+	}
+	return string(encoded)
+}
+
+// decodeStop also accepts the single sequence stored by older rows.
+func decodeStop(stop string) []string {
+	if stop == "" {
+		return nil
+	}
+	var decoded []string
+	if err := json.Unmarshal([]byte(stop), &decoded); err != nil {
+		return []string{stop}
+	}
+	return decoded
+}
+
+// encodeTools stores the names of the enabled tools as a JSON array.
+func encodeTools(tools []string) string {
+	if len(tools) == 0 {
+		return ""
+	}
+	encoded, err := json.Marshal(tools)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+func decodeTools(tools string) []string {
+	var decoded []string
+	if tools == "" || json.Unmarshal([]byte(tools), &decoded) != nil {
+		return nil
+	}
+	return decoded
+}
+
+// decodeResponseSchema keeps chats without a schema at a nil value, so they
+// are not mistaken for chats asking for structured output.
+func decodeResponseSchema(schema string) json.RawMessage {
+	if schema == "" {
+		return nil
+	}
+	return json.RawMessage(schema)
+}
+
+// encodeToolCalls stores the calls of an assistant message as a JSON array,
+// messages without calls keep the column empty.
+func encodeToolCalls(calls []entity.ToolCall) string {
+	if len(calls) == 0 {
+		return ""
+	}
+	encoded, err := json.Marshal(calls)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+func decodeToolCalls(calls string) []entity.ToolCall {
+	var decoded []entity.ToolCall
+	if calls == "" || json.Unmarshal([]byte(calls), &decoded) != nil {
+		return nil
+	}
+	return decoded
+}
+
+// encodeContentParts stores the parts of a multimodal message as a JSON
+// array, text only messages keep the column empty.
+func encodeContentParts(parts []entity.ContentPart) string {
+	if len(parts) == 0 {
+		return ""
+	}
+	encoded, err := json.Marshal(parts)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+func decodeContentParts(parts string) []entity.ContentPart {
+	var decoded []entity.ContentPart
+	if parts == "" || json.Unmarshal([]byte(parts), &decoded) != nil {
+		return nil
+	}
+	return decoded
 }
@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/infra/db"
+)
+
+type DocumentRepositoryMySQL struct {
+	DB      *sql.DB
+	Queries *db.Queries
+}
+
+func NewDocumentRepositoryMySQL(dbt *sql.DB) *DocumentRepositoryMySQL {
+	return &DocumentRepositoryMySQL{
+		DB:      dbt,
+		Queries: db.New(dbt),
+	}
+}
+
+// Create stores the document and its chunks in one transaction, so searches
+// never see a document with part of its chunks.
+func (r *DocumentRepositoryMySQL) Create(ctx context.Context, document *entity.Document) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	queries := r.Queries.WithTx(tx)
+
+	err = queries.CreateDocument(ctx, db.CreateDocumentParams{
+		ID:        document.ID,
+		ChatID:    document.ChatID,
+		UserID:    document.UserID,
+		Name:      document.Name,
+		Chunks:    int32(document.ChunkCount),
+		CreatedAt: document.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	for _, chunk := range document.Chunks {
+		err = queries.AddDocumentChunk(ctx, db.AddDocumentChunkParams{
+			ID:         chunk.ID,
+			DocumentID: document.ID,
+			ChatID:     document.ChatID,
+			Position:   int32(chunk.Position),
+			Content:    chunk.Content,
+			Embedding:  encodeEmbedding(chunk.Embedding),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *DocumentRepositoryMySQL) FindByChatID(ctx context.Context, chatID string) ([]*entity.Document, error) {
+	documentsResult, err := r.Queries.FindDocumentsByChatID(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := make([]*entity.Document, 0, len(documentsResult))
+	for _, documentResult := range documentsResult {
+		documents = append(documents, &entity.Document{
+			ID:         documentResult.ID,
+			ChatID:     documentResult.ChatID,
+			UserID:     documentResult.UserID,
+			Name:       documentResult.Name,
+			ChunkCount: int(documentResult.Chunks),
+			CreatedAt:  documentResult.CreatedAt,
+		})
+	}
+	return documents, nil
+}
+
+func (r *DocumentRepositoryMySQL) FindChunksByChatID(ctx context.Context, chatID string) ([]*entity.DocumentChunk, error) {
+	chunksResult, err := r.Queries.FindDocumentChunksByChatID(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make([]*entity.DocumentChunk, 0, len(chunksResult))
+	for _, chunkResult := range chunksResult {
+		embedding, err := decodeEmbedding(chunkResult.Embedding)
+		if err != nil {
+			return nil, errors.New("error decoding embedding of chunk " + chunkResult.ID + ": " + err.Error())
+		}
+		chunks = append(chunks, &entity.DocumentChunk{
+			ID:           chunkResult.ID,
+			DocumentID:   chunkResult.DocumentID,
+			DocumentName: chunkResult.Name,
+			Position:     int(chunkResult.Position),
+			Content:      chunkResult.Content,
+			Embedding:    embedding,
+		})
+	}
+	return chunks, nil
+}
+
+// encodeEmbedding stores the vector as little endian float32 values, a
+// quarter of the size of the same vector in JSON.
+func encodeEmbedding(embedding []float32) []byte {
+	encoded := make([]byte, 4*len(embedding))
+	for i, value := range embedding {
+		binary.LittleEndian.PutUint32(encoded[4*i:], math.Float32bits(value))
+	}
+	return encoded
+}
+
+func decodeEmbedding(encoded []byte) ([]float32, error) {
+	if len(encoded)%4 != 0 {
+		return nil, errors.New("embedding length is not a multiple of 4 bytes")
+	}
+	embedding := make([]float32, len(encoded)/4)
+	for i := range embedding {
+		embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32(encoded[4*i:]))
+	}
+	return embedding, nil
+}
@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrModelNotFound = errors.New("model not found")
+
+type modelFile struct {
+	Models []modelDefinition `json:"models" yaml:"models"`
+}
+
+type modelDefinition struct {
+	Name             string  `json:"name" yaml:"name"`
+	Provider         string  `json:"provider" yaml:"provider"`
+	ContextWindow    int     `json:"context_window" yaml:"context_window"`
+	MaxOutputTokens  int     `json:"max_output_tokens" yaml:"max_output_tokens"`
+	Encoding         string  `json:"encoding" yaml:"encoding"`
+	InputPricePer1K  float64 `json:"input_price_per_1k" yaml:"input_price_per_1k"`
+	OutputPricePer1K float64 `json:"output_price_per_1k" yaml:"output_price_per_1k"`
+	Vision           bool    `json:"vision" yaml:"vision"`
+}
+
+// ModelRepositoryFile is the model registry read from a YAML or JSON file.
+type ModelRepositoryFile struct {
+	mu     sync.RWMutex
+	models map[string]*entity.Model
+}
+
+func NewModelRepositoryFile(path string) (*ModelRepositoryFile, error) {
+	models, err := readModels(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ModelRepositoryFile{models: models}, nil
+}
+
+// Reload reads the registry from path again. The models in use are kept when
+// the file is invalid.
+func (r *ModelRepositoryFile) Reload(path string) error {
+	models, err := readModels(path)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.models = models
+	return nil
+}
+
+func readModels(path string) (map[string]*entity.Model, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file modelFile
+	switch filepath.Ext(path) {
+	case ".json":
+		err = json.Unmarshal(content, &file)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &file)
+	default:
+		return nil, fmt.Errorf("unsupported model registry format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	models := make(map[string]*entity.Model, len(file.Models))
+	for i, definition := range file.Models {
+		if definition.Name == "" {
+			return nil, fmt.Errorf("model %d: name is empty", i)
+		}
+		if definition.ContextWindow <= 0 {
+			return nil, fmt.Errorf("model %s: context window must be positive", definition.Name)
+		}
+		if definition.MaxOutputTokens > definition.ContextWindow {
+			return nil, fmt.Errorf("model %s: max output tokens exceed the context window", definition.Name)
+		}
+		if _, ok := models[definition.Name]; ok {
+			return nil, fmt.Errorf("model %s: declared twice", definition.Name)
+		}
+
+		models[definition.Name] = &entity.Model{
+			Name:             definition.Name,
+			Provider:         definition.Provider,
+			MaxTokens:        definition.ContextWindow,
+			MaxOutputTokens:  definition.MaxOutputTokens,
+			Encoding:         definition.Encoding,
+			InputPricePer1K:  definition.InputPricePer1K,
+			OutputPricePer1K: definition.OutputPricePer1K,
+			Vision:           definition.Vision,
+		}
+	}
+	return models, nil
+}
+
+func (r *ModelRepositoryFile) FindByName(ctx context.Context, name string) (*entity.Model, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	model, ok := r.models[name]
+	if !ok {
+		return nil, ErrModelNotFound
+	}
+	// callers get their own copy, the registry stays read only
+	found := *model
+	return &found, nil
+}
+
+func (r *ModelRepositoryFile) List(ctx context.Context) ([]*entity.Model, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	models := make([]*entity.Model, 0, len(r.models))
+	for _, model := range r.models {
+		found := *model
+		models = append(models, &found)
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].Name < models[j].Name
+	})
+	return models, nil
+}
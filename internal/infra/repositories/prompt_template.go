@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/infra/db"
+)
+
+type PromptTemplateRepositoryMySQL struct {
+	DB      *sql.DB
+	Queries *db.Queries
+}
+
+func NewPromptTemplateRepositoryMySQL(dbt *sql.DB) *PromptTemplateRepositoryMySQL {
+	return &PromptTemplateRepositoryMySQL{
+		DB:      dbt,
+		Queries: db.New(dbt),
+	}
+}
+
+func (r *PromptTemplateRepositoryMySQL) Create(ctx context.Context, template *entity.PromptTemplate) error {
+	return r.Queries.CreatePromptTemplate(ctx, db.CreatePromptTemplateParams{
+		ID:          template.ID,
+		Name:        template.Name,
+		Version:     int32(template.Version),
+		Description: template.Description,
+		Content:     template.Content,
+		CreatedAt:   template.CreatedAt,
+	})
+}
+
+func (r *PromptTemplateRepositoryMySQL) FindByName(ctx context.Context, name string) (*entity.PromptTemplate, error) {
+	templateResult, err := r.Queries.FindLatestPromptTemplate(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("prompt template not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toPromptTemplate(templateResult), nil
+}
+
+func (r *PromptTemplateRepositoryMySQL) FindByNameAndVersion(ctx context.Context, name string, version int) (*entity.PromptTemplate, error) {
+	templateResult, err := r.Queries.FindPromptTemplateByVersion(ctx, db.FindPromptTemplateByVersionParams{
+		Name:    name,
+		Version: int32(version),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("prompt template not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toPromptTemplate(templateResult), nil
+}
+
+func (r *PromptTemplateRepositoryMySQL) FindAll(ctx context.Context) ([]*entity.PromptTemplate, error) {
+	templatesResult, err := r.Queries.ListLatestPromptTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]*entity.PromptTemplate, 0, len(templatesResult))
+	for _, templateResult := range templatesResult {
+		templates = append(templates, toPromptTemplate(templateResult))
+	}
+	return templates, nil
+}
+
+func (r *PromptTemplateRepositoryMySQL) DeleteByName(ctx context.Context, name string) (int, error) {
+	deleted, err := r.Queries.DeletePromptTemplates(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	if deleted == 0 {
+		return 0, errors.New("prompt template not found")
+	}
+	return int(deleted), nil
+}
+
+func toPromptTemplate(templateResult db.PromptTemplate) *entity.PromptTemplate {
+	return &entity.PromptTemplate{
+		ID:          templateResult.ID,
+		Name:        templateResult.Name,
+		Version:     int(templateResult.Version),
+		Description: templateResult.Description,
+		Content:     templateResult.Content,
+		CreatedAt:   templateResult.CreatedAt,
+	}
+}
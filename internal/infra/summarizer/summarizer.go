@@ -0,0 +1,98 @@
+package summarizer
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/sashabaranov/go-openai"
+)
+
+const summarizePrompt = "You keep a running summary of a conversation between a user and an assistant. " +
+	"Rewrite the summary so it also covers the new messages. Keep names, facts, decisions and open questions. " +
+	"Answer only with the summary."
+
+const titlePrompt = "Write a short title, at most six words, for the conversation below. " +
+	"Answer only with the title, without quotes or a trailing period."
+
+// titleMaxTokens bounds the title completion, titles are a few words long.
+const titleMaxTokens = 20
+
+type SummarizerOpenAI struct {
+	OpenAIClient *openai.Client
+}
+
+func NewSummarizerOpenAI(openAIClient *openai.Client) *SummarizerOpenAI {
+	return &SummarizerOpenAI{
+		OpenAIClient: openAIClient,
+	}
+}
+
+func (s *SummarizerOpenAI) Summarize(ctx context.Context, model *entity.Model, summary string, messages []*entity.Message) (string, error) {
+	var conversation strings.Builder
+	if summary != "" {
+		conversation.WriteString("Current summary:\n" + summary + "\n\n")
+	}
+	conversation.WriteString("New messages:\n")
+	for _, message := range messages {
+		writeMessage(&conversation, message)
+	}
+
+	resp, err := s.OpenAIClient.CreateChatCompletion(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model: model.GetName(),
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: summarizePrompt},
+				{Role: openai.ChatMessageRoleUser, Content: conversation.String()},
+			},
+			MaxTokens: entity.SummaryMaxTokens,
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", errors.New("summary has no choices")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
+
+func (s *SummarizerOpenAI) Title(ctx context.Context, model *entity.Model, messages []*entity.Message) (string, error) {
+	var conversation strings.Builder
+	for _, message := range messages {
+		writeMessage(&conversation, message)
+	}
+
+	resp, err := s.OpenAIClient.CreateChatCompletion(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model: model.GetName(),
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: titlePrompt},
+				{Role: openai.ChatMessageRoleUser, Content: conversation.String()},
+			},
+			MaxTokens: titleMaxTokens,
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", errors.New("title has no choices")
+	}
+	title := strings.TrimSpace(resp.Choices[0].Message.Content)
+	return strings.TrimSuffix(strings.Trim(title, "\"'"), "."), nil
+}
+
+// writeMessage writes one line per message, tool calls are written as the
+// call itself since their content is often empty.
+func writeMessage(conversation *strings.Builder, message *entity.Message) {
+	if message.Content != "" {
+		conversation.WriteString(message.Role + ": " + message.Content + "\n")
+	}
+	for _, call := range message.ToolCalls {
+		conversation.WriteString(message.Role + " called " + call.Name + "(" + call.Arguments + ")\n")
+	}
+}
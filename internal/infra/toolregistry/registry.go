@@ -0,0 +1,65 @@
+package toolregistry
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+)
+
+var ErrToolNotFound = errors.New("tool not found")
+
+// ToolRegistryInMemory keeps the tools registered in Go when the service
+// starts, indexed by name.
+type ToolRegistryInMemory struct {
+	mu    sync.RWMutex
+	tools map[string]*entity.Tool
+}
+
+func NewToolRegistryInMemory() *ToolRegistryInMemory {
+	return &ToolRegistryInMemory{
+		tools: make(map[string]*entity.Tool),
+	}
+}
+
+func (r *ToolRegistryInMemory) Register(tool *entity.Tool) error {
+	if err := tool.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tools[tool.Name]; ok {
+		return errors.New("tool " + tool.Name + " already registered")
+	}
+	r.tools[tool.Name] = tool
+	return nil
+}
+
+func (r *ToolRegistryInMemory) FindByName(ctx context.Context, name string) (*entity.Tool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tool, ok := r.tools[name]
+	if !ok {
+		return nil, ErrToolNotFound
+	}
+	return tool, nil
+}
+
+func (r *ToolRegistryInMemory) List(ctx context.Context) ([]*entity.Tool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tools := make([]*entity.Tool, 0, len(r.tools))
+	for _, tool := range r.tools {
+		tools = append(tools, tool)
+	}
+	sort.Slice(tools, func(i, j int) bool {
+		return tools[i].Name < tools[j].Name
+	})
+	return tools, nil
+}
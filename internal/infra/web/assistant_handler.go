@@ -0,0 +1,173 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gabrielmq/chat-service/internal/usecase/deleteassistant"
+	"github.com/gabrielmq/chat-service/internal/usecase/getassistant"
+	"github.com/gabrielmq/chat-service/internal/usecase/listassistants"
+	"github.com/gabrielmq/chat-service/internal/usecase/saveassistant"
+	"github.com/go-chi/chi"
+)
+
+// maxAssistantBody bounds the settings of an assistant, most of it taken by
+// the system message and the response schema.
+const maxAssistantBody = 256 * 1024
+
+// WebAssistantHandler serves the admin API of the assistants, authorized by
+// the admin token.
+type WebAssistantHandler struct {
+	SaveAssistantUseCase   saveassistant.SaveAssistantUseCase
+	GetAssistantUseCase    getassistant.GetAssistantUseCase
+	ListAssistantsUseCase  listassistants.ListAssistantsUseCase
+	DeleteAssistantUseCase deleteassistant.DeleteAssistantUseCase
+	AdminAuthToken         string
+}
+
+func NewWebAssistantHandler(saveAssistantUseCase saveassistant.SaveAssistantUseCase, getAssistantUseCase getassistant.GetAssistantUseCase, listAssistantsUseCase listassistants.ListAssistantsUseCase, deleteAssistantUseCase deleteassistant.DeleteAssistantUseCase, adminAuthToken string) *WebAssistantHandler {
+	return &WebAssistantHandler{
+		SaveAssistantUseCase:   saveAssistantUseCase,
+		GetAssistantUseCase:    getAssistantUseCase,
+		ListAssistantsUseCase:  listAssistantsUseCase,
+		DeleteAssistantUseCase: deleteAssistantUseCase,
+		AdminAuthToken:         adminAuthToken,
+	}
+}
+
+// HandleCollection serves GET /admin/assistants, listing every assistant,
+// and POST /admin/assistants, creating one.
+func (h *WebAssistantHandler) HandleCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Authorization") != h.AdminAuthToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		h.list(w, r)
+		return
+	}
+	h.save(w, r, "")
+}
+
+// HandleItem serves GET, PUT and DELETE /admin/assistants/{id}. PUT replaces
+// every setting of the assistant.
+func (h *WebAssistantHandler) HandleItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Authorization") != h.AdminAuthToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.get(w, r)
+	case http.MethodPut:
+		h.save(w, r, chi.URLParam(r, "id"))
+	default:
+		h.delete(w, r)
+	}
+}
+
+func (h *WebAssistantHandler) list(w http.ResponseWriter, r *http.Request) {
+	output, err := h.ListAssistantsUseCase.Execute(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// save creates an assistant when assistantID is empty and updates it
+// otherwise.
+func (h *WebAssistantHandler) save(w http.ResponseWriter, r *http.Request, assistantID string) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxAssistantBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var input saveassistant.SaveAssistantInput
+	if err := json.Unmarshal(body, &input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	input.AssistantID = assistantID
+
+	output, err := h.SaveAssistantUseCase.Execute(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, saveassistant.ErrAssistantNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if writeClientError(w, err) {
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if assistantID == "" {
+		status = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(output)
+}
+
+func (h *WebAssistantHandler) get(w http.ResponseWriter, r *http.Request) {
+	output, err := h.GetAssistantUseCase.Execute(r.Context(), getassistant.GetAssistantInput{
+		AssistantID: chi.URLParam(r, "id"),
+	})
+	if err != nil {
+		if errors.Is(err, getassistant.ErrAssistantNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+func (h *WebAssistantHandler) delete(w http.ResponseWriter, r *http.Request) {
+	output, err := h.DeleteAssistantUseCase.Execute(r.Context(), deleteassistant.DeleteAssistantInput{
+		AssistantID: chi.URLParam(r, "id"),
+	})
+	if err != nil {
+		if errors.Is(err, deleteassistant.ErrAssistantNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
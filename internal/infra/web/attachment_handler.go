@@ -0,0 +1,130 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/usecase/downloadattachment"
+	"github.com/gabrielmq/chat-service/internal/usecase/uploadattachment"
+	"github.com/go-chi/chi"
+)
+
+// maxAttachmentBody leaves room for the multipart headers and the user_id
+// field around a file of the largest size accepted.
+const maxAttachmentBody = entity.MaxAttachmentSize + 64*1024
+
+type WebAttachmentHandler struct {
+	UploadAttachmentUseCase   uploadattachment.UploadAttachmentUseCase
+	DownloadAttachmentUseCase downloadattachment.DownloadAttachmentUseCase
+	AuthToken                 string
+}
+
+func NewWebAttachmentHandler(uploadAttachmentUseCase uploadattachment.UploadAttachmentUseCase, downloadAttachmentUseCase downloadattachment.DownloadAttachmentUseCase, authToken string) *WebAttachmentHandler {
+	return &WebAttachmentHandler{
+		UploadAttachmentUseCase:   uploadAttachmentUseCase,
+		DownloadAttachmentUseCase: downloadAttachmentUseCase,
+		AuthToken:                 authToken,
+	}
+}
+
+// HandleUpload serves POST /chats/{id}/attachments, a multipart form with
+// the user_id owning the chat and the file.
+func (h *WebAttachmentHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Authorization") != h.AuthToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentBody)
+	defer r.Body.Close()
+	if err := r.ParseMultipartForm(maxAttachmentBody); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "file is required: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	output, err := h.UploadAttachmentUseCase.Execute(r.Context(), uploadattachment.UploadAttachmentInput{
+		ChatID:  chi.URLParam(r, "id"),
+		UserID:  r.FormValue("user_id"),
+		Name:    header.Filename,
+		Content: content,
+	})
+	if err != nil {
+		if errors.Is(err, uploadattachment.ErrChatNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if writeClientError(w, err) {
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(output)
+}
+
+// HandleDownload serves GET /chats/{id}/attachments/{attachment_id}?user_id={user_id}
+// with the content type detected on upload.
+func (h *WebAttachmentHandler) HandleDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Authorization") != h.AuthToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	output, err := h.DownloadAttachmentUseCase.Execute(r.Context(), downloadattachment.DownloadAttachmentInput{
+		ChatID:       chi.URLParam(r, "id"),
+		AttachmentID: chi.URLParam(r, "attachment_id"),
+		UserID:       r.URL.Query().Get("user_id"),
+	})
+	if err != nil {
+		if errors.Is(err, downloadattachment.ErrAttachmentNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", output.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(output.Content)))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": output.Name}))
+	// browsers must not guess another type, e.g. html, from the content
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	w.Write(output.Content)
+}
@@ -0,0 +1,101 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gabrielmq/chat-service/internal/usecase/listbranches"
+	"github.com/gabrielmq/chat-service/internal/usecase/switchbranch"
+	"github.com/go-chi/chi"
+)
+
+type WebBranchHandler struct {
+	ListBranchesUseCase listbranches.ListBranchesUseCase
+	SwitchBranchUseCase switchbranch.SwitchBranchUseCase
+	AuthToken           string
+}
+
+func NewWebBranchHandler(listBranchesUseCase listbranches.ListBranchesUseCase, switchBranchUseCase switchbranch.SwitchBranchUseCase, authToken string) *WebBranchHandler {
+	return &WebBranchHandler{
+		ListBranchesUseCase: listBranchesUseCase,
+		SwitchBranchUseCase: switchBranchUseCase,
+		AuthToken:           authToken,
+	}
+}
+
+// HandleList serves GET /chats/{id}/branches?user_id={user_id}.
+func (h *WebBranchHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Authorization") != h.AuthToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	output, err := h.ListBranchesUseCase.Execute(r.Context(), listbranches.ListBranchesInput{
+		ChatID: chi.URLParam(r, "id"),
+		UserID: r.URL.Query().Get("user_id"),
+	})
+	if err != nil {
+		if errors.Is(err, listbranches.ErrChatNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// HandleSwitch serves POST /chats/{id}/branches/switch, making the branch
+// that ends at message_id the active one.
+func (h *WebBranchHandler) HandleSwitch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Authorization") != h.AuthToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var input switchbranch.SwitchBranchInput
+	if err := json.Unmarshal(body, &input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	input.ChatID = chi.URLParam(r, "id")
+
+	output, err := h.SwitchBranchUseCase.Execute(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, switchbranch.ErrChatNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if writeClientError(w, err) {
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
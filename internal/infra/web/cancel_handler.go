@@ -0,0 +1,61 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gabrielmq/chat-service/internal/usecase/cancelcompletion"
+)
+
+type WebCancelHandler struct {
+	CancelUseCase cancelcompletion.CancelCompletionUseCase
+	AuthToken     string
+}
+
+func NewWebCancelHandler(usecase cancelcompletion.CancelCompletionUseCase, authToken string) *WebCancelHandler {
+	return &WebCancelHandler{
+		CancelUseCase: usecase,
+		AuthToken:     authToken,
+	}
+}
+
+func (h *WebCancelHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Authorization") != h.AuthToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var input cancelcompletion.CancelCompletionInput
+	if err := json.Unmarshal(body, &input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.CancelUseCase.Execute(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, cancelcompletion.ErrGenerationNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
@@ -2,19 +2,22 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
 	"github.com/gabrielmq/chat-service/internal/usecase/chatcompletion"
+	"github.com/gabrielmq/chat-service/internal/usecase/chatconfig"
 )
 
 type WebChatGPTHandler struct {
 	CompletionUseCase chatcompletion.ChatCompletionUseCase
-	Configuration     chatcompletion.ChatCompletionConfigurationInput
+	Configuration     *chatconfig.ConfigurationStore
 	AuthToken         string
 }
 
-func NewWebChatGPTHandler(usecase chatcompletion.ChatCompletionUseCase, cfg chatcompletion.ChatCompletionConfigurationInput, authToken string) *WebChatGPTHandler {
+func NewWebChatGPTHandler(usecase chatcompletion.ChatCompletionUseCase, cfg *chatconfig.ConfigurationStore, authToken string) *WebChatGPTHandler {
 	return &WebChatGPTHandler{
 		CompletionUseCase: usecase,
 		Configuration:     cfg,
@@ -51,10 +54,22 @@ func (h *WebChatGPTHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	input.Configuration = h.Configuration
+	input.Configuration = h.Configuration.Load()
 
 	output, err := h.CompletionUseCase.Execute(r.Context(), input)
 	if err != nil {
+		if errors.Is(err, chatcompletion.ErrChatNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if writeClientError(w, err) {
+			return
+		}
+		var outputErr *entity.StructuredOutputError
+		if errors.As(err, &outputErr) {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -63,3 +78,55 @@ func (h *WebChatGPTHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(output)
 }
+
+// HandleRegenerate answers the last user message of an existing chat again,
+// keeping the previous reply as an alternative.
+func (h *WebChatGPTHandler) HandleRegenerate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Authorization") != h.AuthToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var input chatcompletion.ChatCompletionInput
+	if err := json.Unmarshal(body, &input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	input.Regenerate = true
+	input.Configuration = h.Configuration.Load()
+
+	output, err := h.CompletionUseCase.Execute(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, chatcompletion.ErrChatNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if writeClientError(w, err) {
+			return
+		}
+		var outputErr *entity.StructuredOutputError
+		if errors.As(err, &outputErr) {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
@@ -0,0 +1,68 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gabrielmq/chat-service/internal/usecase/commitchoice"
+	"github.com/go-chi/chi"
+)
+
+type WebChoiceHandler struct {
+	CommitChoiceUseCase commitchoice.CommitChoiceUseCase
+	AuthToken           string
+}
+
+func NewWebChoiceHandler(commitChoiceUseCase commitchoice.CommitChoiceUseCase, authToken string) *WebChoiceHandler {
+	return &WebChoiceHandler{
+		CommitChoiceUseCase: commitChoiceUseCase,
+		AuthToken:           authToken,
+	}
+}
+
+// HandleCommit serves POST /chats/{id}/choices/commit, keeping the candidate
+// message_id as the last reply of the chat.
+func (h *WebChoiceHandler) HandleCommit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Authorization") != h.AuthToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var input commitchoice.CommitChoiceInput
+	if err := json.Unmarshal(body, &input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	input.ChatID = chi.URLParam(r, "id")
+
+	output, err := h.CommitChoiceUseCase.Execute(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, commitchoice.ErrChatNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if writeClientError(w, err) {
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
@@ -0,0 +1,108 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/usecase/attachdocument"
+	"github.com/gabrielmq/chat-service/internal/usecase/listdocuments"
+	"github.com/go-chi/chi"
+)
+
+// maxDocumentBody leaves room for the JSON escaping of a document of the
+// largest size accepted.
+const maxDocumentBody = 2 * entity.MaxDocumentSize
+
+type WebDocumentHandler struct {
+	AttachDocumentUseCase attachdocument.AttachDocumentUseCase
+	ListDocumentsUseCase  listdocuments.ListDocumentsUseCase
+	AuthToken             string
+}
+
+func NewWebDocumentHandler(attachDocumentUseCase attachdocument.AttachDocumentUseCase, listDocumentsUseCase listdocuments.ListDocumentsUseCase, authToken string) *WebDocumentHandler {
+	return &WebDocumentHandler{
+		AttachDocumentUseCase: attachDocumentUseCase,
+		ListDocumentsUseCase:  listDocumentsUseCase,
+		AuthToken:             authToken,
+	}
+}
+
+// Handle serves GET /chats/{id}/documents?user_id={user_id}, listing the
+// documents of the chat, and POST /chats/{id}/documents, attaching one.
+func (h *WebDocumentHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Authorization") != h.AuthToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		h.list(w, r)
+		return
+	}
+	h.attach(w, r)
+}
+
+func (h *WebDocumentHandler) list(w http.ResponseWriter, r *http.Request) {
+	output, err := h.ListDocumentsUseCase.Execute(r.Context(), listdocuments.ListDocumentsInput{
+		ChatID: chi.URLParam(r, "id"),
+		UserID: r.URL.Query().Get("user_id"),
+	})
+	if err != nil {
+		if errors.Is(err, listdocuments.ErrChatNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+func (h *WebDocumentHandler) attach(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxDocumentBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var input attachdocument.AttachDocumentInput
+	if err := json.Unmarshal(body, &input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	input.ChatID = chi.URLParam(r, "id")
+
+	output, err := h.AttachDocumentUseCase.Execute(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, attachdocument.ErrChatNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if writeClientError(w, err) {
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(output)
+}
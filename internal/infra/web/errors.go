@@ -0,0 +1,56 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+type validationErrorResponse struct {
+	Error  string              `json:"error"`
+	Fields []entity.FieldError `json:"fields"`
+}
+
+// writeClientError answers with 400 when err was caused by the request:
+// invalid fields are listed one by one, messages that do not fit in the
+// context window report their size and chats without a user message cannot
+// be regenerated. Unknown messages of the conversation tree and candidates
+// that are not a reply to the last user message are 404, request ids of
+// running generations are 409. It reports whether the response was written.
+func writeClientError(w http.ResponseWriter, err error) bool {
+	var verr *entity.ValidationError
+	if errors.As(err, &verr) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(validationErrorResponse{
+			Error:  verr.Error(),
+			Fields: verr.Fields,
+		})
+		return true
+	}
+
+	var tooLarge *entity.MessageTooLargeError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, tooLarge.Error(), http.StatusBadRequest)
+		return true
+	}
+
+	if errors.Is(err, entity.ErrNothingToRegenerate) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return true
+	}
+
+	if errors.Is(err, entity.ErrMessageNotFound) || errors.Is(err, entity.ErrChoiceNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return true
+	}
+
+	if errors.Is(err, gateway.ErrRequestIDInUse) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gabrielmq/chat-service/internal/usecase/forkchat"
+	"github.com/go-chi/chi"
+)
+
+type WebForkHandler struct {
+	ForkChatUseCase forkchat.ForkChatUseCase
+	AuthToken       string
+}
+
+func NewWebForkHandler(usecase forkchat.ForkChatUseCase, authToken string) *WebForkHandler {
+	return &WebForkHandler{
+		ForkChatUseCase: usecase,
+		AuthToken:       authToken,
+	}
+}
+
+// Handle serves POST /chats/{id}/fork?at={message_id}, the body carries the
+// user_id owning the chat and the owner_id of the new chat.
+func (h *WebForkHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Authorization") != h.AuthToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var input forkchat.ForkChatInput
+	if err := json.Unmarshal(body, &input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	input.ChatID = chi.URLParam(r, "id")
+	input.MessageID = r.URL.Query().Get("at")
+
+	output, err := h.ForkChatUseCase.Execute(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, forkchat.ErrChatNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if writeClientError(w, err) {
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(output)
+}
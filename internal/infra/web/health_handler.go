@@ -0,0 +1,54 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gabrielmq/chat-service/internal/infra/health"
+)
+
+// WebHealthHandler serves the probes of the orchestrator, they need no
+// authorization.
+type WebHealthHandler struct {
+	Checker *health.Checker
+}
+
+func NewWebHealthHandler(checker *health.Checker) *WebHealthHandler {
+	return &WebHealthHandler{
+		Checker: checker,
+	}
+}
+
+// HandleLiveness serves GET /healthz. It answers while the process serves
+// requests and checks no dependency, an outage of the database must not get
+// the process restarted.
+func (h *WebHealthHandler) HandleLiveness(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": health.StatusOK})
+}
+
+// HandleReadiness serves GET /readyz with the result of every check. A
+// degraded service still answers 200, it serves the requests that do not need
+// the failing dependency. It answers 503 once the server shuts down.
+func (h *WebHealthHandler) HandleReadiness(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := h.Checker.Check(r.Context())
+	status := http.StatusOK
+	if report.Status == health.StatusUnavailable {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gabrielmq/chat-service/internal/usecase/chathistory"
+	"github.com/gabrielmq/chat-service/internal/usecase/listchats"
+	"github.com/go-chi/chi"
+)
+
+type WebChatHistoryHandler struct {
+	ChatHistoryUseCase chathistory.ChatHistoryUseCase
+	ListChatsUseCase   listchats.ListChatsUseCase
+	AuthToken          string
+}
+
+func NewWebChatHistoryHandler(chatHistoryUseCase chathistory.ChatHistoryUseCase, listChatsUseCase listchats.ListChatsUseCase, authToken string) *WebChatHistoryHandler {
+	return &WebChatHistoryHandler{
+		ChatHistoryUseCase: chatHistoryUseCase,
+		ListChatsUseCase:   listChatsUseCase,
+		AuthToken:          authToken,
+	}
+}
+
+// HandleList serves GET /chats?user_id={user_id}.
+func (h *WebChatHistoryHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Authorization") != h.AuthToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	output, err := h.ListChatsUseCase.Execute(r.Context(), listchats.ListChatsInput{
+		UserID: r.URL.Query().Get("user_id"),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// HandleGet serves GET /chats/{id}?user_id={user_id}.
+func (h *WebChatHistoryHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Authorization") != h.AuthToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	output, err := h.ChatHistoryUseCase.Execute(r.Context(), chathistory.ChatHistoryInput{
+		ChatID: chi.URLParam(r, "id"),
+		UserID: r.URL.Query().Get("user_id"),
+	})
+	if err != nil {
+		if errors.Is(err, chathistory.ErrChatNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
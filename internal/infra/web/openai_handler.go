@@ -0,0 +1,680 @@
+package web
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+	"github.com/gabrielmq/chat-service/internal/usecase/chatcompletion"
+	"github.com/gabrielmq/chat-service/internal/usecase/chatcompletionstream"
+	"github.com/gabrielmq/chat-service/internal/usecase/chatconfig"
+	"github.com/gabrielmq/chat-service/internal/usecase/embeddings"
+	"github.com/gabrielmq/chat-service/internal/usecase/listchats"
+	"github.com/sashabaranov/go-openai"
+)
+
+// ChatIDHeader is the extension used by OpenAI clients to continue one of
+// the conversations stored by the service. It is echoed back on every
+// response so the client can pick up the id of a newly created chat.
+const ChatIDHeader = "X-Chat-ID"
+
+var errConversationNotFound = errors.New("conversation not found")
+
+// WebOpenAIHandler exposes the chat use cases with the same schema as the
+// OpenAI REST API, so existing SDKs can be pointed at the service.
+type WebOpenAIHandler struct {
+	CompletionUseCase       chatcompletion.ChatCompletionUseCase
+	CompletionStreamUseCase chatcompletionstream.ChatCompletionUseCase
+	ListChatsUseCase        listchats.ListChatsUseCase
+	Configuration           *chatconfig.ConfigurationStore
+	EmbeddingsUseCase       embeddings.EmbeddingsUseCase
+	EmbeddingsConfiguration *embeddings.ConfigurationStore
+	AuthToken               string
+}
+
+func NewWebOpenAIHandler(
+	usecase chatcompletion.ChatCompletionUseCase,
+	usecaseStream chatcompletionstream.ChatCompletionUseCase,
+	usecaseListChats listchats.ListChatsUseCase,
+	cfg *chatconfig.ConfigurationStore,
+	usecaseEmbeddings embeddings.EmbeddingsUseCase,
+	cfgEmbeddings *embeddings.ConfigurationStore,
+	authToken string,
+) *WebOpenAIHandler {
+	return &WebOpenAIHandler{
+		CompletionUseCase:       usecase,
+		CompletionStreamUseCase: usecaseStream,
+		ListChatsUseCase:        usecaseListChats,
+		Configuration:           cfg,
+		EmbeddingsUseCase:       usecaseEmbeddings,
+		EmbeddingsConfiguration: cfgEmbeddings,
+		AuthToken:               authToken,
+	}
+}
+
+func (h *WebOpenAIHandler) HandleModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeOpenAIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+
+	if !h.authorized(r) {
+		writeOpenAIError(w, http.StatusUnauthorized, "invalid_request_error", "invalid authorization token")
+		return
+	}
+
+	var models openai.ModelsList
+	seen := map[string]bool{}
+	chatConfig := h.Configuration.Load()
+	embeddingsConfig := h.EmbeddingsConfiguration.Load()
+	names := append([]string{chatConfig.Model}, chatConfig.AllowedModels...)
+	names = append(names, embeddingsConfig.Model)
+	names = append(names, embeddingsConfig.AllowedModels...)
+	for _, model := range names {
+		if seen[model] {
+			continue
+		}
+		seen[model] = true
+		models.Models = append(models.Models, openai.Model{
+			ID:      model,
+			Object:  "model",
+			OwnedBy: "chat-service",
+			Root:    model,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Object string `json:"object"`
+		openai.ModelsList
+	}{
+		Object:     "list",
+		ModelsList: models,
+	})
+}
+
+func (h *WebOpenAIHandler) HandleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeOpenAIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+
+	if !h.authorized(r) {
+		writeOpenAIError(w, http.StatusUnauthorized, "invalid_request_error", "invalid authorization token")
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeOpenAIError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	var wrapped chatCompletionRequest
+	if err := json.Unmarshal(body, &wrapped); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	req := wrapped.ChatCompletionRequest
+	schema, err := wrapped.ResponseFormat.schema()
+	if err != nil {
+		param := "response_format"
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(openai.ErrorResponse{
+			Error: &openai.APIError{
+				Type:    "invalid_request_error",
+				Message: err.Error(),
+				Param:   &param,
+			},
+		})
+		return
+	}
+
+	if req.User == "" {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "user is required")
+		return
+	}
+
+	// the service keeps the history itself, only the last user message is new
+	last := -1
+	systemMessage := ""
+	for i, message := range req.Messages {
+		switch message.Role {
+		case openai.ChatMessageRoleUser:
+			last = i
+		case openai.ChatMessageRoleSystem:
+			if systemMessage == "" {
+				systemMessage = message.Content
+			}
+		}
+	}
+	if last < 0 || (req.Messages[last].Content == "" && len(req.Messages[last].MultiContent) == 0) {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "messages must contain a user message")
+		return
+	}
+	userMessage, userContent := req.Messages[last].Content, toContentPartsInput(req.Messages[last].MultiContent)
+
+	chatID, err := h.conversation(r, req)
+	if err != nil {
+		writeOpenAIUseCaseError(w, err)
+		return
+	}
+	// a conversation held by the client starts a new chat with its turns
+	var history []chatcompletion.ChatCompletionHistoryInput
+	if chatID == "" {
+		history = toHistoryInput(req.Messages[:last])
+	}
+
+	if req.Stream {
+		h.streamChatCompletion(w, r, req, chatID, userMessage, userContent, history, systemMessage, schema)
+		return
+	}
+
+	overrides := toOverridesInput(req, systemMessage)
+	input := chatcompletion.ChatCompletionInput{
+		ChatID:         chatID,
+		UserID:         req.User,
+		UserMessage:    userMessage,
+		UserContent:    userContent,
+		History:        history,
+		ResponseSchema: schema,
+		Overrides:      &overrides,
+		Configuration:  h.Configuration.Load(),
+	}
+
+	output, err := h.CompletionUseCase.Execute(r.Context(), input)
+	if err != nil {
+		writeOpenAIUseCaseError(w, err)
+		return
+	}
+
+	choices := make([]openai.ChatCompletionChoice, 0, len(output.Choices))
+	for _, choice := range output.Choices {
+		choices = append(choices, openai.ChatCompletionChoice{
+			Index: choice.Index,
+			Message: openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleAssistant,
+				Content: choice.Content,
+			},
+			FinishReason: openai.FinishReason(choice.FinishReason),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(ChatIDHeader, output.ChatID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+		ID:      "chatcmpl-" + output.RequestID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   output.Model,
+		Choices: choices,
+		Usage: openai.Usage{
+			PromptTokens:     output.Usage.PromptTokens,
+			CompletionTokens: output.Usage.CompletionTokens,
+			TotalTokens:      output.Usage.TotalTokens,
+		},
+	})
+}
+
+// conversation returns the chat in the X-Chat-ID header, which must belong
+// to the user. Without it the request starts a new chat and gets an empty id.
+func (h *WebOpenAIHandler) conversation(r *http.Request, req openai.ChatCompletionRequest) (string, error) {
+	chatID := r.Header.Get(ChatIDHeader)
+	if chatID == "" {
+		return "", nil
+	}
+
+	output, err := h.ListChatsUseCase.Execute(r.Context(), listchats.ListChatsInput{UserID: req.User})
+	if err != nil {
+		return "", err
+	}
+	for _, chat := range output.Chats {
+		if chat.ChatID == chatID {
+			return chat.ChatID, nil
+		}
+	}
+	return "", errConversationNotFound
+}
+
+// embeddingRequest is openai.EmbeddingRequest with the input kept raw, as it
+// is either a string or an array of strings.
+type embeddingRequest struct {
+	Input          json.RawMessage `json:"input"`
+	Model          string          `json:"model"`
+	User           string          `json:"user"`
+	EncodingFormat string          `json:"encoding_format,omitempty"`
+}
+
+func (r embeddingRequest) inputs() ([]string, error) {
+	var input string
+	if err := json.Unmarshal(r.Input, &input); err == nil {
+		return []string{input}, nil
+	}
+	var inputs []string
+	if err := json.Unmarshal(r.Input, &inputs); err != nil {
+		return nil, errors.New("input must be a string or an array of strings")
+	}
+	return inputs, nil
+}
+
+// base64Embedding is the embedding sent when encoding_format is base64, the
+// little endian float32 values encoded in base64.
+type base64Embedding struct {
+	Object    string `json:"object"`
+	Embedding string `json:"embedding"`
+	Index     int    `json:"index"`
+}
+
+func (h *WebOpenAIHandler) HandleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeOpenAIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+
+	if !h.authorized(r) {
+		writeOpenAIError(w, http.StatusUnauthorized, "invalid_request_error", "invalid authorization token")
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeOpenAIError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	var req embeddingRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	inputs, err := req.inputs()
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	if req.EncodingFormat != "" && req.EncodingFormat != string(openai.EmbeddingEncodingFormatFloat) && req.EncodingFormat != string(openai.EmbeddingEncodingFormatBase64) {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("unsupported encoding_format %q", req.EncodingFormat))
+		return
+	}
+
+	output, err := h.EmbeddingsUseCase.Execute(r.Context(), embeddings.EmbeddingsInput{
+		Model:  req.Model,
+		Inputs: inputs,
+		Config: h.EmbeddingsConfiguration.Load(),
+	})
+	if err != nil {
+		writeOpenAIUseCaseError(w, err)
+		return
+	}
+
+	usage := openai.Usage{PromptTokens: output.PromptTokens, TotalTokens: output.PromptTokens}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if req.EncodingFormat == string(openai.EmbeddingEncodingFormatBase64) {
+		data := make([]base64Embedding, 0, len(output.Data))
+		for _, item := range output.Data {
+			data = append(data, base64Embedding{
+				Object:    "embedding",
+				Embedding: encodeBase64Embedding(item.Embedding),
+				Index:     item.Index,
+			})
+		}
+		json.NewEncoder(w).Encode(struct {
+			Object string            `json:"object"`
+			Data   []base64Embedding `json:"data"`
+			Model  string            `json:"model"`
+			Usage  openai.Usage      `json:"usage"`
+		}{"list", data, output.Model, usage})
+		return
+	}
+
+	data := make([]openai.Embedding, 0, len(output.Data))
+	for _, item := range output.Data {
+		data = append(data, openai.Embedding{
+			Object:    "embedding",
+			Embedding: item.Embedding,
+			Index:     item.Index,
+		})
+	}
+	json.NewEncoder(w).Encode(openai.EmbeddingResponse{
+		Object: "list",
+		Data:   data,
+		Model:  openai.EmbeddingModel(output.Model),
+		Usage:  usage,
+	})
+}
+
+func encodeBase64Embedding(embedding []float32) string {
+	encoded := make([]byte, 4*len(embedding))
+	for i, value := range embedding {
+		binary.LittleEndian.PutUint32(encoded[4*i:], math.Float32bits(value))
+	}
+	return base64.StdEncoding.EncodeToString(encoded)
+}
+
+type streamResult struct {
+	output *chatcompletionstream.ChatCompletionOutput
+	err    error
+}
+
+func (h *WebOpenAIHandler) streamChatCompletion(w http.ResponseWriter, r *http.Request, req openai.ChatCompletionRequest, chatID string, userMessage string, userContent []chatcompletion.ChatCompletionContentPartInput, history []chatcompletion.ChatCompletionHistoryInput, systemMessage string, schema json.RawMessage) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeOpenAIError(w, http.StatusInternalServerError, "server_error", "streaming is not supported")
+		return
+	}
+
+	overrides := toOverridesInput(req, systemMessage)
+	input := chatcompletionstream.ChatCompletionInput{
+		ChatID:         chatID,
+		UserID:         req.User,
+		UserMessage:    userMessage,
+		UserContent:    userContent,
+		History:        history,
+		ResponseSchema: schema,
+		Overrides:      &overrides,
+		Configuration:  h.Configuration.Load(),
+	}
+
+	streamChannel := make(chan chatcompletionstream.ChatCompletionOutput)
+	result := make(chan streamResult, 1)
+	go func() {
+		output, err := h.CompletionStreamUseCase.Execute(r.Context(), input, streamChannel)
+		close(streamChannel)
+		result <- streamResult{output: output, err: err}
+	}()
+
+	created := time.Now().Unix()
+	model := ""
+	started := false
+	start := func(chatID, chatModel string) {
+		model = chatModel
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set(ChatIDHeader, chatID)
+		w.WriteHeader(http.StatusOK)
+		started = true
+	}
+
+	// the use case sends the accumulated content of each choice, OpenAI
+	// clients expect deltas. Content starts over after tool calls, the next
+	// completion is then sent whole. Provisional content is held back until
+	// the use case returns the final choices.
+	sent := map[int]string{}
+	send := func(requestID string, index int, content string) {
+		delta := strings.TrimPrefix(content, sent[index])
+		if delta == "" {
+			return
+		}
+		writeSSE(w, openai.ChatCompletionStreamResponse{
+			ID:      "chatcmpl-" + requestID,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   model,
+			Choices: []openai.ChatCompletionStreamChoice{
+				{
+					Index: index,
+					Delta: openai.ChatCompletionStreamChoiceDelta{Content: delta},
+				},
+			},
+		})
+		sent[index] = content
+		flusher.Flush()
+	}
+	for msg := range streamChannel {
+		if !started {
+			start(msg.ChatID, msg.Model)
+		}
+		if !msg.Provisional {
+			send(msg.RequestID, msg.Index, msg.Content)
+		}
+	}
+
+	res := <-result
+	if res.err != nil {
+		if !started {
+			writeOpenAIUseCaseError(w, res.err)
+			return
+		}
+		writeSSE(w, openai.ErrorResponse{Error: &openai.APIError{Type: "server_error", Message: res.err.Error()}})
+		flusher.Flush()
+		return
+	}
+
+	if !started {
+		start(res.output.ChatID, res.output.Model)
+	}
+	for _, choice := range res.output.Choices {
+		send(res.output.RequestID, choice.Index, choice.Content)
+	}
+	finished := []openai.ChatCompletionStreamChoice{{Index: 0, FinishReason: openai.FinishReason(res.output.FinishReason)}}
+	if len(res.output.Choices) > 0 {
+		finished = finished[:0]
+		for _, choice := range res.output.Choices {
+			finished = append(finished, openai.ChatCompletionStreamChoice{
+				Index:        choice.Index,
+				FinishReason: openai.FinishReason(choice.FinishReason),
+			})
+		}
+	}
+	writeSSE(w, openai.ChatCompletionStreamResponse{
+		ID:      "chatcmpl-" + res.output.RequestID,
+		Object:  "chat.completion.chunk",
+		Created: created,
+		Model:   model,
+		Choices: finished,
+	})
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	flusher.Flush()
+}
+
+// chatCompletionRequest reads response_format apart from the rest of the
+// request, go-openai cannot decode the schema of a json_schema format.
+type chatCompletionRequest struct {
+	openai.ChatCompletionRequest
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type responseFormat struct {
+	Type       string `json:"type"`
+	JSONSchema *struct {
+		Schema json.RawMessage `json:"schema"`
+	} `json:"json_schema,omitempty"`
+}
+
+// schema returns the JSON schema replies must follow for this request. JSON
+// mode only asks for an object, text keeps the schema of the chat if any.
+func (f *responseFormat) schema() (json.RawMessage, error) {
+	if f == nil {
+		return nil, nil
+	}
+	switch openai.ChatCompletionResponseFormatType(f.Type) {
+	case openai.ChatCompletionResponseFormatTypeText, "":
+		return nil, nil
+	case openai.ChatCompletionResponseFormatTypeJSONObject:
+		return json.RawMessage(`{"type":"object"}`), nil
+	case openai.ChatCompletionResponseFormatTypeJSONSchema:
+		if f.JSONSchema == nil || len(f.JSONSchema.Schema) == 0 {
+			return nil, errors.New("json_schema.schema is required")
+		}
+		return f.JSONSchema.Schema, nil
+	}
+	return nil, fmt.Errorf("unsupported response_format type %q", f.Type)
+}
+
+// toOverridesInput maps the OpenAI request settings onto the chat overrides.
+// The OpenAI schema omits zero values, so those keep the server defaults.
+func toOverridesInput(req openai.ChatCompletionRequest, systemMessage string) chatconfig.OverridesInput {
+	var overrides chatconfig.OverridesInput
+	if req.Model != "" {
+		overrides.Model = &req.Model
+	}
+	if req.Temperature != 0 {
+		overrides.Temperature = &req.Temperature
+	}
+	if req.TopP != 0 {
+		overrides.TopP = &req.TopP
+	}
+	if req.MaxTokens != 0 {
+		overrides.MaxTokens = &req.MaxTokens
+	}
+	if req.PresencePenalty != 0 {
+		overrides.PresencePenalty = &req.PresencePenalty
+	}
+	if req.FrequencyPenalty != 0 {
+		overrides.FrequencyPenalty = &req.FrequencyPenalty
+	}
+	if req.N != 0 {
+		overrides.N = &req.N
+	}
+	if systemMessage != "" {
+		overrides.SystemMessage = &systemMessage
+	}
+	overrides.Stop = req.Stop
+	return overrides
+}
+
+// toContentPartsInput maps the parts of an OpenAI user message, images sent
+// as base64 data URLs become base64 parts.
+func toContentPartsInput(parts []openai.ChatMessagePart) []chatcompletion.ChatCompletionContentPartInput {
+	var converted []chatcompletion.ChatCompletionContentPartInput
+	for _, part := range parts {
+		if part.Type != openai.ChatMessagePartTypeImageURL || part.ImageURL == nil {
+			converted = append(converted, chatcompletion.ChatCompletionContentPartInput{
+				Type: string(part.Type),
+				Text: part.Text,
+			})
+			continue
+		}
+
+		input := chatcompletion.ChatCompletionContentPartInput{
+			Type:   entity.ContentPartImageURL,
+			URL:    part.ImageURL.URL,
+			Detail: string(part.ImageURL.Detail),
+		}
+		if dataURL, ok := strings.CutPrefix(part.ImageURL.URL, "data:"); ok {
+			if mediaType, data, ok := strings.Cut(dataURL, ";base64,"); ok {
+				input.Type, input.URL, input.MediaType, input.Data = entity.ContentPartImageBase64, "", mediaType, data
+			}
+		}
+		converted = append(converted, input)
+	}
+	return converted
+}
+
+// toHistoryInput keeps the user and assistant turns with text, tool calls and
+// their results are not replayed.
+func toHistoryInput(messages []openai.ChatCompletionMessage) []chatcompletion.ChatCompletionHistoryInput {
+	var history []chatcompletion.ChatCompletionHistoryInput
+	for _, message := range messages {
+		if message.Role != openai.ChatMessageRoleUser && message.Role != openai.ChatMessageRoleAssistant {
+			continue
+		}
+		content := message.Content
+		for _, part := range message.MultiContent {
+			if part.Type == openai.ChatMessagePartTypeText {
+				content += part.Text
+			}
+		}
+		if content == "" {
+			continue
+		}
+		history = append(history, chatcompletion.ChatCompletionHistoryInput{Role: message.Role, Content: content})
+	}
+	return history
+}
+
+// authorized accepts the raw token as the other handlers do and also the
+// bearer form sent by the OpenAI SDKs.
+func (h *WebOpenAIHandler) authorized(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token == h.AuthToken
+}
+
+func writeSSE(w http.ResponseWriter, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "data: %s\n\n", payload)
+}
+
+// writeOpenAIUseCaseError maps validation failures to invalid_request_error,
+// using the first invalid field as the OpenAI param. Replies that never
+// matched the response schema are reported as a bad gateway.
+func writeOpenAIUseCaseError(w http.ResponseWriter, err error) {
+	var tooLarge *entity.MessageTooLargeError
+	if errors.As(err, &tooLarge) {
+		code, param := "context_length_exceeded", "messages"
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(openai.ErrorResponse{
+			Error: &openai.APIError{
+				Type:    "invalid_request_error",
+				Message: tooLarge.Error(),
+				Param:   &param,
+				Code:    &code,
+			},
+		})
+		return
+	}
+
+	var outputErr *entity.StructuredOutputError
+	if errors.As(err, &outputErr) {
+		writeOpenAIError(w, http.StatusBadGateway, "server_error", outputErr.Error())
+		return
+	}
+
+	if errors.Is(err, chatcompletion.ErrChatNotFound) || errors.Is(err, chatcompletionstream.ErrChatNotFound) || errors.Is(err, errConversationNotFound) {
+		writeOpenAIError(w, http.StatusNotFound, "invalid_request_error", err.Error())
+		return
+	}
+
+	if errors.Is(err, gateway.ErrRequestIDInUse) {
+		writeOpenAIError(w, http.StatusConflict, "invalid_request_error", err.Error())
+		return
+	}
+
+	var verr *entity.ValidationError
+	if !errors.As(err, &verr) {
+		writeOpenAIError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	param := verr.Fields[0].Field
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(openai.ErrorResponse{
+		Error: &openai.APIError{
+			Type:    "invalid_request_error",
+			Message: verr.Error(),
+			Param:   &param,
+		},
+	})
+}
+
+func writeOpenAIError(w http.ResponseWriter, statusCode int, errType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(openai.ErrorResponse{
+		Error: &openai.APIError{
+			Type:    errType,
+			Message: message,
+		},
+	})
+}
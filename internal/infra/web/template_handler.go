@@ -0,0 +1,179 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/usecase/deletetemplate"
+	"github.com/gabrielmq/chat-service/internal/usecase/gettemplate"
+	"github.com/gabrielmq/chat-service/internal/usecase/listtemplates"
+	"github.com/gabrielmq/chat-service/internal/usecase/savetemplate"
+	"github.com/go-chi/chi"
+)
+
+// maxTemplateBody leaves room for the JSON escaping of a template of the
+// largest size accepted and its description.
+const maxTemplateBody = 2*entity.MaxTemplateSize + 4*1024
+
+// WebTemplateHandler serves the admin API of the prompt templates, it is
+// authorized by the admin token instead of the one of the chat API.
+type WebTemplateHandler struct {
+	SaveTemplateUseCase   savetemplate.SaveTemplateUseCase
+	GetTemplateUseCase    gettemplate.GetTemplateUseCase
+	ListTemplatesUseCase  listtemplates.ListTemplatesUseCase
+	DeleteTemplateUseCase deletetemplate.DeleteTemplateUseCase
+	AdminAuthToken        string
+}
+
+func NewWebTemplateHandler(saveTemplateUseCase savetemplate.SaveTemplateUseCase, getTemplateUseCase gettemplate.GetTemplateUseCase, listTemplatesUseCase listtemplates.ListTemplatesUseCase, deleteTemplateUseCase deletetemplate.DeleteTemplateUseCase, adminAuthToken string) *WebTemplateHandler {
+	return &WebTemplateHandler{
+		SaveTemplateUseCase:   saveTemplateUseCase,
+		GetTemplateUseCase:    getTemplateUseCase,
+		ListTemplatesUseCase:  listTemplatesUseCase,
+		DeleteTemplateUseCase: deleteTemplateUseCase,
+		AdminAuthToken:        adminAuthToken,
+	}
+}
+
+// HandleCollection serves GET /admin/templates, listing the latest version
+// of every template, and POST /admin/templates, saving a new version of the
+// template named in the body.
+func (h *WebTemplateHandler) HandleCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Authorization") != h.AdminAuthToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		h.list(w, r)
+		return
+	}
+	h.save(w, r, "")
+}
+
+// HandleItem serves GET /admin/templates/{name}?version={version}, the
+// latest version when none is given, PUT /admin/templates/{name}, saving a
+// new version, and DELETE /admin/templates/{name}, removing every version.
+func (h *WebTemplateHandler) HandleItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Authorization") != h.AdminAuthToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.get(w, r)
+	case http.MethodPut:
+		h.save(w, r, chi.URLParam(r, "name"))
+	default:
+		h.delete(w, r)
+	}
+}
+
+func (h *WebTemplateHandler) list(w http.ResponseWriter, r *http.Request) {
+	output, err := h.ListTemplatesUseCase.Execute(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// save stores a new version of the template, name comes from the path on PUT
+// and from the body on POST.
+func (h *WebTemplateHandler) save(w http.ResponseWriter, r *http.Request, name string) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxTemplateBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var input savetemplate.SaveTemplateInput
+	if err := json.Unmarshal(body, &input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if name != "" {
+		input.Name = name
+	}
+
+	output, err := h.SaveTemplateUseCase.Execute(r.Context(), input)
+	if err != nil {
+		if writeClientError(w, err) {
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(output)
+}
+
+func (h *WebTemplateHandler) get(w http.ResponseWriter, r *http.Request) {
+	input := gettemplate.GetTemplateInput{Name: chi.URLParam(r, "name")}
+	if version := r.URL.Query().Get("version"); version != "" {
+		parsed, err := strconv.Atoi(version)
+		if err != nil || parsed < 1 {
+			http.Error(w, "version must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		input.Version = parsed
+	}
+
+	output, err := h.GetTemplateUseCase.Execute(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, gettemplate.ErrTemplateNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+func (h *WebTemplateHandler) delete(w http.ResponseWriter, r *http.Request) {
+	output, err := h.DeleteTemplateUseCase.Execute(r.Context(), deletetemplate.DeleteTemplateInput{
+		Name: chi.URLParam(r, "name"),
+	})
+	if err != nil {
+		if errors.Is(err, deletetemplate.ErrTemplateNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
@@ -1,6 +1,8 @@
 package webserver
 
 import (
+	"context"
+	"errors"
 	"net/http"
 
 	"github.com/go-chi/chi"
@@ -11,6 +13,7 @@ type WebServer struct {
 	Router        chi.Router
 	Handlers      map[string]http.HandlerFunc
 	WebServerPort string
+	server        *http.Server
 }
 
 func NewWebServer(port string) *WebServer {
@@ -18,6 +21,7 @@ func NewWebServer(port string) *WebServer {
 		Router:        chi.NewRouter(),
 		Handlers:      make(map[string]http.HandlerFunc),
 		WebServerPort: port,
+		server:        &http.Server{Addr: port},
 	}
 }
 
@@ -25,13 +29,28 @@ func (w *WebServer) AddHandler(path string, handler http.HandlerFunc) {
 	w.Handlers[path] = handler
 }
 
-func (w *WebServer) Start() {
+// Start serves the handlers until Shutdown is called, it returns nil once the
+// server was shut down.
+func (w *WebServer) Start() error {
 	w.Router.Use(middleware.Logger)
 	for path, handler := range w.Handlers {
 		w.Router.Handle(path, handler)
 	}
 
-	if err := http.ListenAndServe(w.WebServerPort, w.Router); err != nil {
-		panic(err.Error())
+	w.server.Handler = w.Router
+	if err := w.server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
 	}
+	return nil
+}
+
+// Shutdown stops accepting connections and waits for the running requests,
+// streams included, to finish. The connections still open when ctx is done
+// are closed, cancelling their requests.
+func (w *WebServer) Shutdown(ctx context.Context) error {
+	if err := w.server.Shutdown(ctx); err != nil {
+		w.server.Close()
+		return err
+	}
+	return nil
 }
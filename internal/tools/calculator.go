@@ -0,0 +1,214 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+)
+
+// maxExpressionLength keeps the parser recursion bounded.
+const maxExpressionLength = 256
+
+// NewCalculator evaluates arithmetic expressions with + - * / % ^ and
+// parentheses, so the assistant does not have to do the math itself.
+func NewCalculator() *entity.Tool {
+	return &entity.Tool{
+		Name:        "calculator",
+		Description: "Evaluates an arithmetic expression with + - * / % ^ and parentheses, e.g. (2 + 3) * 4 ^ 2.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"expression": {"type": "string", "description": "arithmetic expression to evaluate"}
+			},
+			"required": ["expression"]
+		}`),
+		Handler: calculate,
+	}
+}
+
+func calculate(ctx context.Context, arguments string) (string, error) {
+	var args struct {
+		Expression string `json:"expression"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", errors.New("invalid arguments: " + err.Error())
+	}
+	if len(args.Expression) > maxExpressionLength {
+		return "", fmt.Errorf("expression is longer than %d characters", maxExpressionLength)
+	}
+
+	result, err := Evaluate(args.Expression)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(result, 'g', -1, 64), nil
+}
+
+// Evaluate returns the value of an arithmetic expression. ^ binds tighter
+// than the unary minus and is right associative, so -2^2 is -4 and 2^3^2 is
+// 512.
+func Evaluate(expression string) (float64, error) {
+	p := &parser{input: []rune(expression)}
+	result, err := p.expression()
+	if err != nil {
+		return 0, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos+1)
+	}
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0, errors.New("result is not a finite number")
+	}
+	return result, nil
+}
+
+type parser struct {
+	input []rune
+	pos   int
+}
+
+// expression = term { ("+" | "-") term }
+func (p *parser) expression() (float64, error) {
+	left, err := p.term()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		switch p.peek() {
+		case '+':
+			p.pos++
+			right, err := p.term()
+			if err != nil {
+				return 0, err
+			}
+			left += right
+		case '-':
+			p.pos++
+			right, err := p.term()
+			if err != nil {
+				return 0, err
+			}
+			left -= right
+		default:
+			return left, nil
+		}
+	}
+}
+
+// term = unary { ("*" | "/" | "%") unary }
+func (p *parser) term() (float64, error) {
+	left, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		operator := p.peek()
+		if operator != '*' && operator != '/' && operator != '%' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch operator {
+		case '*':
+			left *= right
+		case '/':
+			if right == 0 {
+				return 0, errors.New("division by zero")
+			}
+			left /= right
+		case '%':
+			if right == 0 {
+				return 0, errors.New("division by zero")
+			}
+			left = math.Mod(left, right)
+		}
+	}
+}
+
+// unary = ("-" | "+") unary | power
+func (p *parser) unary() (float64, error) {
+	switch p.peek() {
+	case '-':
+		p.pos++
+		value, err := p.unary()
+		return -value, err
+	case '+':
+		p.pos++
+		return p.unary()
+	}
+	return p.power()
+}
+
+// power = primary [ "^" unary ]
+func (p *parser) power() (float64, error) {
+	base, err := p.primary()
+	if err != nil {
+		return 0, err
+	}
+	if p.peek() != '^' {
+		return base, nil
+	}
+	p.pos++
+	exponent, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	return math.Pow(base, exponent), nil
+}
+
+// primary = number | "(" expression ")"
+func (p *parser) primary() (float64, error) {
+	switch r := p.peek(); {
+	case r == '(':
+		p.pos++
+		value, err := p.expression()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, fmt.Errorf("missing ) at position %d", p.pos+1)
+		}
+		p.pos++
+		return value, nil
+	case unicode.IsDigit(r) || r == '.':
+		start := p.pos
+		for p.pos < len(p.input) && (unicode.IsDigit(p.input[p.pos]) || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		value, err := strconv.ParseFloat(string(p.input[start:p.pos]), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q", string(p.input[start:p.pos]))
+		}
+		return value, nil
+	case r == 0:
+		return 0, errors.New("unexpected end of expression")
+	default:
+		return 0, fmt.Errorf("unexpected %q at position %d", r, p.pos+1)
+	}
+}
+
+// peek skips the spaces before the next rune and returns it, or 0 at the end
+// of the input.
+func (p *parser) peek() rune {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       float64
+		wantErr    string
+	}{
+		{expression: "1 + 2 * 3", want: 7},
+		{expression: "(1 + 2) * 3", want: 9},
+		{expression: "10 - 4 - 3", want: 3},
+		{expression: "12 / 4 / 3", want: 1},
+		{expression: "7 % 4", want: 3},
+		{expression: "-2^2", want: -4},
+		{expression: "2^3^2", want: 512},
+		{expression: "2^-1", want: 0.5},
+		{expression: "--3 + +2", want: 5},
+		{expression: " 1.5 * ( 2 + .5 ) ", want: 3.75},
+		{expression: "1 / 0", wantErr: "division by zero"},
+		{expression: "1 % 0", wantErr: "division by zero"},
+		{expression: "(1 + 2", wantErr: "missing ) at position 7"},
+		{expression: "1 + 2)", wantErr: `unexpected ')' at position 6`},
+		{expression: "2 * x", wantErr: `unexpected 'x' at position 5`},
+		{expression: "1..2", wantErr: `invalid number "1..2"`},
+		{expression: "", wantErr: "unexpected end of expression"},
+		{expression: "3 -", wantErr: "unexpected end of expression"},
+		{expression: "10^400", wantErr: "result is not a finite number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			got, err := Evaluate(tt.expression)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Evaluate: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments string
+		want      string
+		wantErr   string
+	}{
+		{name: "formats the result", arguments: `{"expression": "1 / 4"}`, want: "0.25"},
+		{name: "large integers", arguments: `{"expression": "2^40"}`, want: "1.099511627776e+12"},
+		{name: "invalid arguments", arguments: `{"expression": 1}`, wantErr: "invalid arguments"},
+		{name: "expression too long", arguments: `{"expression": "` + strings.Repeat("1+", 200) + `1"}`, wantErr: "longer than 256 characters"},
+		{name: "evaluation error", arguments: `{"expression": "1 / 0"}`, wantErr: "division by zero"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewCalculator().Handler(context.Background(), tt.arguments)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("calculate: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
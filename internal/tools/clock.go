@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+	// the timezone database is embedded so containers without zoneinfo can
+	// still convert between zones
+	_ "time/tzdata"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+)
+
+// timeLayouts are the formats accepted by convert_time, the first one is also
+// used to answer.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// NewCurrentTime tells the assistant the current time in a timezone. now is
+// the clock used, time.Now outside of tests.
+func NewCurrentTime(now func() time.Time) *entity.Tool {
+	return &entity.Tool{
+		Name:        "current_time",
+		Description: "Returns the current date and time in an IANA timezone, UTC by default.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"timezone": {"type": "string", "description": "IANA timezone, e.g. America/Sao_Paulo"}
+			}
+		}`),
+		Handler: func(ctx context.Context, arguments string) (string, error) {
+			var args struct {
+				Timezone string `json:"timezone"`
+			}
+			if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+				return "", errors.New("invalid arguments: " + err.Error())
+			}
+			location, err := loadLocation(args.Timezone)
+			if err != nil {
+				return "", err
+			}
+			return formatTime(now().In(location)), nil
+		},
+	}
+}
+
+// NewTimeConverter converts a date and time from one timezone to another.
+func NewTimeConverter() *entity.Tool {
+	return &entity.Tool{
+		Name:        "convert_time",
+		Description: "Converts a date and time from one IANA timezone to another.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"time": {"type": "string", "description": "date and time, e.g. 2024-05-01 14:30 or an RFC 3339 timestamp"},
+				"from": {"type": "string", "description": "IANA timezone of the time, ignored when it has an offset, UTC by default"},
+				"to": {"type": "string", "description": "IANA timezone to convert to"}
+			},
+			"required": ["time", "to"]
+		}`),
+		Handler: convertTime,
+	}
+}
+
+func convertTime(ctx context.Context, arguments string) (string, error) {
+	var args struct {
+		Time string `json:"time"`
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", errors.New("invalid arguments: " + err.Error())
+	}
+	from, err := loadLocation(args.From)
+	if err != nil {
+		return "", err
+	}
+	to, err := loadLocation(args.To)
+	if err != nil {
+		return "", err
+	}
+
+	for _, layout := range timeLayouts {
+		parsed, err := time.ParseInLocation(layout, args.Time, from)
+		if err == nil {
+			return formatTime(parsed.In(to)), nil
+		}
+	}
+	return "", errors.New("unsupported time format, use YYYY-MM-DD HH:MM or RFC 3339")
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.New("unknown timezone " + name)
+	}
+	return location, nil
+}
+
+func formatTime(t time.Time) string {
+	return t.Format(timeLayouts[0]) + " (" + t.Weekday().String() + ", " + t.Location().String() + ")"
+}
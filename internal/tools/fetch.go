@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+)
+
+const (
+	DefaultFetchMaxBytes = 8 * 1024 // about 2k tokens of text
+	maxFetchRedirects    = 3
+)
+
+// NewFetchURL downloads text documents for the assistant from an allowlist of
+// hosts. An entry matches a host on any port, or only on the given one when
+// it has a port, e.g. example.com or localhost:8080. Bodies longer than
+// maxBytes are truncated.
+func NewFetchURL(client *http.Client, allowedHosts []string, maxBytes int64) *entity.Tool {
+	if maxBytes <= 0 {
+		maxBytes = DefaultFetchMaxBytes
+	}
+	f := &fetcher{
+		client:       client,
+		allowedHosts: allowedHosts,
+		maxBytes:     maxBytes,
+	}
+	return &entity.Tool{
+		Name:        "fetch_url",
+		Description: "Downloads a text, HTML or JSON document over HTTP. Only a few hosts are allowed and long documents are truncated.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"url": {"type": "string", "description": "http or https URL of the document"}
+			},
+			"required": ["url"]
+		}`),
+		Handler: f.fetch,
+	}
+}
+
+type fetcher struct {
+	client       *http.Client
+	allowedHosts []string
+	maxBytes     int64
+}
+
+type fetchResult struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        string `json:"body"`
+	Truncated   bool   `json:"truncated"`
+}
+
+func (f *fetcher) fetch(ctx context.Context, arguments string) (string, error) {
+	var args struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", errors.New("invalid arguments: " + err.Error())
+	}
+	target, err := url.Parse(args.URL)
+	if err != nil {
+		return "", errors.New("invalid url: " + err.Error())
+	}
+	if err := f.check(target); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	// redirects are followed only inside the allowlist
+	client := *f.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxFetchRedirects {
+			return errors.New("too many redirects")
+		}
+		return f.check(req.URL)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	contentType := res.Header.Get("Content-Type")
+	if !isText(contentType) {
+		return "", fmt.Errorf("unsupported content type %q", contentType)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, f.maxBytes+1))
+	if err != nil {
+		return "", err
+	}
+	result := fetchResult{
+		Status:      res.StatusCode,
+		ContentType: contentType,
+		Body:        string(body),
+	}
+	if int64(len(body)) > f.maxBytes {
+		result.Body = strings.ToValidUTF8(string(body[:f.maxBytes]), "")
+		result.Truncated = true
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+func (f *fetcher) check(target *url.URL) error {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return errors.New("only http and https urls can be fetched")
+	}
+	for _, allowed := range f.allowedHosts {
+		if strings.EqualFold(allowed, target.Host) || strings.EqualFold(allowed, target.Hostname()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %s is not allowed", target.Host)
+}
+
+func isText(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		mediaType == "application/json" ||
+		mediaType == "application/xml" ||
+		strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "+xml")
+}
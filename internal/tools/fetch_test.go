@@ -0,0 +1,161 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestFetchURL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/text", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("hello world"))
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"title":"not found"}`))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte{0x89, 'P', 'N', 'G'})
+	})
+	mux.HandleFunc("/utf8", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("aé")) // the second rune takes two bytes
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/text", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	// localhost resolves to the same server but is not on the allowlist
+	serverURL, _ := url.Parse(server.URL)
+	outside := "http://localhost:" + serverURL.Port() + "/text"
+	mux.HandleFunc("/outside", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, outside, http.StatusFound)
+	})
+
+	tests := []struct {
+		name         string
+		allowedHosts []string
+		maxBytes     int64
+		url          string
+		want         fetchResult
+		wantErr      string
+	}{
+		{
+			name:         "allowed host on any port",
+			allowedHosts: []string{serverURL.Hostname()},
+			url:          server.URL + "/text",
+			want:         fetchResult{Status: 200, ContentType: "text/plain; charset=utf-8", Body: "hello world"},
+		},
+		{
+			name:         "allowed host and port",
+			allowedHosts: []string{serverURL.Host},
+			url:          server.URL + "/text",
+			want:         fetchResult{Status: 200, ContentType: "text/plain; charset=utf-8", Body: "hello world"},
+		},
+		{
+			name:         "error statuses are returned",
+			allowedHosts: []string{serverURL.Host},
+			url:          server.URL + "/json",
+			want:         fetchResult{Status: 404, ContentType: "application/problem+json", Body: `{"title":"not found"}`},
+		},
+		{
+			name:         "long bodies are truncated",
+			allowedHosts: []string{serverURL.Host},
+			maxBytes:     5,
+			url:          server.URL + "/text",
+			want:         fetchResult{Status: 200, ContentType: "text/plain; charset=utf-8", Body: "hello", Truncated: true},
+		},
+		{
+			name:         "truncation drops a split rune",
+			allowedHosts: []string{serverURL.Host},
+			maxBytes:     2,
+			url:          server.URL + "/utf8",
+			want:         fetchResult{Status: 200, ContentType: "text/plain", Body: "a", Truncated: true},
+		},
+		{
+			name:         "redirect inside the allowlist",
+			allowedHosts: []string{serverURL.Host},
+			url:          server.URL + "/redirect",
+			want:         fetchResult{Status: 200, ContentType: "text/plain; charset=utf-8", Body: "hello world"},
+		},
+		{
+			name:         "other port of an allowed host",
+			allowedHosts: []string{serverURL.Hostname() + ":1"},
+			url:          server.URL + "/text",
+			wantErr:      "host " + serverURL.Host + " is not allowed",
+		},
+		{
+			name:         "host not allowed",
+			allowedHosts: []string{"example.com"},
+			url:          server.URL + "/text",
+			wantErr:      "is not allowed",
+		},
+		{
+			name:         "redirect outside the allowlist",
+			allowedHosts: []string{serverURL.Host},
+			url:          server.URL + "/outside",
+			wantErr:      "host localhost:" + serverURL.Port() + " is not allowed",
+		},
+		{
+			name:         "too many redirects",
+			allowedHosts: []string{serverURL.Host},
+			url:          server.URL + "/loop",
+			wantErr:      "too many redirects",
+		},
+		{
+			name:         "scheme not allowed",
+			allowedHosts: []string{serverURL.Host},
+			url:          "file:///etc/passwd",
+			wantErr:      "only http and https urls can be fetched",
+		},
+		{
+			name:         "binary content",
+			allowedHosts: []string{serverURL.Host},
+			url:          server.URL + "/image",
+			wantErr:      `unsupported content type "image/png"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := NewFetchURL(server.Client(), tt.allowedHosts, tt.maxBytes)
+			arguments, _ := json.Marshal(map[string]string{"url": tt.url})
+			got, err := tool.Handler(context.Background(), string(arguments))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetch: %v", err)
+			}
+			var result fetchResult
+			if err := json.Unmarshal([]byte(got), &result); err != nil {
+				t.Fatalf("result %s: %v", got, err)
+			}
+			if result != tt.want {
+				t.Errorf("got %+v, want %+v", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchURLInvalidArguments(t *testing.T) {
+	tool := NewFetchURL(http.DefaultClient, nil, 0)
+	if _, err := tool.Handler(context.Background(), `{"url": 1}`); err == nil || !strings.HasPrefix(err.Error(), "invalid arguments") {
+		t.Errorf("err = %v, want invalid arguments", err)
+	}
+}
@@ -0,0 +1,28 @@
+package tools
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+)
+
+// Builtin returns the tools the service registers on startup, fetch_url
+// downloading with client from allowedHosts at most maxBytes.
+func Builtin(client *http.Client, allowedHosts []string, maxBytes int64) []*entity.Tool {
+	return []*entity.Tool{
+		NewCalculator(),
+		NewCurrentTime(time.Now),
+		NewTimeConverter(),
+		NewFetchURL(client, allowedHosts, maxBytes),
+	}
+}
+
+// Names lists the names of the Builtin tools.
+func Names() []string {
+	var names []string
+	for _, tool := range Builtin(http.DefaultClient, nil, 0) {
+		names = append(names, tool.Name)
+	}
+	return names
+}
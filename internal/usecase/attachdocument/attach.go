@@ -0,0 +1,86 @@
+package attachdocument
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+var ErrChatNotFound = errors.New("chat not found")
+
+type AttachDocumentInput struct {
+	ChatID  string `json:"chat_id"`
+	UserID  string `json:"user_id"`
+	Name    string `json:"name"`    // cited as the source of the chunks
+	Content string `json:"content"` // plain text
+}
+
+type AttachDocumentOutput struct {
+	DocumentID string `json:"document_id"`
+	ChatID     string `json:"chat_id"`
+	Name       string `json:"name"`
+	Chunks     int    `json:"chunks"`
+}
+
+// AttachDocumentUseCase splits a document into chunks and embeds them, so
+// the completions of the chat can use the ones related to each question.
+type AttachDocumentUseCase struct {
+	ChatGateway      gateway.ChatGateway
+	DocumentGateway  gateway.DocumentGateway
+	EmbeddingGateway gateway.EmbeddingGateway
+}
+
+func NewAttachDocumentUseCase(chatGateway gateway.ChatGateway, documentGateway gateway.DocumentGateway, embeddingGateway gateway.EmbeddingGateway) *AttachDocumentUseCase {
+	return &AttachDocumentUseCase{
+		ChatGateway:      chatGateway,
+		DocumentGateway:  documentGateway,
+		EmbeddingGateway: embeddingGateway,
+	}
+}
+
+func (uc *AttachDocumentUseCase) Execute(ctx context.Context, input AttachDocumentInput) (*AttachDocumentOutput, error) {
+	if input.ChatID == "" {
+		verr := &entity.ValidationError{Entity: "document"}
+		verr.Add("chat_id", "is empty")
+		return nil, verr
+	}
+
+	chat, err := uc.ChatGateway.FindByID(ctx, input.ChatID)
+	if err != nil {
+		if err.Error() == "chat not found" {
+			return nil, ErrChatNotFound
+		}
+		return nil, errors.New("error fetching chat: " + err.Error())
+	}
+	// other users chats are reported as missing so their ids do not leak
+	if chat.UserID != input.UserID {
+		return nil, ErrChatNotFound
+	}
+
+	document, err := entity.NewDocument(chat.ID, input.UserID, input.Name, input.Content)
+	if err != nil {
+		return nil, fmt.Errorf("error creating document: %w", err)
+	}
+	embeddings, err := uc.EmbeddingGateway.Embed(ctx, document.ChunkContents())
+	if err != nil {
+		return nil, errors.New("error embedding document: " + err.Error())
+	}
+	err = document.SetEmbeddings(embeddings)
+	if err != nil {
+		return nil, errors.New("error embedding document: " + err.Error())
+	}
+
+	err = uc.DocumentGateway.Create(ctx, document)
+	if err != nil {
+		return nil, errors.New("error persisting document: " + err.Error())
+	}
+	return &AttachDocumentOutput{
+		DocumentID: document.ID,
+		ChatID:     chat.ID,
+		Name:       document.Name,
+		Chunks:     document.ChunkCount,
+	}, nil
+}
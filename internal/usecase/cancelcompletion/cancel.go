@@ -0,0 +1,54 @@
+package cancelcompletion
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+var ErrGenerationNotFound = errors.New("generation not found")
+
+type CancelCompletionInput struct {
+	RequestID string `json:"request_id"`
+	UserID    string `json:"user_id"`
+}
+
+type CancelCompletionOutput struct {
+	RequestID string `json:"request_id"`
+	Cancelled bool   `json:"cancelled"`
+}
+
+type CancelCompletionUseCase struct {
+	GenerationGateway gateway.GenerationGateway
+}
+
+func NewCancelCompletionUseCase(generationGateway gateway.GenerationGateway) *CancelCompletionUseCase {
+	return &CancelCompletionUseCase{
+		GenerationGateway: generationGateway,
+	}
+}
+
+func (uc *CancelCompletionUseCase) Execute(ctx context.Context, input CancelCompletionInput) (*CancelCompletionOutput, error) {
+	verr := &entity.ValidationError{Entity: "cancel"}
+	if input.RequestID == "" {
+		verr.Add("request_id", "is empty")
+	}
+	if input.UserID == "" {
+		verr.Add("user_id", "is empty")
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	// generations of other users are reported as missing
+	if !uc.GenerationGateway.Cancel(input.RequestID, input.UserID) {
+		return nil, ErrGenerationNotFound
+	}
+
+	return &CancelCompletionOutput{
+		RequestID: input.RequestID,
+		Cancelled: true,
+	}, nil
+}
@@ -2,145 +2,331 @@ package chatcompletion
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/gabrielmq/chat-service/internal/domain/entity"
 	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+	"github.com/gabrielmq/chat-service/internal/usecase/chatconfig"
+	"github.com/gabrielmq/chat-service/internal/usecase/chatturn"
+	"github.com/google/uuid"
 	"github.com/sashabaranov/go-openai"
 )
 
-type ChatCompletionConfigurationInput struct {
-	Model                string
-	ModelMaxTokens       int
-	Temperature          float32  // 0.0 to 1.0
-	TopP                 float32  // 0.0 to 1.0 - to a low value, like 0.1, the model will be very conservative in its word choices, and will tend to generate relatively predictable prompts
-	N                    int      // number of messages to generate
-	Stop                 []string // list of tokens to stop on
-	MaxTokens            int      // number of tokens to generate
-	PresencePenalty      float32  // -2.0 to 2.0 - Number between -2.0 and 2.0. Positive values penalize new tokens based on whether they appear in the text so far, increasing the model's likelihood to talk about new topics.
-	FrequencyPenalty     float32  // -2.0 to 2.0 - Number between -2.0 and 2.0. Positive values penalize new tokens based on their existing frequency in the text so far, increasing the model's likelihood to talk about new topics.
-	InitialSystemMessage string
-}
+const saveTimeout = 5 * time.Second
+
+var ErrChatNotFound = chatturn.ErrChatNotFound
+
+type ChatCompletionContentPartInput = chatturn.ContentPartInput
+
+type ChatCompletionHistoryInput = chatturn.HistoryInput
 
 type ChatCompletionInput struct {
-	ChatID        string                           `json:"chat_id,omitempty"`
-	UserID        string                           `json:"user_id"`
-	UserMessage   string                           `json:"user_message"`
-	Configuration ChatCompletionConfigurationInput `json:"Configuration"`
+	RequestID         string                           `json:"request_id,omitempty"`
+	ChatID            string                           `json:"chat_id,omitempty"`
+	UserID            string                           `json:"user_id"`
+	UserMessage       string                           `json:"user_message"`
+	UserContent       []ChatCompletionContentPartInput `json:"user_content,omitempty"` // text and images, instead of UserMessage
+	History           []ChatCompletionHistoryInput     `json:"-"`                      // earlier turns a new chat starts with
+	PinMessage        bool                             `json:"pin_message,omitempty"`
+	AssistantID       string                           `json:"assistant_id,omitempty"`     // settings of a new chat, instead of the server defaults
+	ParentMessageID   string                           `json:"parent_id,omitempty"`        // continue from this message, starting a new branch when it already has replies
+	ResponseSchema    json.RawMessage                  `json:"response_schema,omitempty"`  // replaces the chat schema for this reply only
+	Regenerate        bool                             `json:"-"`                          // answer the last user message again instead of UserMessage
+	Template          string                           `json:"template,omitempty"`         // prompt template rendered into the system message of a new chat
+	TemplateVersion   int                              `json:"template_version,omitempty"` // 0 renders the latest version
+	TemplateVariables map[string]string                `json:"template_variables,omitempty"`
+	Overrides         *chatconfig.OverridesInput       `json:"configuration,omitempty"`
+	Configuration     chatconfig.ConfigurationInput    `json:"-"`
 }
 
+type ChatCompletionChoiceOutput struct {
+	Index        int    `json:"index"`
+	MessageID    string `json:"message_id"`
+	Content      string `json:"content"`
+	FinishReason string `json:"finish_reason"`
+}
+
+// ChatCompletionSourceOutput is a document chunk the reply may cite.
+type ChatCompletionSourceOutput struct {
+	DocumentID string  `json:"document_id"`
+	Source     string  `json:"source"`
+	Score      float64 `json:"score"`
+}
+
+// ChatCompletionUsageOutput counts the tokens of every request made for the reply.
+type ChatCompletionUsageOutput struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+func (u *ChatCompletionUsageOutput) add(usage openai.Usage) {
+	u.PromptTokens += usage.PromptTokens
+	u.CompletionTokens += usage.CompletionTokens
+	u.TotalTokens += usage.TotalTokens
+}
+
+// ChatCompletionOutput carries the reply kept in the chat history in Content.
 type ChatCompletionOutput struct {
-	ChatID  string `json:"chat_id"`
-	UserID  string `json:"user_id"`
-	Content string `json:"content"`
+	RequestID    string                       `json:"request_id"`
+	ChatID       string                       `json:"chat_id"`
+	UserID       string                       `json:"user_id"`
+	Model        string                       `json:"model"`
+	Content      string                       `json:"content"`
+	Object       json.RawMessage              `json:"object,omitempty"`
+	FinishReason string                       `json:"finish_reason"`
+	Choices      []ChatCompletionChoiceOutput `json:"choices"`
+	Sources      []ChatCompletionSourceOutput `json:"sources,omitempty"`
+	Usage        ChatCompletionUsageOutput    `json:"usage"`
 }
 
 type ChatCompletionUseCase struct {
-	ChatGateway  gateway.ChatGateway
-	OpenAIClient *openai.Client
+	ChatGateway       gateway.ChatGateway
+	ModelGateway      gateway.ModelGateway
+	SummaryGateway    gateway.SummaryGateway
+	GenerationGateway gateway.GenerationGateway
+	ToolGateway       gateway.ToolGateway
+	DocumentGateway   gateway.DocumentGateway
+	EmbeddingGateway  gateway.EmbeddingGateway
+	AttachmentGateway gateway.AttachmentGateway
+	StorageGateway    gateway.AttachmentStorageGateway
+	TemplateGateway   gateway.PromptTemplateGateway
+	AssistantGateway  gateway.AssistantGateway
+	OpenAIClient      *openai.Client
+	turns             *chatturn.Turns
 }
 
-func NewChatCompletionUseCase(chatGateway gateway.ChatGateway, openAIClient *openai.Client) *ChatCompletionUseCase {
+func NewChatCompletionUseCase(chatGateway gateway.ChatGateway, modelGateway gateway.ModelGateway, summaryGateway gateway.SummaryGateway, generationGateway gateway.GenerationGateway, toolGateway gateway.ToolGateway, documentGateway gateway.DocumentGateway, embeddingGateway gateway.EmbeddingGateway, attachmentGateway gateway.AttachmentGateway, storageGateway gateway.AttachmentStorageGateway, templateGateway gateway.PromptTemplateGateway, assistantGateway gateway.AssistantGateway, openAIClient *openai.Client) *ChatCompletionUseCase {
 	return &ChatCompletionUseCase{
-		ChatGateway:  chatGateway,
-		OpenAIClient: openAIClient,
+		ChatGateway:       chatGateway,
+		ModelGateway:      modelGateway,
+		SummaryGateway:    summaryGateway,
+		GenerationGateway: generationGateway,
+		ToolGateway:       toolGateway,
+		DocumentGateway:   documentGateway,
+		EmbeddingGateway:  embeddingGateway,
+		AttachmentGateway: attachmentGateway,
+		StorageGateway:    storageGateway,
+		TemplateGateway:   templateGateway,
+		AssistantGateway:  assistantGateway,
+		OpenAIClient:      openAIClient,
+		turns: &chatturn.Turns{
+			ChatGateway:       chatGateway,
+			ModelGateway:      modelGateway,
+			SummaryGateway:    summaryGateway,
+			ToolGateway:       toolGateway,
+			DocumentGateway:   documentGateway,
+			EmbeddingGateway:  embeddingGateway,
+			AttachmentGateway: attachmentGateway,
+			StorageGateway:    storageGateway,
+			TemplateGateway:   templateGateway,
+			AssistantGateway:  assistantGateway,
+		},
+	}
+}
+
+// Wait returns once the chats described in the background are saved.
+func (uc *ChatCompletionUseCase) Wait() {
+	uc.turns.Wait()
+}
+
+func (input ChatCompletionInput) turn() chatturn.Input {
+	return chatturn.Input{
+		ChatID:            input.ChatID,
+		UserID:            input.UserID,
+		UserMessage:       input.UserMessage,
+		UserContent:       input.UserContent,
+		History:           input.History,
+		PinMessage:        input.PinMessage,
+		AssistantID:       input.AssistantID,
+		ParentMessageID:   input.ParentMessageID,
+		Regenerate:        input.Regenerate,
+		ResponseSchema:    input.ResponseSchema,
+		Template:          input.Template,
+		TemplateVersion:   input.TemplateVersion,
+		TemplateVariables: input.TemplateVariables,
+		Overrides:         input.Overrides,
+		Configuration:     input.Configuration,
 	}
 }
 
 func (uc *ChatCompletionUseCase) Execute(ctx context.Context, input ChatCompletionInput) (*ChatCompletionOutput, error) {
-	chat, err := uc.ChatGateway.FindByID(ctx, input.ChatID)
+	if input.RequestID == "" {
+		input.RequestID = uuid.New().String()
+	}
+
+	turn := input.turn()
+	if err := turn.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	err := uc.GenerationGateway.Register(input.RequestID, input.UserID, cancel)
 	if err != nil {
-		if err.Error() == "chat not found" {
-			chat, err = createNewChat(input)
-			if err != nil {
-				return nil, errors.New("error creating new chat: " + err.Error())
-			}
-			err = uc.ChatGateway.Create(ctx, chat)
-			if err != nil {
-				return nil, errors.New("error persisting new chat: " + err.Error())
-			}
-		} else {
-			return nil, errors.New("error fetching existing chat: " + err.Error())
-		}
+		return nil, fmt.Errorf("error registering generation: %w", err)
 	}
+	defer uc.GenerationGateway.Unregister(input.RequestID)
 
-	userMessage, err := entity.NewMessage("user", input.UserMessage, chat.Configuration.Model)
+	chat, err := uc.turns.Chat(ctx, turn)
 	if err != nil {
-		return nil, errors.New("error creating new message: " + err.Error())
+		return nil, err
 	}
-	err = chat.AddMessage(userMessage)
+	schema, err := chatturn.ResponseSchema(chat, input.ResponseSchema)
 	if err != nil {
-		return nil, errors.New("error adding new message: " + err.Error())
+		return nil, err
 	}
-
-	messages := []openai.ChatCompletionMessage{}
-	for _, msg := range chat.Messages {
-		messages = append(messages, openai.ChatCompletionMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
+	err = uc.turns.AddUserMessage(ctx, chat, turn)
+	if err != nil {
+		return nil, err
 	}
+	uc.turns.Summarize(ctx, chat)
+	attachments := uc.turns.LoadAttachments(ctx, chat)
+	sources := uc.turns.Retrieve(ctx, chat)
 
-	resp, err := uc.OpenAIClient.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
+	tools := uc.turns.ToolDefinitions(ctx, chat)
+	var resp openai.ChatCompletionResponse
+	var object json.RawMessage
+	var usage ChatCompletionUsageOutput
+	var corrections []openai.ChatCompletionMessage
+	retries := 0
+	toolsCalled := false
+	for round := 1; ; round++ {
+		request := openai.ChatCompletionRequest{
 			Model:            chat.Configuration.Model.Name,
-			Messages:         messages,
+			Messages:         append(chatturn.Messages(chat, attachments, sources), corrections...),
 			MaxTokens:        chat.Configuration.MaxTokens,
 			Temperature:      chat.Configuration.Temperature,
 			TopP:             chat.Configuration.TopP,
+			N:                chat.Configuration.N,
 			PresencePenalty:  chat.Configuration.PresencePenalty,
 			FrequencyPenalty: chat.Configuration.FrequencyPenalty,
 			Stop:             chat.Configuration.Stop,
-		},
-	)
-	if err != nil {
-		return nil, errors.New("error openai: " + err.Error())
+			Tools:            tools,
+		}
+		if len(tools) > 0 && (round >= entity.MaxToolRounds || retries > 0) {
+			request.ToolChoice = "none"
+		}
+		if schema != nil {
+			request.Messages = append(request.Messages, chatturn.SchemaInstruction(schema))
+			request.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+		}
+
+		resp, err = uc.OpenAIClient.CreateChatCompletion(ctx, request)
+		if err != nil {
+			if ctx.Err() != nil {
+				return uc.cancelled(chat, input)
+			}
+			err = errors.New("error openai: " + err.Error())
+			if toolsCalled {
+				return nil, uc.turns.Failed(chat, err)
+			}
+			return nil, err
+		}
+		usage.add(resp.Usage)
+		if len(resp.Choices) == 0 {
+			break
+		}
+		if len(resp.Choices[0].Message.ToolCalls) > 0 && round < entity.MaxToolRounds && retries == 0 {
+			chat.AddUsage(resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+			toolsCalled = true
+			_, err = uc.turns.CallTools(ctx, chat, resp.Choices[0].Message.Content, chatturn.ToolCalls(resp.Choices[0].Message.ToolCalls))
+			if err != nil {
+				return nil, uc.turns.Failed(chat, err)
+			}
+			uc.turns.Summarize(ctx, chat)
+			continue
+		}
+		if schema == nil {
+			break
+		}
+
+		var parseErr error
+		object, parseErr = schema.Parse(resp.Choices[0].Message.Content)
+		if parseErr == nil {
+			break
+		}
+		if retries == input.Configuration.SchemaRetries {
+			return nil, uc.turns.Failed(chat, &entity.StructuredOutputError{Attempts: retries + 1, Err: parseErr})
+		}
+		retries++
+		chat.AddUsage(resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+		corrections = append(corrections, chatturn.Correction(resp.Choices[0].Message.Content, parseErr)...)
 	}
 
-	assistant, err := entity.NewMessage("assistant", resp.Choices[0].Message.Content, chat.Configuration.Model)
-	if err != nil {
-		return nil, err
+	if len(resp.Choices) == 0 {
+		return nil, errors.New("error openai: completion has no choices")
 	}
-	err = chat.AddMessage(assistant)
+	sort.Slice(resp.Choices, func(i, j int) bool {
+		return resp.Choices[i].Index < resp.Choices[j].Index
+	})
+	choices := make([]*entity.Message, 0, len(resp.Choices))
+	for _, choice := range resp.Choices {
+		assistant, err := entity.NewMessage("assistant", choice.Message.Content, chat.Configuration.Model)
+		if err != nil {
+			return nil, err
+		}
+		assistant.FinishReason = string(choice.FinishReason)
+		choices = append(choices, assistant)
+	}
+	err = chat.AddChoices(choices)
 	if err != nil {
 		return nil, err
 	}
+	chat.AddUsage(resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	uc.turns.Summarize(ctx, chat)
 
 	err = uc.ChatGateway.Save(ctx, chat)
 	if err != nil {
 		return nil, err
 	}
+	uc.turns.Describe(chat, input.Configuration.RunningSummary)
 
 	output := &ChatCompletionOutput{
-		ChatID:  chat.ID,
-		UserID:  input.UserID,
-		Content: resp.Choices[0].Message.Content,
+		RequestID:    input.RequestID,
+		ChatID:       chat.ID,
+		UserID:       input.UserID,
+		Model:        chat.Configuration.Model.GetName(),
+		Content:      choices[0].Content,
+		Object:       object,
+		FinishReason: choices[0].FinishReason,
+		Usage:        usage,
+	}
+	for i, choice := range choices {
+		output.Choices = append(output.Choices, ChatCompletionChoiceOutput{
+			Index:        resp.Choices[i].Index,
+			MessageID:    choice.ID,
+			Content:      choice.Content,
+			FinishReason: choice.FinishReason,
+		})
+	}
+	for _, source := range sources {
+		output.Sources = append(output.Sources, ChatCompletionSourceOutput{
+			DocumentID: source.Chunk.DocumentID,
+			Source:     source.Chunk.Source(),
+			Score:      source.Score,
+		})
 	}
 
 	return output, nil
 }
 
-func createNewChat(input ChatCompletionInput) (*entity.Chat, error) {
-	model := entity.NewModel(input.Configuration.Model, input.Configuration.ModelMaxTokens)
-	chatConfiguration := &entity.ChatConfiguration{
-		Temperature:      input.Configuration.Temperature,
-		TopP:             input.Configuration.TopP,
-		N:                input.Configuration.N,
-		Stop:             input.Configuration.Stop,
-		MaxTokens:        input.Configuration.MaxTokens,
-		PresencePenalty:  input.Configuration.PresencePenalty,
-		FrequencyPenalty: input.Configuration.FrequencyPenalty,
-		Model:            model,
-	}
-
-	initialMessage, err := entity.NewMessage("system", input.Configuration.InitialSystemMessage, model)
-	if err != nil {
-		return nil, errors.New("error creating initial message: " + err.Error())
-	}
-	chat, err := entity.NewChat(input.UserID, initialMessage, chatConfiguration)
-	if err != nil {
-		return nil, errors.New("error creating new chat: " + err.Error())
+// cancelled saves the user message of a cancelled generation.
+func (uc *ChatCompletionUseCase) cancelled(chat *entity.Chat, input ChatCompletionInput) (*ChatCompletionOutput, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), saveTimeout)
+	defer cancel()
+	if err := uc.ChatGateway.Save(ctx, chat); err != nil {
+		return nil, err
 	}
-	return chat, nil
+	return &ChatCompletionOutput{
+		RequestID:    input.RequestID,
+		ChatID:       chat.ID,
+		UserID:       input.UserID,
+		Model:        chat.Configuration.Model.GetName(),
+		FinishReason: entity.FinishReasonCancelled,
+	}, nil
 }
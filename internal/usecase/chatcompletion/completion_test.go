@@ -0,0 +1,273 @@
+package chatcompletion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/usecase/chatconfig"
+	"github.com/sashabaranov/go-openai"
+)
+
+type fakeChatGateway struct {
+	mu    sync.Mutex
+	chats map[string]*entity.Chat
+}
+
+func (g *fakeChatGateway) Create(ctx context.Context, chat *entity.Chat) error {
+	return g.Save(ctx, chat)
+}
+
+func (g *fakeChatGateway) FindByID(ctx context.Context, chatID string) (*entity.Chat, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	chat, ok := g.chats[chatID]
+	if !ok {
+		return nil, errors.New("chat not found")
+	}
+	return chat, nil
+}
+
+func (g *fakeChatGateway) FindByUserID(ctx context.Context, userID string) ([]*entity.Chat, error) {
+	return nil, nil
+}
+
+func (g *fakeChatGateway) Save(ctx context.Context, chat *entity.Chat) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.chats[chat.ID] = chat
+	return nil
+}
+
+func (g *fakeChatGateway) UpdateTitle(ctx context.Context, chatID string, title string) error {
+	return nil
+}
+
+func (g *fakeChatGateway) UpdateSummary(ctx context.Context, chatID string, summary string) error {
+	return nil
+}
+
+type fakeModelGateway struct{}
+
+func (fakeModelGateway) FindByName(ctx context.Context, name string) (*entity.Model, error) {
+	if name != "gpt-3.5-turbo" {
+		return nil, errors.New("model not found")
+	}
+	return entity.NewModel(name, 4096), nil
+}
+
+func (fakeModelGateway) List(ctx context.Context) ([]*entity.Model, error) {
+	return nil, nil
+}
+
+type fakeSummaryGateway struct{}
+
+func (fakeSummaryGateway) Summarize(ctx context.Context, model *entity.Model, summary string, messages []*entity.Message) (string, error) {
+	return summary, nil
+}
+
+func (fakeSummaryGateway) Title(ctx context.Context, model *entity.Model, messages []*entity.Message) (string, error) {
+	return "title", nil
+}
+
+type fakeGenerationGateway struct{}
+
+func (fakeGenerationGateway) Register(requestID, userID string, cancel context.CancelFunc) error {
+	return nil
+}
+
+func (fakeGenerationGateway) Unregister(requestID string) {}
+
+func (fakeGenerationGateway) Cancel(requestID, userID string) bool {
+	return false
+}
+
+type fakeToolGateway struct {
+	tools map[string]*entity.Tool
+}
+
+func (g fakeToolGateway) FindByName(ctx context.Context, name string) (*entity.Tool, error) {
+	tool, ok := g.tools[name]
+	if !ok {
+		return nil, errors.New("tool not found")
+	}
+	return tool, nil
+}
+
+func (g fakeToolGateway) List(ctx context.Context) ([]*entity.Tool, error) {
+	return nil, nil
+}
+
+// fakeOpenAI answers the completion requests with replies in order and keeps
+// the requests it got.
+type fakeOpenAI struct {
+	mu       sync.Mutex
+	replies  []openai.ChatCompletionMessage
+	requests []openai.ChatCompletionRequest
+}
+
+func (f *fakeOpenAI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request openai.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.requests) >= len(f.replies) {
+		http.Error(w, "no reply left", http.StatusInternalServerError)
+		return
+	}
+	reply := f.replies[len(f.requests)]
+	f.requests = append(f.requests, request)
+
+	finishReason := openai.FinishReasonStop
+	if len(reply.ToolCalls) > 0 {
+		finishReason = openai.FinishReasonToolCalls
+	}
+	json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{Message: reply, FinishReason: finishReason}},
+		Usage:   openai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	})
+}
+
+func toolCall(id, name, arguments string) openai.ChatCompletionMessage {
+	return openai.ChatCompletionMessage{
+		Role: openai.ChatMessageRoleAssistant,
+		ToolCalls: []openai.ToolCall{{
+			ID:       id,
+			Type:     openai.ToolTypeFunction,
+			Function: openai.FunctionCall{Name: name, Arguments: arguments},
+		}},
+	}
+}
+
+func reply(content string) openai.ChatCompletionMessage {
+	return openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content}
+}
+
+func TestExecuteToolCalls(t *testing.T) {
+	tools := map[string]*entity.Tool{
+		"echo": {
+			Name:       "echo",
+			Parameters: json.RawMessage(`{"type":"object"}`),
+			Handler: func(ctx context.Context, arguments string) (string, error) {
+				return arguments, nil
+			},
+		},
+		"broken": {
+			Name:       "broken",
+			Parameters: json.RawMessage(`{"type":"object"}`),
+			Handler: func(ctx context.Context, arguments string) (string, error) {
+				return "", errors.New("out of order")
+			},
+		},
+	}
+
+	// the assistant keeps calling tools, the calls of the last round are
+	// ignored
+	loop := make([]openai.ChatCompletionMessage, entity.MaxToolRounds)
+	for i := range loop {
+		loop[i] = toolCall("call", "echo", `{}`)
+	}
+	loop[entity.MaxToolRounds-1].Content = "done"
+
+	tests := []struct {
+		name    string
+		replies []openai.ChatCompletionMessage
+		results []string // contents of the tool result messages stored in the chat
+		content string
+	}{
+		{
+			name:    "replies after the tool results",
+			replies: []openai.ChatCompletionMessage{toolCall("call-1", "echo", `{"text":"hi"}`), reply("hi")},
+			results: []string{`{"text":"hi"}`},
+			content: "hi",
+		},
+		{
+			name:    "sends failed calls back with the error",
+			replies: []openai.ChatCompletionMessage{toolCall("call-1", "broken", `{}`), reply("sorry")},
+			results: []string{"error: out of order"},
+			content: "sorry",
+		},
+		{
+			name:    "sends unknown tools back as not enabled",
+			replies: []openai.ChatCompletionMessage{toolCall("call-1", "missing", `{}`), reply("sorry")},
+			results: []string{"error: tool missing is not enabled"},
+			content: "sorry",
+		},
+		{
+			name:    "stops calling tools after the last round",
+			replies: loop,
+			results: []string{`{}`, `{}`, `{}`, `{}`},
+			content: "done",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeOpenAI{replies: tt.replies}
+			server := httptest.NewServer(provider)
+			defer server.Close()
+			config := openai.DefaultConfig("token")
+			config.BaseURL = server.URL
+
+			chats := &fakeChatGateway{chats: map[string]*entity.Chat{}}
+			uc := NewChatCompletionUseCase(chats, fakeModelGateway{}, fakeSummaryGateway{}, fakeGenerationGateway{}, fakeToolGateway{tools: tools}, nil, nil, nil, nil, nil, nil, openai.NewClientWithConfig(config))
+
+			output, err := uc.Execute(context.Background(), ChatCompletionInput{
+				ChatID:      "chat",
+				UserID:      "user",
+				UserMessage: "hello",
+				Configuration: chatconfig.ConfigurationInput{
+					Model:                "gpt-3.5-turbo",
+					N:                    1,
+					MaxTokens:            100,
+					InitialSystemMessage: "system",
+					Tools:                []string{"echo", "broken"},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if output.Content != tt.content {
+				t.Errorf("content = %q, want %q", output.Content, tt.content)
+			}
+			if output.Usage.TotalTokens != 15*len(tt.replies) {
+				t.Errorf("total tokens = %d, want %d", output.Usage.TotalTokens, 15*len(tt.replies))
+			}
+
+			if len(provider.requests) != len(tt.replies) {
+				t.Fatalf("got %d completion requests, want %d", len(provider.requests), len(tt.replies))
+			}
+			last := provider.requests[len(provider.requests)-1]
+			if len(tt.replies) == entity.MaxToolRounds && last.ToolChoice != "none" {
+				t.Errorf("tool choice of the last round = %v, want none", last.ToolChoice)
+			}
+
+			chat, err := chats.FindByID(context.Background(), output.ChatID)
+			if err != nil {
+				t.Fatalf("FindByID: %v", err)
+			}
+			var results []string
+			for _, message := range chat.Messages {
+				if message.Role == openai.ChatMessageRoleTool {
+					results = append(results, message.Content)
+				}
+			}
+			if len(results) != len(tt.results) {
+				t.Fatalf("tool results = %q, want %q", results, tt.results)
+			}
+			for i := range results {
+				if results[i] != tt.results[i] {
+					t.Errorf("tool result %d = %q, want %q", i, results[i], tt.results[i])
+				}
+			}
+		})
+	}
+}
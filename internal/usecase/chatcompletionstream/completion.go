@@ -2,165 +2,412 @@ package chatcompletionstream
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/gabrielmq/chat-service/internal/domain/entity"
 	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+	"github.com/gabrielmq/chat-service/internal/usecase/chatconfig"
+	"github.com/gabrielmq/chat-service/internal/usecase/chatturn"
+	"github.com/google/uuid"
 	"github.com/sashabaranov/go-openai"
 )
 
-type ChatCompletionConfigurationInput struct {
-	Model                string
-	ModelMaxTokens       int
-	Temperature          float32
-	TopP                 float32
-	N                    int
-	Stop                 []string
-	MaxTokens            int
-	PresencePenalty      float32
-	FrequencyPenalty     float32
-	InitialSystemMessage string
-}
+const saveTimeout = 5 * time.Second
+
+var ErrChatNotFound = chatturn.ErrChatNotFound
+
+type ChatCompletionContentPartInput = chatturn.ContentPartInput
+
+type ChatCompletionHistoryInput = chatturn.HistoryInput
 
 type ChatCompletionInput struct {
-	ChatID      string
-	UserID      string
-	UserMessage string
-	Config      ChatCompletionConfigurationInput
+	RequestID         string
+	ChatID            string
+	UserID            string
+	UserMessage       string
+	UserContent       []ChatCompletionContentPartInput
+	History           []ChatCompletionHistoryInput // earlier turns a new chat starts with
+	PinMessage        bool
+	AssistantID       string // settings of a new chat, instead of the server defaults
+	ParentMessageID   string
+	Regenerate        bool
+	ResponseSchema    json.RawMessage
+	Template          string // prompt template rendered into the system message of a new chat
+	TemplateVersion   int    // 0 renders the latest version
+	TemplateVariables map[string]string
+	Overrides         *chatconfig.OverridesInput
+	Configuration     chatconfig.ConfigurationInput
+}
+
+type ChatCompletionChoiceOutput struct {
+	Index        int
+	MessageID    string
+	Content      string
+	FinishReason string
 }
 
+// ChatCompletionSourceOutput is a document chunk the reply may cite.
+type ChatCompletionSourceOutput struct {
+	DocumentID string
+	Source     string
+	Score      float64
+}
+
+// ChatCompletionOutput is sent with the content accumulated so far for the choice at Index.
 type ChatCompletionOutput struct {
-	ChatID  string
-	UserID  string
-	Content string
+	RequestID    string
+	ChatID       string
+	UserID       string
+	Model        string
+	Index        int
+	Content      string
+	Object       json.RawMessage
+	FinishReason string
+	Choices      []ChatCompletionChoiceOutput
+	Sources      []ChatCompletionSourceOutput
+	Provisional  bool // the next completion may still replace the content
 }
 
 type ChatCompletionUseCase struct {
-	ChatGateway  gateway.ChatGateway
-	OpenAiClient *openai.Client
-	Stream       chan ChatCompletionOutput
+	ChatGateway       gateway.ChatGateway
+	ModelGateway      gateway.ModelGateway
+	SummaryGateway    gateway.SummaryGateway
+	GenerationGateway gateway.GenerationGateway
+	ToolGateway       gateway.ToolGateway
+	DocumentGateway   gateway.DocumentGateway
+	EmbeddingGateway  gateway.EmbeddingGateway
+	AttachmentGateway gateway.AttachmentGateway
+	StorageGateway    gateway.AttachmentStorageGateway
+	TemplateGateway   gateway.PromptTemplateGateway
+	AssistantGateway  gateway.AssistantGateway
+	OpenAiClient      *openai.Client
+	turns             *chatturn.Turns
 }
 
-func NewChatCompletionUseCase(chatGateway gateway.ChatGateway, openAiClient *openai.Client, stream chan ChatCompletionOutput) *ChatCompletionUseCase {
+func NewChatCompletionUseCase(chatGateway gateway.ChatGateway, modelGateway gateway.ModelGateway, summaryGateway gateway.SummaryGateway, generationGateway gateway.GenerationGateway, toolGateway gateway.ToolGateway, documentGateway gateway.DocumentGateway, embeddingGateway gateway.EmbeddingGateway, attachmentGateway gateway.AttachmentGateway, storageGateway gateway.AttachmentStorageGateway, templateGateway gateway.PromptTemplateGateway, assistantGateway gateway.AssistantGateway, openAiClient *openai.Client) *ChatCompletionUseCase {
 	return &ChatCompletionUseCase{
-		ChatGateway:  chatGateway,
-		OpenAiClient: openAiClient,
-		Stream:       stream,
+		ChatGateway:       chatGateway,
+		ModelGateway:      modelGateway,
+		SummaryGateway:    summaryGateway,
+		GenerationGateway: generationGateway,
+		ToolGateway:       toolGateway,
+		DocumentGateway:   documentGateway,
+		EmbeddingGateway:  embeddingGateway,
+		AttachmentGateway: attachmentGateway,
+		StorageGateway:    storageGateway,
+		TemplateGateway:   templateGateway,
+		AssistantGateway:  assistantGateway,
+		OpenAiClient:      openAiClient,
+		turns: &chatturn.Turns{
+			ChatGateway:       chatGateway,
+			ModelGateway:      modelGateway,
+			SummaryGateway:    summaryGateway,
+			ToolGateway:       toolGateway,
+			DocumentGateway:   documentGateway,
+			EmbeddingGateway:  embeddingGateway,
+			AttachmentGateway: attachmentGateway,
+			StorageGateway:    storageGateway,
+			TemplateGateway:   templateGateway,
+			AssistantGateway:  assistantGateway,
+		},
 	}
 }
 
-func (uc *ChatCompletionUseCase) Execute(ctx context.Context, input ChatCompletionInput) (*ChatCompletionOutput, error) {
-	chat, err := uc.ChatGateway.FindByID(ctx, input.ChatID)
-	if err != nil {
-		if err.Error() == "chat not found" {
-			chat, err = createNewChat(input)
-			if err != nil {
-				return nil, errors.New("error creating new chat: " + err.Error())
-			}
+// Wait returns once the chats described in the background are saved.
+func (uc *ChatCompletionUseCase) Wait() {
+	uc.turns.Wait()
+}
 
-			if err = uc.ChatGateway.Create(ctx, chat); err != nil {
-				return nil, errors.New("error persisting new chat: " + err.Error())
-			}
-		} else {
-			return nil, errors.New("error fetching existing chat: " + err.Error())
-		}
+func (input ChatCompletionInput) turn() chatturn.Input {
+	return chatturn.Input{
+		ChatID:            input.ChatID,
+		UserID:            input.UserID,
+		UserMessage:       input.UserMessage,
+		UserContent:       input.UserContent,
+		History:           input.History,
+		PinMessage:        input.PinMessage,
+		AssistantID:       input.AssistantID,
+		ParentMessageID:   input.ParentMessageID,
+		Regenerate:        input.Regenerate,
+		ResponseSchema:    input.ResponseSchema,
+		Template:          input.Template,
+		TemplateVersion:   input.TemplateVersion,
+		TemplateVariables: input.TemplateVariables,
+		Overrides:         input.Overrides,
+		Configuration:     input.Configuration,
 	}
+}
 
-	userMessage, err := entity.NewMessage("user", input.UserMessage, chat.Configuration.Model)
-	if err != nil {
-		return nil, errors.New("error creating user message: " + err.Error())
+// Execute sends every partial response to stream, which must be read until it returns.
+func (uc *ChatCompletionUseCase) Execute(ctx context.Context, input ChatCompletionInput, stream chan<- ChatCompletionOutput) (*ChatCompletionOutput, error) {
+	if input.RequestID == "" {
+		input.RequestID = uuid.New().String()
 	}
 
-	if err := chat.AddMessage(userMessage); err != nil {
-		return nil, errors.New("error adding new message: " + err.Error())
+	turn := input.turn()
+	if err := turn.Validate(); err != nil {
+		return nil, err
 	}
 
-	messages := []openai.ChatCompletionMessage{}
-	for _, message := range chat.Messages {
-		messages = append(messages, openai.ChatCompletionMessage{
-			Role:    message.Role,
-			Content: message.Content,
-		})
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if err := uc.GenerationGateway.Register(input.RequestID, input.UserID, cancel); err != nil {
+		return nil, fmt.Errorf("error registering generation: %w", err)
 	}
+	defer uc.GenerationGateway.Unregister(input.RequestID)
 
-	res, err := uc.OpenAiClient.CreateChatCompletionStream(
-		ctx,
-		openai.ChatCompletionRequest{
+	chat, err := uc.turns.Chat(ctx, turn)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := chatturn.ResponseSchema(chat, input.ResponseSchema)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.turns.AddUserMessage(ctx, chat, turn); err != nil {
+		return nil, err
+	}
+
+	uc.turns.Summarize(ctx, chat)
+	attachments := uc.turns.LoadAttachments(ctx, chat)
+	sources := uc.turns.Retrieve(ctx, chat)
+
+	tools := uc.turns.ToolDefinitions(ctx, chat)
+	var corrections []openai.ChatCompletionMessage
+	retries := 0
+	toolsCalled := false
+	for round := 1; ; round++ {
+		request := openai.ChatCompletionRequest{
 			Model:            chat.Configuration.Model.Name,
-			Messages:         messages,
+			Messages:         append(chatturn.Messages(chat, attachments, sources), corrections...),
 			MaxTokens:        chat.Configuration.MaxTokens,
 			Temperature:      chat.Configuration.Temperature,
 			TopP:             chat.Configuration.TopP,
+			N:                chat.Configuration.N,
 			PresencePenalty:  chat.Configuration.PresencePenalty,
 			FrequencyPenalty: chat.Configuration.FrequencyPenalty,
 			Stop:             chat.Configuration.Stop,
+			Tools:            tools,
 			Stream:           true,
-		},
-	)
-	if err != nil {
-		return nil, errors.New("error creating chat completion: " + err.Error())
+		}
+		if len(tools) > 0 && (round >= entity.MaxToolRounds || retries > 0) {
+			request.ToolChoice = "none"
+		}
+		if schema != nil {
+			request.Messages = append(request.Messages, chatturn.SchemaInstruction(schema))
+			request.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+		}
+
+		res, err := uc.OpenAiClient.CreateChatCompletionStream(ctx, request)
+		if err != nil {
+			if ctx.Err() != nil {
+				return uc.finish(chat, input, nil, nil, sources)
+			}
+			err = errors.New("error creating chat completion: " + err.Error())
+			if toolsCalled {
+				return nil, uc.turns.Failed(chat, err)
+			}
+			return nil, err
+		}
+		generated, finishReasons, calls, err := uc.receive(ctx, res, chat, input, schema != nil, stream)
+		res.Close()
+		if err != nil {
+			if toolsCalled {
+				return nil, uc.turns.Failed(chat, err)
+			}
+			return nil, err
+		}
+		if ctx.Err() != nil {
+			return uc.finish(chat, input, generated, finishReasons, sources)
+		}
+
+		// the stream does not report usage, the prompt is the history sent
+		promptTokens := chat.TokenUsage
+		if len(calls) > 0 && round < entity.MaxToolRounds && retries == 0 {
+			toolsCalled = true
+			assistant, err := uc.turns.CallTools(ctx, chat, generated[0], calls)
+			if err != nil {
+				return nil, uc.turns.Failed(chat, err)
+			}
+			chat.AddUsage(promptTokens, assistant.GetQtdTokens())
+			uc.turns.Summarize(ctx, chat)
+			continue
+		}
+		if schema == nil {
+			return uc.finish(chat, input, generated, finishReasons, sources)
+		}
+
+		object, parseErr := schema.Parse(generated[0])
+		if parseErr == nil {
+			output, err := uc.finish(chat, input, generated, finishReasons, sources)
+			if err != nil {
+				return nil, err
+			}
+			output.Object = object
+			return output, nil
+		}
+		if retries == input.Configuration.SchemaRetries {
+			return nil, uc.turns.Failed(chat, &entity.StructuredOutputError{Attempts: retries + 1, Err: parseErr})
+		}
+		retries++
+		rejected, err := entity.NewMessage("assistant", generated[0], chat.Configuration.Model)
+		if err != nil {
+			return nil, errors.New("error creating assistant message: " + err.Error())
+		}
+		chat.AddUsage(promptTokens, rejected.GetQtdTokens())
+		corrections = append(corrections, chatturn.Correction(generated[0], parseErr)...)
 	}
+}
 
-	var fullResponse strings.Builder
+// receive reads one streamed completion, sending the content of each choice to stream.
+func (uc *ChatCompletionUseCase) receive(ctx context.Context, res *openai.ChatCompletionStream, chat *entity.Chat, input ChatCompletionInput, provisional bool, stream chan<- ChatCompletionOutput) ([]string, []string, []entity.ToolCall, error) {
+	contents := make([]strings.Builder, chat.Configuration.N)
+	finishReasons := make([]string, chat.Configuration.N)
+	var calls []entity.ToolCall
+	cancelled := false
 	for {
 		response, err := res.Recv()
 		if errors.Is(err, io.EOF) {
 			break
 		}
 		if err != nil {
-			return nil, errors.New("error streaming response: " + err.Error())
+			if ctx.Err() != nil {
+				cancelled = true
+				break
+			}
+			return nil, nil, nil, errors.New("error streaming response: " + err.Error())
 		}
 
-		fullResponse.WriteString(response.Choices[0].Delta.Content)
+		for _, choice := range response.Choices {
+			if choice.Index < 0 || choice.Index >= len(contents) {
+				continue
+			}
+			contents[choice.Index].WriteString(choice.Delta.Content)
+			if choice.FinishReason != "" {
+				finishReasons[choice.Index] = string(choice.FinishReason)
+			}
+			// tools are only sent with a single choice, the calls come in
+			// fragments that are joined by their index
+			if len(choice.Delta.ToolCalls) > 0 {
+				calls = appendToolCallDeltas(calls, choice.Delta.ToolCalls)
+				continue
+			}
 
-		uc.Stream <- ChatCompletionOutput{
-			ChatID:  chat.ID,
-			UserID:  input.UserID,
-			Content: fullResponse.String(),
+			select {
+			case stream <- ChatCompletionOutput{
+				RequestID:   input.RequestID,
+				ChatID:      chat.ID,
+				UserID:      input.UserID,
+				Model:       chat.Configuration.Model.GetName(),
+				Index:       choice.Index,
+				Content:     contents[choice.Index].String(),
+				Provisional: provisional || len(calls) > 0,
+			}:
+			case <-ctx.Done():
+			}
 		}
 	}
 
-	assistant, err := entity.NewMessage("assistant", fullResponse.String(), chat.Configuration.Model)
-	if err != nil {
-		return nil, errors.New("error creating assistant message: " + err.Error())
+	generated := make([]string, len(contents))
+	for i := range contents {
+		generated[i] = contents[i].String()
+		if finishReasons[i] != "" {
+			continue
+		}
+		finishReasons[i] = entity.FinishReasonStop
+		if cancelled {
+			finishReasons[i] = entity.FinishReasonCancelled
+		}
 	}
+	return generated, finishReasons, calls, nil
+}
 
-	if err := chat.AddMessage(assistant); err != nil {
-		return nil, errors.New("error adding new message: " + err.Error())
+func appendToolCallDeltas(calls []entity.ToolCall, deltas []openai.ToolCall) []entity.ToolCall {
+	for _, delta := range deltas {
+		i := len(calls) - 1
+		if delta.Index != nil {
+			i = *delta.Index
+		} else if delta.ID != "" {
+			i = len(calls)
+		}
+		if i < 0 {
+			continue
+		}
+		for len(calls) <= i {
+			calls = append(calls, entity.ToolCall{})
+		}
+		if delta.ID != "" {
+			calls[i].ID = delta.ID
+		}
+		calls[i].Name += delta.Function.Name
+		calls[i].Arguments += delta.Function.Arguments
 	}
+	return calls
+}
 
-	if err := uc.ChatGateway.Save(ctx, chat); err != nil {
-		return nil, errors.New("error saving chat: " + err.Error())
+// finish stores whatever the assistant produced, even when the generation was cancelled.
+func (uc *ChatCompletionUseCase) finish(chat *entity.Chat, input ChatCompletionInput, contents, finishReasons []string, sources []entity.ScoredChunk) (*ChatCompletionOutput, error) {
+	output := &ChatCompletionOutput{
+		RequestID:    input.RequestID,
+		ChatID:       chat.ID,
+		UserID:       input.UserID,
+		Model:        chat.Configuration.Model.GetName(),
+		FinishReason: entity.FinishReasonCancelled,
+	}
+	for _, source := range sources {
+		output.Sources = append(output.Sources, ChatCompletionSourceOutput{
+			DocumentID: source.Chunk.DocumentID,
+			Source:     source.Chunk.Source(),
+			Score:      source.Score,
+		})
 	}
-	return &ChatCompletionOutput{
-		ChatID:  chat.ID,
-		UserID:  input.UserID,
-		Content: fullResponse.String(),
-	}, nil
-}
 
-func createNewChat(input ChatCompletionInput) (*entity.Chat, error) {
-	model := entity.NewModel(input.Config.Model, input.Config.ModelMaxTokens)
-	chatConfig := &entity.ChatConfiguration{
-		Temperature:      input.Config.Temperature,
-		TopP:             input.Config.TopP,
-		N:                input.Config.N,
-		Stop:             input.Config.Stop,
-		MaxTokens:        input.Config.MaxTokens,
-		PresencePenalty:  input.Config.PresencePenalty,
-		FrequencyPenalty: input.Config.FrequencyPenalty,
-		Model:            model,
-	}
-	initialMessage, err := entity.NewMessage("system", input.Config.InitialSystemMessage, model)
-	if err != nil {
-		return nil, errors.New("error creating initial message: " + err.Error())
+	var choices []*entity.Message
+	completionTokens := 0
+	for i, content := range contents {
+		if content == "" {
+			continue
+		}
+		assistant, err := entity.NewMessage("assistant", content, chat.Configuration.Model)
+		if err != nil {
+			return nil, errors.New("error creating assistant message: " + err.Error())
+		}
+		assistant.FinishReason = finishReasons[i]
+		choices = append(choices, assistant)
+		completionTokens += assistant.GetQtdTokens()
+		output.Choices = append(output.Choices, ChatCompletionChoiceOutput{
+			Index:        i,
+			MessageID:    assistant.ID,
+			Content:      content,
+			FinishReason: finishReasons[i],
+		})
+	}
+	if len(choices) > 0 {
+		// the stream does not report usage, the prompt is the history sent
+		promptTokens := chat.TokenUsage
+		if err := chat.AddChoices(choices); err != nil {
+			return nil, errors.New("error adding new message: " + err.Error())
+		}
+		chat.AddUsage(promptTokens, completionTokens)
+		output.Content = choices[0].Content
+		output.FinishReason = choices[0].FinishReason
+	} else if len(finishReasons) > 0 {
+		output.FinishReason = finishReasons[0]
 	}
 
-	chat, err := entity.NewChat(input.UserID, initialMessage, chatConfig)
-	if err != nil {
-		return nil, errors.New("error creating new chat: " + err.Error())
+	ctx, cancel := context.WithTimeout(context.Background(), saveTimeout)
+	defer cancel()
+	uc.turns.Summarize(ctx, chat)
+	if err := uc.ChatGateway.Save(ctx, chat); err != nil {
+		return nil, errors.New("error saving chat: " + err.Error())
+	}
+	if len(choices) > 0 {
+		uc.turns.Describe(chat, input.Configuration.RunningSummary)
 	}
-	return chat, nil
+	return output, nil
 }
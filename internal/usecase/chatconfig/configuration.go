@@ -0,0 +1,180 @@
+package chatconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+)
+
+// ConfigurationInput holds the server settings of new chats.
+type ConfigurationInput struct {
+	Model                string
+	Temperature          float32  // 0.0 to 1.0
+	TopP                 float32  // 0.0 to 1.0 - to a low value, like 0.1, the model will be very conservative in its word choices, and will tend to generate relatively predictable prompts
+	N                    int      // number of messages to generate
+	Stop                 []string // list of tokens to stop on
+	MaxTokens            int      // number of tokens to generate
+	PresencePenalty      float32  // -2.0 to 2.0 - Number between -2.0 and 2.0. Positive values penalize new tokens based on whether they appear in the text so far, increasing the model's likelihood to talk about new topics.
+	FrequencyPenalty     float32  // -2.0 to 2.0 - Number between -2.0 and 2.0. Positive values penalize new tokens based on their existing frequency in the text so far, increasing the model's likelihood to talk about new topics.
+	InitialSystemMessage string
+	ContextStrategy      string          // sliding_window, pinned or summarize
+	AllowedModels        []string        // models clients may pick besides Model
+	MaxTokensLimit       int             // highest MaxTokens a client may ask for, defaults to MaxTokens
+	RunningSummary       bool            // keep a summary of the whole chat for the history
+	Tools                []string        // tools the assistant may call in new chats
+	ResponseSchema       json.RawMessage // JSON schema replies of new chats must follow
+	SchemaRetries        int             // corrective requests sent when a reply does not match the schema
+	RetrievalTopK        int             // chunks of the chat documents added to the prompt, 0 disables retrieval
+}
+
+// OverridesInput holds the settings a client may choose, nil keeps the default.
+type OverridesInput struct {
+	Model            *string         `json:"model,omitempty"`
+	Temperature      *float32        `json:"temperature,omitempty"`
+	TopP             *float32        `json:"top_p,omitempty"`
+	N                *int            `json:"n,omitempty"`
+	MaxTokens        *int            `json:"max_tokens,omitempty"`
+	Stop             []string        `json:"stop,omitempty"`
+	PresencePenalty  *float32        `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float32        `json:"frequency_penalty,omitempty"`
+	SystemMessage    *string         `json:"system_message,omitempty"`
+	ContextStrategy  *string         `json:"context_strategy,omitempty"`
+	Tools            []string        `json:"tools,omitempty"`           // names of registered tools, checked when the chat is created
+	ResponseSchema   json.RawMessage `json:"response_schema,omitempty"` // JSON schema every reply of the chat must follow
+}
+
+// WithOverrides applies the client overrides allowed by the server.
+func (c ConfigurationInput) WithOverrides(overrides *OverridesInput) (ConfigurationInput, error) {
+	if overrides == nil {
+		return c, nil
+	}
+
+	verr := &entity.ValidationError{Entity: "chat configuration"}
+	if overrides.Model != nil {
+		if c.isModelAllowed(*overrides.Model) {
+			c.Model = *overrides.Model
+		} else {
+			verr.Add("model", fmt.Sprintf("model %q is not allowed", *overrides.Model))
+		}
+	}
+	for _, tool := range overrides.Tools {
+		if !c.isToolAllowed(tool) {
+			verr.Add("tools", fmt.Sprintf("tool %q is not allowed", tool))
+		}
+	}
+	if overrides.MaxTokens != nil {
+		if *overrides.MaxTokens <= c.maxTokensLimit() {
+			c.MaxTokens = *overrides.MaxTokens
+		} else {
+			verr.Add("max_tokens", fmt.Sprintf("must not exceed %d", c.maxTokensLimit()))
+		}
+	}
+	if err := verr.Err(); err != nil {
+		return c, err
+	}
+
+	// the ranges are checked by entity.ChatConfiguration when the chat is created
+	if overrides.Temperature != nil {
+		c.Temperature = *overrides.Temperature
+	}
+	if overrides.TopP != nil {
+		c.TopP = *overrides.TopP
+	}
+	if overrides.N != nil {
+		c.N = *overrides.N
+	}
+	if overrides.Stop != nil {
+		c.Stop = overrides.Stop
+	}
+	if overrides.PresencePenalty != nil {
+		c.PresencePenalty = *overrides.PresencePenalty
+	}
+	if overrides.FrequencyPenalty != nil {
+		c.FrequencyPenalty = *overrides.FrequencyPenalty
+	}
+	if overrides.SystemMessage != nil && *overrides.SystemMessage != "" {
+		c.InitialSystemMessage = *overrides.SystemMessage
+	}
+	if overrides.ContextStrategy != nil {
+		c.ContextStrategy = *overrides.ContextStrategy
+	}
+	if overrides.Tools != nil {
+		c.Tools = overrides.Tools
+	}
+	if overrides.ResponseSchema != nil {
+		c.ResponseSchema = overrides.ResponseSchema
+	}
+	return c, nil
+}
+
+// WithAssistant applies the settings of the assistant, its model is always allowed.
+func (c ConfigurationInput) WithAssistant(assistant *entity.Assistant) ConfigurationInput {
+	configuration := assistant.Configuration
+	c.AllowedModels = append(append([]string(nil), c.AllowedModels...), c.Model)
+	c.Model = configuration.Model.GetName()
+	c.Temperature = configuration.Temperature
+	c.TopP = configuration.TopP
+	c.N = configuration.N
+	c.Stop = configuration.Stop
+	c.MaxTokens = configuration.MaxTokens
+	c.PresencePenalty = configuration.PresencePenalty
+	c.FrequencyPenalty = configuration.FrequencyPenalty
+	c.ContextStrategy = configuration.ContextStrategy
+	c.Tools = configuration.Tools
+	c.ResponseSchema = configuration.ResponseSchema
+	c.InitialSystemMessage = assistant.SystemMessage
+	return c
+}
+
+func (c ConfigurationInput) isModelAllowed(model string) bool {
+	if model == c.Model {
+		return true
+	}
+	for _, allowed := range c.AllowedModels {
+		if allowed == model {
+			return true
+		}
+	}
+	return false
+}
+
+// isToolAllowed only lets clients pick among the configured tools.
+func (c ConfigurationInput) isToolAllowed(tool string) bool {
+	for _, allowed := range c.Tools {
+		if allowed == tool {
+			return true
+		}
+	}
+	return false
+}
+
+func (c ConfigurationInput) maxTokensLimit() int {
+	if c.MaxTokensLimit > 0 {
+		return c.MaxTokensLimit
+	}
+	return c.MaxTokens
+}
+
+// ConfigurationStore holds the server configuration, replaced on reload.
+type ConfigurationStore struct {
+	mu     sync.RWMutex
+	config ConfigurationInput
+}
+
+func NewConfigurationStore(config ConfigurationInput) *ConfigurationStore {
+	return &ConfigurationStore{config: config}
+}
+
+func (s *ConfigurationStore) Load() ConfigurationInput {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+func (s *ConfigurationStore) Store(config ConfigurationInput) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = config
+}
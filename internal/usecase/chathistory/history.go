@@ -0,0 +1,169 @@
+package chathistory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+var ErrChatNotFound = errors.New("chat not found")
+
+type ChatHistoryInput struct {
+	ChatID string `json:"chat_id"`
+	UserID string `json:"user_id"`
+}
+
+type ChatHistoryToolCallOutput struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type ChatHistoryContentPartOutput struct {
+	Type         string `json:"type"`
+	Text         string `json:"text,omitempty"`
+	URL          string `json:"url,omitempty"`
+	MediaType    string `json:"media_type,omitempty"`
+	Data         string `json:"data,omitempty"`
+	AttachmentID string `json:"attachment_id,omitempty"`
+	Detail       string `json:"detail,omitempty"`
+}
+
+type ChatHistoryMessageOutput struct {
+	ID           string                         `json:"id"`
+	Role         string                         `json:"role"`
+	Content      string                         `json:"content"`
+	FinishReason string                         `json:"finish_reason,omitempty"`
+	Pinned       bool                           `json:"pinned"`
+	Erased       bool                           `json:"erased"` // left the context window, the model no longer sees it
+	ParentID     string                         `json:"parent_id,omitempty"`
+	Branch       int                            `json:"branch"`
+	Active       bool                           `json:"active"` // part of the active branch
+	ToolCalls    []ChatHistoryToolCallOutput    `json:"tool_calls,omitempty"`
+	ToolCallID   string                         `json:"tool_call_id,omitempty"`  // call a tool message answers
+	ContentParts []ChatHistoryContentPartOutput `json:"content_parts,omitempty"` // text and images of a multimodal user message
+	CreatedAt    time.Time                      `json:"created_at"`
+}
+
+type ChatHistoryOutput struct {
+	ChatID       string                     `json:"chat_id"`
+	UserID       string                     `json:"user_id"`
+	AssistantID  string                     `json:"assistant_id,omitempty"`
+	Title        string                     `json:"title"`
+	Summary      string                     `json:"summary"`
+	Status       string                     `json:"status"`
+	Model        string                     `json:"model"`
+	Cost         float64                    `json:"cost"`
+	ActiveLeafID string                     `json:"active_leaf_id"`
+	Messages     []ChatHistoryMessageOutput `json:"messages"` // the active branch in order, then the other branches
+}
+
+type ChatHistoryUseCase struct {
+	ChatGateway gateway.ChatGateway
+}
+
+func NewChatHistoryUseCase(chatGateway gateway.ChatGateway) *ChatHistoryUseCase {
+	return &ChatHistoryUseCase{
+		ChatGateway: chatGateway,
+	}
+}
+
+func (uc *ChatHistoryUseCase) Execute(ctx context.Context, input ChatHistoryInput) (*ChatHistoryOutput, error) {
+	verr := &entity.ValidationError{Entity: "chat history"}
+	if input.ChatID == "" {
+		verr.Add("chat_id", "is empty")
+	}
+	if input.UserID == "" {
+		verr.Add("user_id", "is empty")
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	chat, err := uc.ChatGateway.FindByID(ctx, input.ChatID)
+	if err != nil {
+		if err.Error() == "chat not found" {
+			return nil, ErrChatNotFound
+		}
+		return nil, errors.New("error fetching chat: " + err.Error())
+	}
+	// other users chats are reported as missing so their ids do not leak
+	if chat.UserID != input.UserID {
+		return nil, ErrChatNotFound
+	}
+
+	erased := make(map[string]bool, len(chat.ErasedMessages))
+	for _, message := range chat.ErasedMessages {
+		erased[message.ID] = true
+	}
+	active, err := chat.PathTo(chat.ActiveLeafID)
+	if err != nil {
+		return nil, errors.New("error reading active branch: " + err.Error())
+	}
+
+	messages := make([]ChatHistoryMessageOutput, 0, len(active)+len(chat.InactiveMessages))
+	for _, message := range active {
+		output := toMessageOutput(message)
+		output.Erased = erased[message.ID]
+		output.Active = true
+		messages = append(messages, output)
+	}
+	inactive := make([]ChatHistoryMessageOutput, 0, len(chat.InactiveMessages))
+	for _, message := range chat.InactiveMessages {
+		inactive = append(inactive, toMessageOutput(message))
+	}
+	sort.SliceStable(inactive, func(i, j int) bool {
+		return inactive[i].CreatedAt.Before(inactive[j].CreatedAt)
+	})
+	messages = append(messages, inactive...)
+
+	return &ChatHistoryOutput{
+		ChatID:       chat.ID,
+		UserID:       chat.UserID,
+		AssistantID:  chat.AssistantID,
+		Title:        chat.Title,
+		Summary:      chat.Summary,
+		Status:       chat.Status,
+		Model:        chat.Configuration.Model.GetName(),
+		Cost:         chat.Cost,
+		ActiveLeafID: chat.ActiveLeafID,
+		Messages:     messages,
+	}, nil
+}
+
+func toMessageOutput(message *entity.Message) ChatHistoryMessageOutput {
+	output := ChatHistoryMessageOutput{
+		ID:           message.ID,
+		Role:         message.Role,
+		Content:      message.Content,
+		FinishReason: message.FinishReason,
+		Pinned:       message.Pinned,
+		ParentID:     message.ParentID,
+		Branch:       message.Branch,
+		ToolCallID:   message.ToolCallID,
+		CreatedAt:    message.CreatedAt,
+	}
+	for _, call := range message.ToolCalls {
+		output.ToolCalls = append(output.ToolCalls, ChatHistoryToolCallOutput{
+			ID:        call.ID,
+			Name:      call.Name,
+			Arguments: call.Arguments,
+		})
+	}
+	for _, part := range message.Parts {
+		output.ContentParts = append(output.ContentParts, ChatHistoryContentPartOutput{
+			Type:         part.Type,
+			Text:         part.Text,
+			URL:          part.URL,
+			MediaType:    part.MediaType,
+			Data:         part.Data,
+			AttachmentID: part.AttachmentID,
+			Detail:       part.Detail,
+		})
+	}
+	return output
+}
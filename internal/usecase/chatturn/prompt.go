@@ -0,0 +1,250 @@
+package chatturn
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Messages converts the prompt of the chat, with the retrieved document
+// chunks before the last user message.
+func Messages(chat *entity.Chat, attachments map[string]string, sources []entity.ScoredChunk) []openai.ChatCompletionMessage {
+	return withDocuments(toOpenAIMessages(chat.PromptMessages(), attachments), sources)
+}
+
+// ResponseSchema returns the schema the reply must follow, if any. A schema
+// sent with the request replaces the one of the chat.
+func ResponseSchema(chat *entity.Chat, requested json.RawMessage) (*entity.ResponseSchema, error) {
+	raw := chat.Configuration.ResponseSchema
+	if len(requested) > 0 {
+		raw = requested
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	verr := &entity.ValidationError{Entity: "chat completion"}
+	schema, err := entity.NewResponseSchema(raw)
+	if err != nil {
+		verr.Add("response_schema", err.Error())
+	} else if chat.Configuration.N > 1 {
+		verr.Add("response_schema", "cannot be used in chats generating more than one choice")
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// SchemaInstruction describes the expected document, JSON mode only makes
+// the reply parse.
+func SchemaInstruction(schema *entity.ResponseSchema) openai.ChatCompletionMessage {
+	return openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleSystem,
+		Content: "Reply with a single JSON document that matches this JSON schema: " + string(schema.Raw),
+	}
+}
+
+// Correction sends back a reply that did not match the response schema.
+func Correction(content string, err error) []openai.ChatCompletionMessage {
+	var messages []openai.ChatCompletionMessage
+	if content != "" {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleAssistant,
+			Content: content,
+		})
+	}
+	return append(messages, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: "Your reply does not match the JSON schema (" + err.Error() + "). Reply again with only the corrected JSON document.",
+	})
+}
+
+// Retrieve finds the chunks of the chat documents closest to the last user
+// message. Failures are only logged.
+func (t *Turns) Retrieve(ctx context.Context, chat *entity.Chat) []entity.ScoredChunk {
+	k := chat.Configuration.RetrievalTopK
+	question := chat.LastUserMessage()
+	if k <= 0 || question == nil {
+		return nil
+	}
+	chunks, err := t.DocumentGateway.FindChunksByChatID(ctx, chat.ID)
+	if err != nil {
+		log.Println("error loading documents of chat " + chat.ID + ": " + err.Error())
+		return nil
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+	embeddings, err := t.EmbeddingGateway.Embed(ctx, []string{question.Content})
+	if err != nil || len(embeddings) == 0 {
+		log.Println("error embedding question of chat " + chat.ID + ": " + fmt.Sprint(err))
+		return nil
+	}
+	return entity.TopChunks(chunks, embeddings[0], k)
+}
+
+func withDocuments(messages []openai.ChatCompletionMessage, sources []entity.ScoredChunk) []openai.ChatCompletionMessage {
+	if len(sources) == 0 {
+		return messages
+	}
+
+	var excerpts strings.Builder
+	excerpts.WriteString("Excerpts of the documents attached to this chat follow, each after its source. " +
+		"Use them when they help to answer and cite the sources you used in square brackets, e.g. [" + sources[0].Chunk.Source() + "].")
+	for _, source := range sources {
+		excerpts.WriteString("\n\n[" + source.Chunk.Source() + "]\n" + source.Chunk.Content)
+	}
+	documents := openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleSystem,
+		Content: excerpts.String(),
+	}
+
+	last := len(messages)
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == openai.ChatMessageRoleUser {
+			last = i
+			break
+		}
+	}
+	with := make([]openai.ChatCompletionMessage, 0, len(messages)+1)
+	with = append(with, messages[:last]...)
+	with = append(with, documents)
+	return append(with, messages[last:]...)
+}
+
+func toOpenAIMessages(messages []*entity.Message, attachments map[string]string) []openai.ChatCompletionMessage {
+	converted := make([]openai.ChatCompletionMessage, 0, len(messages))
+	for _, msg := range messages {
+		message := openai.ChatCompletionMessage{
+			Role:       msg.Role,
+			Content:    msg.Content,
+			ToolCallID: msg.ToolCallID,
+		}
+		if len(msg.Parts) > 0 {
+			// the request is rejected when both Content and MultiContent are set
+			message.Content = ""
+			message.MultiContent = toOpenAIParts(attachments, msg.Parts)
+		}
+		for _, call := range msg.ToolCalls {
+			message.ToolCalls = append(message.ToolCalls, openai.ToolCall{
+				ID:   call.ID,
+				Type: openai.ToolTypeFunction,
+				Function: openai.FunctionCall{
+					Name:      call.Name,
+					Arguments: call.Arguments,
+				},
+			})
+		}
+		converted = append(converted, message)
+	}
+	return converted
+}
+
+func toContentParts(parts []ContentPartInput) []entity.ContentPart {
+	converted := make([]entity.ContentPart, 0, len(parts))
+	for _, part := range parts {
+		converted = append(converted, entity.ContentPart{
+			Type:         part.Type,
+			Text:         part.Text,
+			URL:          part.URL,
+			MediaType:    part.MediaType,
+			Data:         part.Data,
+			AttachmentID: part.AttachmentID,
+			Detail:       part.Detail,
+		})
+	}
+	return converted
+}
+
+// toOpenAIParts sends attachments as data URLs, the ones that could not be
+// loaded are replaced by a note.
+func toOpenAIParts(attachments map[string]string, parts []entity.ContentPart) []openai.ChatMessagePart {
+	converted := make([]openai.ChatMessagePart, 0, len(parts))
+	for _, part := range parts {
+		url := part.ImageURL()
+		if part.Type == entity.ContentPartAttachment {
+			url = attachments[part.AttachmentID]
+		}
+		if !part.IsImage() || url == "" {
+			text := part.Text
+			if part.IsImage() {
+				text = "[image attachment " + part.AttachmentID + " is no longer available]"
+			}
+			converted = append(converted, openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeText,
+				Text: text,
+			})
+			continue
+		}
+		converted = append(converted, openai.ChatMessagePart{
+			Type: openai.ChatMessagePartTypeImageURL,
+			ImageURL: &openai.ChatMessageImageURL{
+				URL:    url,
+				Detail: openai.ImageURLDetail(part.Detail),
+			},
+		})
+	}
+	return converted
+}
+
+// referenceAttachments fills the media type of the attachment parts, which
+// must reference attachments uploaded to the chat.
+func (t *Turns) referenceAttachments(ctx context.Context, chat *entity.Chat, parts []entity.ContentPart) ([]entity.ContentPart, error) {
+	verr := &entity.ValidationError{Entity: "message"}
+	for i, part := range parts {
+		if part.Type != entity.ContentPartAttachment || part.AttachmentID == "" {
+			continue
+		}
+		attachment, err := t.AttachmentGateway.FindByID(ctx, part.AttachmentID)
+		if err != nil && err.Error() != "attachment not found" {
+			return nil, errors.New("error fetching attachment: " + err.Error())
+		}
+		if err != nil || attachment.ChatID != chat.ID || attachment.UserID != chat.UserID {
+			verr.Add(fmt.Sprintf("content[%d].attachment_id", i), fmt.Sprintf("unknown attachment %q", part.AttachmentID))
+			continue
+		}
+		parts[i].MediaType = attachment.ContentType
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+// LoadAttachments reads the images of the prompt as data URLs indexed by
+// attachment id.
+func (t *Turns) LoadAttachments(ctx context.Context, chat *entity.Chat) map[string]string {
+	attachments := map[string]string{}
+	for _, message := range chat.PromptMessages() {
+		for _, part := range message.Parts {
+			if part.Type != entity.ContentPartAttachment {
+				continue
+			}
+			if _, ok := attachments[part.AttachmentID]; ok {
+				continue
+			}
+			attachments[part.AttachmentID] = ""
+
+			attachment, err := t.AttachmentGateway.FindByID(ctx, part.AttachmentID)
+			if err != nil || attachment.ChatID != chat.ID || attachment.UserID != chat.UserID {
+				log.Println("error loading attachment " + part.AttachmentID + " of chat " + chat.ID)
+				continue
+			}
+			content, err := t.StorageGateway.Get(ctx, attachment.StorageKey)
+			if err != nil {
+				log.Println("error reading attachment " + attachment.ID + ": " + err.Error())
+				continue
+			}
+			attachments[attachment.ID] = "data:" + attachment.ContentType + ";base64," + base64.StdEncoding.EncodeToString(content)
+		}
+	}
+	return attachments
+}
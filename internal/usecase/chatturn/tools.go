@@ -0,0 +1,85 @@
+package chatturn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/sashabaranov/go-openai"
+)
+
+// ToolDefinitions describes the tools of the chat that are still registered.
+func (t *Turns) ToolDefinitions(ctx context.Context, chat *entity.Chat) []openai.Tool {
+	var tools []openai.Tool
+	for _, name := range chat.Configuration.Tools {
+		tool, err := t.ToolGateway.FindByName(ctx, name)
+		if err != nil {
+			log.Println("error loading tool " + name + " of chat " + chat.ID + ": " + err.Error())
+			continue
+		}
+		tools = append(tools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+	return tools
+}
+
+func ToolCalls(calls []openai.ToolCall) []entity.ToolCall {
+	converted := make([]entity.ToolCall, 0, len(calls))
+	for _, call := range calls {
+		converted = append(converted, entity.ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+	return converted
+}
+
+// CallTools adds the tool call message and the result of every call to the
+// chat. Failed calls are answered with the error.
+func (t *Turns) CallTools(ctx context.Context, chat *entity.Chat, content string, calls []entity.ToolCall) (*entity.Message, error) {
+	assistant, err := entity.NewToolCallMessage(content, calls, chat.Configuration.Model)
+	if err != nil {
+		return nil, errors.New("error creating tool call message: " + err.Error())
+	}
+	if err := chat.AddMessage(assistant); err != nil {
+		return nil, fmt.Errorf("error adding tool call message: %w", err)
+	}
+
+	for _, call := range calls {
+		result, err := entity.NewToolResultMessage(call.ID, t.runTool(ctx, chat, call), chat.Configuration.Model)
+		if err != nil {
+			return nil, errors.New("error creating tool result message: " + err.Error())
+		}
+		if err := chat.AddMessage(result); err != nil {
+			return nil, fmt.Errorf("error adding tool result message: %w", err)
+		}
+	}
+	return assistant, nil
+}
+
+func (t *Turns) runTool(ctx context.Context, chat *entity.Chat, call entity.ToolCall) string {
+	if !chat.Configuration.HasTool(call.Name) {
+		return "error: tool " + call.Name + " is not enabled"
+	}
+	tool, err := t.ToolGateway.FindByName(ctx, call.Name)
+	if err != nil {
+		return "error: tool " + call.Name + " is not available"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, toolTimeout)
+	defer cancel()
+	output, err := tool.Handler(ctx, call.Arguments)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return output
+}
@@ -0,0 +1,358 @@
+package chatturn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+	"github.com/gabrielmq/chat-service/internal/usecase/chatconfig"
+)
+
+const (
+	saveTimeout     = 5 * time.Second
+	describeTimeout = 30 * time.Second
+	toolTimeout     = 30 * time.Second
+)
+
+var ErrChatNotFound = errors.New("chat not found")
+
+// ContentPartInput is a part of a multimodal user message: text, an image
+// url, a base64 image with its media_type or an image uploaded to the chat.
+type ContentPartInput struct {
+	Type         string `json:"type"`
+	Text         string `json:"text,omitempty"`
+	URL          string `json:"url,omitempty"`
+	MediaType    string `json:"media_type,omitempty"`
+	Data         string `json:"data,omitempty"`
+	AttachmentID string `json:"attachment_id,omitempty"`
+	Detail       string `json:"detail,omitempty"`
+}
+
+// HistoryInput is an earlier turn of a conversation held by the client, a
+// new chat starts with it.
+type HistoryInput struct {
+	Role    string
+	Content string
+}
+
+// Input is the part of a completion request that picks the chat and the
+// user turn, the same for both completion use cases.
+type Input struct {
+	ChatID            string
+	UserID            string
+	UserMessage       string
+	UserContent       []ContentPartInput
+	History           []HistoryInput
+	PinMessage        bool
+	AssistantID       string
+	ParentMessageID   string
+	Regenerate        bool
+	ResponseSchema    json.RawMessage
+	Template          string
+	TemplateVersion   int
+	TemplateVariables map[string]string
+	Overrides         *chatconfig.OverridesInput
+	Configuration     chatconfig.ConfigurationInput
+}
+
+func (input Input) Validate() error {
+	verr := &entity.ValidationError{Entity: "chat completion"}
+	if len(input.ResponseSchema) > 0 {
+		if _, err := entity.NewResponseSchema(input.ResponseSchema); err != nil {
+			verr.Add("response_schema", err.Error())
+		}
+	}
+	if len(input.UserContent) > 0 && input.UserMessage != "" {
+		verr.Add("user_content", "must not be sent with user_message")
+	}
+	for i, turn := range input.History {
+		if turn.Role != "user" && turn.Role != "assistant" {
+			verr.Add(fmt.Sprintf("history[%d].role", i), "must be user or assistant")
+		}
+	}
+	return verr.Err()
+}
+
+// Turns prepares the turns of the completion use cases and stores them.
+type Turns struct {
+	ChatGateway       gateway.ChatGateway
+	ModelGateway      gateway.ModelGateway
+	SummaryGateway    gateway.SummaryGateway
+	ToolGateway       gateway.ToolGateway
+	DocumentGateway   gateway.DocumentGateway
+	EmbeddingGateway  gateway.EmbeddingGateway
+	AttachmentGateway gateway.AttachmentGateway
+	StorageGateway    gateway.AttachmentStorageGateway
+	TemplateGateway   gateway.PromptTemplateGateway
+	AssistantGateway  gateway.AssistantGateway
+	describing        sync.WaitGroup
+}
+
+// Chat returns the chat of the user named by the input, creating it when it
+// does not exist yet.
+func (t *Turns) Chat(ctx context.Context, input Input) (*entity.Chat, error) {
+	chat, err := t.ChatGateway.FindByID(ctx, input.ChatID)
+	if err != nil {
+		if err.Error() != "chat not found" {
+			return nil, errors.New("error fetching existing chat: " + err.Error())
+		}
+		if input.Regenerate {
+			return nil, ErrChatNotFound
+		}
+		chat, err = t.createNewChat(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("error creating new chat: %w", err)
+		}
+		if err = t.ChatGateway.Create(ctx, chat); err != nil {
+			return nil, errors.New("error persisting new chat: " + err.Error())
+		}
+		return chat, nil
+	}
+
+	if chat.UserID != input.UserID {
+		// other users chats are reported as missing so their ids do not leak
+		return nil, ErrChatNotFound
+	}
+	// chats whose model left the registry keep the stored settings
+	if model, err := t.ModelGateway.FindByName(ctx, chat.Configuration.Model.GetName()); err == nil {
+		chat.Configuration.Model = model
+	}
+	return chat, nil
+}
+
+func (t *Turns) createNewChat(ctx context.Context, input Input) (*entity.Chat, error) {
+	// the overrides apply on top of the assistant settings
+	config := input.Configuration
+	if input.AssistantID != "" {
+		assistantConfig, err := t.withAssistant(ctx, config, input.AssistantID)
+		if err != nil {
+			return nil, err
+		}
+		config = assistantConfig
+	}
+	config, err := config.WithOverrides(input.Overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	verr := &entity.ValidationError{Entity: "chat configuration"}
+	model, err := t.ModelGateway.FindByName(ctx, config.Model)
+	if err != nil {
+		verr.Add("model", fmt.Sprintf("unknown model %q", config.Model))
+	}
+	for _, name := range config.Tools {
+		if _, err := t.ToolGateway.FindByName(ctx, name); err != nil {
+			verr.Add("tools", fmt.Sprintf("unknown tool %q", name))
+		}
+	}
+	if len(input.ResponseSchema) > 0 && config.N > 1 {
+		verr.Add("n", "must be 1 when a response schema is set")
+	}
+	systemMessage, err := t.systemMessage(ctx, input, config, verr)
+	if err != nil {
+		return nil, err
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+	chatConfiguration := &entity.ChatConfiguration{
+		Temperature:      config.Temperature,
+		TopP:             config.TopP,
+		N:                config.N,
+		Stop:             config.Stop,
+		MaxTokens:        config.MaxTokens,
+		PresencePenalty:  config.PresencePenalty,
+		FrequencyPenalty: config.FrequencyPenalty,
+		ContextStrategy:  config.ContextStrategy,
+		Tools:            config.Tools,
+		ResponseSchema:   config.ResponseSchema,
+		RetrievalTopK:    config.RetrievalTopK,
+		Model:            model,
+	}
+
+	initialMessage, err := entity.NewMessage("system", systemMessage, model)
+	if err != nil {
+		return nil, errors.New("error creating initial message: " + err.Error())
+	}
+	chat, err := entity.NewChat(input.UserID, initialMessage, chatConfiguration)
+	if err != nil {
+		return nil, fmt.Errorf("error creating new chat: %w", err)
+	}
+	chat.AssistantID = input.AssistantID
+	for _, turn := range input.History {
+		message, err := entity.NewMessage(turn.Role, turn.Content, model)
+		if err != nil {
+			return nil, errors.New("error creating history message: " + err.Error())
+		}
+		if err := chat.AddMessage(message); err != nil {
+			return nil, fmt.Errorf("error adding history message: %w", err)
+		}
+	}
+	return chat, nil
+}
+
+func (t *Turns) withAssistant(ctx context.Context, config chatconfig.ConfigurationInput, assistantID string) (chatconfig.ConfigurationInput, error) {
+	assistant, err := t.AssistantGateway.FindByID(ctx, assistantID)
+	if err != nil {
+		if err.Error() != "assistant not found" {
+			return config, errors.New("error fetching assistant: " + err.Error())
+		}
+		verr := &entity.ValidationError{Entity: "chat completion"}
+		verr.Add("assistant_id", fmt.Sprintf("unknown assistant %q", assistantID))
+		return config, verr
+	}
+	return config.WithAssistant(assistant), nil
+}
+
+// systemMessage renders the template named by the input, problems with it
+// are added to verr.
+func (t *Turns) systemMessage(ctx context.Context, input Input, config chatconfig.ConfigurationInput, verr *entity.ValidationError) (string, error) {
+	if input.Template == "" {
+		return config.InitialSystemMessage, nil
+	}
+	if input.Overrides != nil && input.Overrides.SystemMessage != nil && *input.Overrides.SystemMessage != "" {
+		verr.Add("template", "must not be sent with system_message")
+		return "", nil
+	}
+
+	var template *entity.PromptTemplate
+	var err error
+	if input.TemplateVersion > 0 {
+		template, err = t.TemplateGateway.FindByNameAndVersion(ctx, input.Template, input.TemplateVersion)
+	} else {
+		template, err = t.TemplateGateway.FindByName(ctx, input.Template)
+	}
+	if err != nil {
+		if err.Error() != "prompt template not found" {
+			return "", errors.New("error fetching template: " + err.Error())
+		}
+		if input.TemplateVersion > 0 {
+			verr.Add("template_version", fmt.Sprintf("unknown version %d of template %q", input.TemplateVersion, input.Template))
+		} else {
+			verr.Add("template", fmt.Sprintf("unknown template %q", input.Template))
+		}
+		return "", nil
+	}
+
+	rendered, err := template.Render(input.TemplateVariables)
+	if err != nil {
+		verr.Add("template_variables", err.Error())
+		return "", nil
+	}
+	return rendered, nil
+}
+
+// AddUserMessage adds the user message of the input to the chat, or discards
+// the last reply when regenerating.
+func (t *Turns) AddUserMessage(ctx context.Context, chat *entity.Chat, input Input) error {
+	if input.Regenerate {
+		if _, err := chat.DiscardLastReply(); err != nil {
+			return fmt.Errorf("error regenerating reply: %w", err)
+		}
+		return nil
+	}
+
+	if input.ParentMessageID != "" {
+		if err := chat.SwitchBranch(input.ParentMessageID); err != nil {
+			return fmt.Errorf("error switching branch: %w", err)
+		}
+	}
+
+	var userMessage *entity.Message
+	var err error
+	if len(input.UserContent) > 0 {
+		parts, err := t.referenceAttachments(ctx, chat, toContentParts(input.UserContent))
+		if err != nil {
+			return err
+		}
+		userMessage, err = entity.NewMultimodalMessage(parts, chat.Configuration.Model)
+		if err != nil {
+			return fmt.Errorf("error creating new message: %w", err)
+		}
+	} else {
+		userMessage, err = entity.NewMessage("user", input.UserMessage, chat.Configuration.Model)
+		if err != nil {
+			return errors.New("error creating new message: " + err.Error())
+		}
+	}
+	userMessage.Pinned = input.PinMessage
+	if err := chat.AddMessage(userMessage); err != nil {
+		return fmt.Errorf("error adding new message: %w", err)
+	}
+	return nil
+}
+
+// Failed saves the turn of a completion that cannot be answered and returns
+// err.
+func (t *Turns) Failed(chat *entity.Chat, err error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), saveTimeout)
+	defer cancel()
+	if saveErr := t.ChatGateway.Save(ctx, chat); saveErr != nil {
+		return errors.Join(err, errors.New("error saving chat: "+saveErr.Error()))
+	}
+	return err
+}
+
+// Summarize folds the messages erased by a summarizing strategy into the chat
+// summary. Failures are only logged.
+func (t *Turns) Summarize(ctx context.Context, chat *entity.Chat) {
+	if len(chat.PendingSummary) == 0 {
+		return
+	}
+
+	summary, err := t.SummaryGateway.Summarize(ctx, chat.Configuration.Model, chat.Summary, chat.PendingSummary)
+	if err != nil {
+		log.Println("error summarizing chat " + chat.ID + ": " + err.Error())
+		return
+	}
+	chat.SetSummary(summary)
+}
+
+// Describe titles the chat after its first exchange and updates its running
+// summary in the background. Failures are only logged.
+func (t *Turns) Describe(chat *entity.Chat, runningSummary bool) {
+	t.describing.Add(1)
+	go func() {
+		defer t.describing.Done()
+		t.describe(chat, runningSummary)
+	}()
+}
+
+// Wait returns once the chats being described are saved.
+func (t *Turns) Wait() {
+	t.describing.Wait()
+}
+
+func (t *Turns) describe(chat *entity.Chat, runningSummary bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), describeTimeout)
+	defer cancel()
+
+	if exchange := chat.FirstExchange(); chat.Title == "" && exchange != nil {
+		title, err := t.SummaryGateway.Title(ctx, chat.Configuration.Model, exchange)
+		if err != nil {
+			log.Println("error titling chat " + chat.ID + ": " + err.Error())
+		} else if err = t.ChatGateway.UpdateTitle(ctx, chat.ID, title); err != nil {
+			log.Println("error saving title of chat " + chat.ID + ": " + err.Error())
+		}
+	}
+
+	// summarizing strategies keep the summary of the erased messages instead
+	exchange := chat.LastExchange()
+	if !runningSummary || chat.Configuration.Strategy().Summarizes() || exchange == nil {
+		return
+	}
+	summary, err := t.SummaryGateway.Summarize(ctx, chat.Configuration.Model, chat.Summary, exchange)
+	if err != nil {
+		log.Println("error summarizing chat " + chat.ID + ": " + err.Error())
+		return
+	}
+	if err = t.ChatGateway.UpdateSummary(ctx, chat.ID, summary); err != nil {
+		log.Println("error saving summary of chat " + chat.ID + ": " + err.Error())
+	}
+}
@@ -0,0 +1,101 @@
+package commitchoice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+var ErrChatNotFound = errors.New("chat not found")
+
+type CommitChoiceInput struct {
+	ChatID    string `json:"chat_id"`
+	UserID    string `json:"user_id"`
+	MessageID string `json:"message_id"`
+}
+
+type CommitChoiceOutput struct {
+	ChatID       string `json:"chat_id"`
+	ActiveLeafID string `json:"active_leaf_id"`
+	Content      string `json:"content"`
+}
+
+// CommitChoiceUseCase keeps one of the candidates of a completion generated
+// with n greater than 1 as the reply in the chat history.
+type CommitChoiceUseCase struct {
+	ChatGateway    gateway.ChatGateway
+	ModelGateway   gateway.ModelGateway
+	SummaryGateway gateway.SummaryGateway
+}
+
+func NewCommitChoiceUseCase(chatGateway gateway.ChatGateway, modelGateway gateway.ModelGateway, summaryGateway gateway.SummaryGateway) *CommitChoiceUseCase {
+	return &CommitChoiceUseCase{
+		ChatGateway:    chatGateway,
+		ModelGateway:   modelGateway,
+		SummaryGateway: summaryGateway,
+	}
+}
+
+func (uc *CommitChoiceUseCase) Execute(ctx context.Context, input CommitChoiceInput) (*CommitChoiceOutput, error) {
+	verr := &entity.ValidationError{Entity: "choice"}
+	if input.ChatID == "" {
+		verr.Add("chat_id", "is empty")
+	}
+	if input.MessageID == "" {
+		verr.Add("message_id", "is empty")
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	chat, err := uc.ChatGateway.FindByID(ctx, input.ChatID)
+	if err != nil {
+		if err.Error() == "chat not found" {
+			return nil, ErrChatNotFound
+		}
+		return nil, errors.New("error fetching chat: " + err.Error())
+	}
+	// other users chats are reported as missing so their ids do not leak
+	if chat.UserID != input.UserID {
+		return nil, ErrChatNotFound
+	}
+	if model, err := uc.ModelGateway.FindByName(ctx, chat.Configuration.Model.GetName()); err == nil {
+		chat.Configuration.Model = model
+	}
+
+	err = chat.CommitChoice(input.MessageID)
+	if err != nil {
+		return nil, fmt.Errorf("error committing choice: %w", err)
+	}
+	uc.summarize(ctx, chat)
+
+	err = uc.ChatGateway.Save(ctx, chat)
+	if err != nil {
+		return nil, errors.New("error saving chat: " + err.Error())
+	}
+	return &CommitChoiceOutput{
+		ChatID:       chat.ID,
+		ActiveLeafID: chat.ActiveLeafID,
+		Content:      chat.Messages[len(chat.Messages)-1].Content,
+	}, nil
+}
+
+// summarize folds the messages the chosen candidate pushed out of the
+// context window into the summary. Without it they are only missing from
+// the prompt, so a failure is logged and the choice is still committed.
+func (uc *CommitChoiceUseCase) summarize(ctx context.Context, chat *entity.Chat) {
+	if len(chat.PendingSummary) == 0 {
+		return
+	}
+
+	summary, err := uc.SummaryGateway.Summarize(ctx, chat.Configuration.Model, chat.Summary, chat.PendingSummary)
+	if err != nil {
+		log.Println("error summarizing chat " + chat.ID + ": " + err.Error())
+		return
+	}
+	chat.SetSummary(summary)
+}
@@ -0,0 +1,43 @@
+package deleteassistant
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+var ErrAssistantNotFound = errors.New("assistant not found")
+
+type DeleteAssistantInput struct {
+	AssistantID string
+}
+
+type DeleteAssistantOutput struct {
+	AssistantID string `json:"assistant_id"`
+}
+
+type DeleteAssistantUseCase struct {
+	AssistantGateway gateway.AssistantGateway
+}
+
+func NewDeleteAssistantUseCase(assistantGateway gateway.AssistantGateway) *DeleteAssistantUseCase {
+	return &DeleteAssistantUseCase{
+		AssistantGateway: assistantGateway,
+	}
+}
+
+// Execute removes the assistant, the chats created with it hold a copy of
+// its settings and go on unchanged.
+func (uc *DeleteAssistantUseCase) Execute(ctx context.Context, input DeleteAssistantInput) (*DeleteAssistantOutput, error) {
+	err := uc.AssistantGateway.Delete(ctx, input.AssistantID)
+	if err != nil {
+		if err.Error() == "assistant not found" {
+			return nil, ErrAssistantNotFound
+		}
+		return nil, errors.New("error deleting assistant: " + err.Error())
+	}
+	return &DeleteAssistantOutput{
+		AssistantID: input.AssistantID,
+	}, nil
+}
@@ -0,0 +1,45 @@
+package deletetemplate
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+var ErrTemplateNotFound = errors.New("prompt template not found")
+
+type DeleteTemplateInput struct {
+	Name string
+}
+
+type DeleteTemplateOutput struct {
+	Name     string `json:"name"`
+	Versions int    `json:"versions"` // versions removed
+}
+
+type DeleteTemplateUseCase struct {
+	PromptTemplateGateway gateway.PromptTemplateGateway
+}
+
+func NewDeleteTemplateUseCase(promptTemplateGateway gateway.PromptTemplateGateway) *DeleteTemplateUseCase {
+	return &DeleteTemplateUseCase{
+		PromptTemplateGateway: promptTemplateGateway,
+	}
+}
+
+// Execute removes every version of the template. Chats created from it keep
+// their system message, only new chats can no longer name it.
+func (uc *DeleteTemplateUseCase) Execute(ctx context.Context, input DeleteTemplateInput) (*DeleteTemplateOutput, error) {
+	versions, err := uc.PromptTemplateGateway.DeleteByName(ctx, input.Name)
+	if err != nil {
+		if err.Error() == "prompt template not found" {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, errors.New("error deleting template: " + err.Error())
+	}
+	return &DeleteTemplateOutput{
+		Name:     input.Name,
+		Versions: versions,
+	}, nil
+}
@@ -0,0 +1,65 @@
+package downloadattachment
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+type DownloadAttachmentInput struct {
+	ChatID       string
+	AttachmentID string
+	UserID       string
+}
+
+type DownloadAttachmentOutput struct {
+	AttachmentID string
+	Name         string
+	ContentType  string
+	Content      []byte
+}
+
+type DownloadAttachmentUseCase struct {
+	AttachmentGateway        gateway.AttachmentGateway
+	AttachmentStorageGateway gateway.AttachmentStorageGateway
+}
+
+func NewDownloadAttachmentUseCase(attachmentGateway gateway.AttachmentGateway, attachmentStorageGateway gateway.AttachmentStorageGateway) *DownloadAttachmentUseCase {
+	return &DownloadAttachmentUseCase{
+		AttachmentGateway:        attachmentGateway,
+		AttachmentStorageGateway: attachmentStorageGateway,
+	}
+}
+
+func (uc *DownloadAttachmentUseCase) Execute(ctx context.Context, input DownloadAttachmentInput) (*DownloadAttachmentOutput, error) {
+	if input.AttachmentID == "" {
+		return nil, errors.New("attachment id is empty")
+	}
+
+	attachment, err := uc.AttachmentGateway.FindByID(ctx, input.AttachmentID)
+	if err != nil {
+		if err.Error() == "attachment not found" {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, errors.New("error fetching attachment: " + err.Error())
+	}
+	// attachments of other users or chats are reported as missing so their
+	// ids do not leak
+	if attachment.UserID != input.UserID || attachment.ChatID != input.ChatID {
+		return nil, ErrAttachmentNotFound
+	}
+
+	content, err := uc.AttachmentStorageGateway.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		return nil, errors.New("error reading attachment: " + err.Error())
+	}
+	return &DownloadAttachmentOutput{
+		AttachmentID: attachment.ID,
+		Name:         attachment.Name,
+		ContentType:  attachment.ContentType,
+		Content:      content,
+	}, nil
+}
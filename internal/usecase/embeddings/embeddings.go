@@ -0,0 +1,201 @@
+package embeddings
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+type EmbeddingsConfigurationInput struct {
+	Model          string   // used when the request names no model
+	AllowedModels  []string // models clients may pick besides Model
+	MaxInputs      int      // inputs accepted in one request, 0 means no limit
+	MaxTokensLimit int      // tokens accepted in one request summed over the inputs, 0 means no limit
+}
+
+// ConfigurationStore holds the embeddings limits, replaced when the service
+// configuration is reloaded.
+type ConfigurationStore struct {
+	mu     sync.RWMutex
+	config EmbeddingsConfigurationInput
+}
+
+func NewConfigurationStore(config EmbeddingsConfigurationInput) *ConfigurationStore {
+	return &ConfigurationStore{config: config}
+}
+
+func (s *ConfigurationStore) Load() EmbeddingsConfigurationInput {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+func (s *ConfigurationStore) Store(config EmbeddingsConfigurationInput) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = config
+}
+
+type EmbeddingsInput struct {
+	Model  string                       `json:"model"`
+	Inputs []string                     `json:"inputs"`
+	Config EmbeddingsConfigurationInput `json:"-"`
+}
+
+type EmbeddingsItemOutput struct {
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// EmbeddingsOutput reports the tokens of every input in PromptTokens, while
+// Cost only covers the inputs that were not found in the cache.
+type EmbeddingsOutput struct {
+	Model        string                 `json:"model"`
+	Data         []EmbeddingsItemOutput `json:"data"`
+	PromptTokens int                    `json:"prompt_tokens"`
+	CachedInputs int                    `json:"cached_inputs"`
+	Cost         float64                `json:"cost"`
+}
+
+type EmbeddingsUseCase struct {
+	ModelGateway          gateway.ModelGateway
+	EmbeddingGateway      gateway.EmbeddingGateway
+	EmbeddingCacheGateway gateway.EmbeddingCacheGateway
+}
+
+func NewEmbeddingsUseCase(modelGateway gateway.ModelGateway, embeddingGateway gateway.EmbeddingGateway, embeddingCacheGateway gateway.EmbeddingCacheGateway) *EmbeddingsUseCase {
+	return &EmbeddingsUseCase{
+		ModelGateway:          modelGateway,
+		EmbeddingGateway:      embeddingGateway,
+		EmbeddingCacheGateway: embeddingCacheGateway,
+	}
+}
+
+func (uc *EmbeddingsUseCase) Execute(ctx context.Context, input EmbeddingsInput) (*EmbeddingsOutput, error) {
+	model, tokens, err := uc.validate(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &EmbeddingsOutput{
+		Model: model.GetName(),
+		Data:  make([]EmbeddingsItemOutput, len(input.Inputs)),
+	}
+	// inputs repeated in the request are sent once
+	keys := make([]string, len(input.Inputs))
+	missing := map[string]bool{}
+	var pending []string
+	var pendingTokens int
+	for i, text := range input.Inputs {
+		output.PromptTokens += tokens[i]
+		keys[i] = cacheKey(model.GetName(), text)
+		if embedding, ok := uc.EmbeddingCacheGateway.Get(ctx, keys[i]); ok {
+			output.Data[i] = EmbeddingsItemOutput{Index: i, Embedding: embedding}
+			output.CachedInputs++
+			continue
+		}
+		if !missing[keys[i]] {
+			missing[keys[i]] = true
+			pending = append(pending, text)
+			pendingTokens += tokens[i]
+		}
+	}
+
+	if len(pending) > 0 {
+		embeddings, err := uc.EmbeddingGateway.EmbedWithModel(ctx, model.GetName(), pending)
+		if err != nil {
+			return nil, errors.New("error creating embeddings: " + err.Error())
+		}
+		if len(embeddings) != len(pending) {
+			return nil, fmt.Errorf("error creating embeddings: got %d embeddings for %d inputs", len(embeddings), len(pending))
+		}
+		computed := make(map[string][]float32, len(pending))
+		for i, text := range pending {
+			key := cacheKey(model.GetName(), text)
+			computed[key] = embeddings[i]
+			uc.EmbeddingCacheGateway.Set(ctx, key, embeddings[i])
+		}
+		for i, key := range keys {
+			if embedding, ok := computed[key]; ok && output.Data[i].Embedding == nil {
+				output.Data[i] = EmbeddingsItemOutput{Index: i, Embedding: embedding}
+			}
+		}
+		output.Cost = model.Cost(pendingTokens, 0)
+	}
+	return output, nil
+}
+
+// validate resolves the model and counts the tokens of every input, applying
+// the same model allowlist and token limit as the completions.
+func (uc *EmbeddingsUseCase) validate(ctx context.Context, input EmbeddingsInput) (*entity.Model, []int, error) {
+	verr := &entity.ValidationError{Entity: "embeddings request"}
+	name := input.Model
+	if name == "" {
+		name = input.Config.Model
+	}
+	if !input.Config.isModelAllowed(name) {
+		verr.Add("model", fmt.Sprintf("model %q is not allowed", name))
+		return nil, nil, verr.Err()
+	}
+	model, err := uc.ModelGateway.FindByName(ctx, name)
+	if err != nil {
+		verr.Add("model", fmt.Sprintf("unknown model %q", name))
+		return nil, nil, verr.Err()
+	}
+
+	if len(input.Inputs) == 0 {
+		verr.Add("inputs", "is empty")
+	} else if input.Config.MaxInputs > 0 && len(input.Inputs) > input.Config.MaxInputs {
+		verr.Add("inputs", fmt.Sprintf("must have at most %d inputs", input.Config.MaxInputs))
+	}
+	if err := verr.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	tokens := make([]int, len(input.Inputs))
+	total := 0
+	for i, text := range input.Inputs {
+		field := fmt.Sprintf("inputs[%d]", i)
+		if text == "" {
+			verr.Add(field, "is empty")
+			continue
+		}
+		tokens[i] = entity.CountTokens(model, text)
+		if tokens[i] > model.GetMaxTokens() {
+			verr.Add(field, fmt.Sprintf("has %d tokens, the model accepts %d", tokens[i], model.GetMaxTokens()))
+		}
+		total += tokens[i]
+	}
+	if input.Config.MaxTokensLimit > 0 && total > input.Config.MaxTokensLimit {
+		verr.Add("inputs", fmt.Sprintf("has %d tokens, must not exceed %d", total, input.Config.MaxTokensLimit))
+	}
+	if err := verr.Err(); err != nil {
+		return nil, nil, err
+	}
+	return model, tokens, nil
+}
+
+func (c EmbeddingsConfigurationInput) isModelAllowed(model string) bool {
+	if model == c.Model {
+		return true
+	}
+	for _, allowed := range c.AllowedModels {
+		if allowed == model {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheKey names an embedding by its model and the SHA-256 of the input, so
+// the cache does not keep the texts themselves.
+func cacheKey(model, input string) string {
+	sum := sha256.Sum256([]byte(input))
+	return model + ":" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,161 @@
+package forkchat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+var ErrChatNotFound = errors.New("chat not found")
+
+type ForkChatInput struct {
+	ChatID    string `json:"chat_id"`
+	UserID    string `json:"user_id"`    // owner of the chat, only they may fork it
+	OwnerID   string `json:"owner_id"`   // owner of the fork, defaults to UserID
+	MessageID string `json:"message_id"` // last message copied, defaults to the active leaf
+}
+
+type ForkChatOutput struct {
+	ChatID       string `json:"chat_id"`
+	SourceChatID string `json:"source_chat_id"`
+	UserID       string `json:"user_id"`
+	ActiveLeafID string `json:"active_leaf_id"`
+}
+
+// ForkChatUseCase copies a conversation into a new chat, of its owner or of a
+// user they share it with. The source chat is left untouched.
+type ForkChatUseCase struct {
+	ChatGateway       gateway.ChatGateway
+	ModelGateway      gateway.ModelGateway
+	SummaryGateway    gateway.SummaryGateway
+	AttachmentGateway gateway.AttachmentGateway
+}
+
+func NewForkChatUseCase(chatGateway gateway.ChatGateway, modelGateway gateway.ModelGateway, summaryGateway gateway.SummaryGateway, attachmentGateway gateway.AttachmentGateway) *ForkChatUseCase {
+	return &ForkChatUseCase{
+		ChatGateway:       chatGateway,
+		ModelGateway:      modelGateway,
+		SummaryGateway:    summaryGateway,
+		AttachmentGateway: attachmentGateway,
+	}
+}
+
+func (uc *ForkChatUseCase) Execute(ctx context.Context, input ForkChatInput) (*ForkChatOutput, error) {
+	if input.ChatID == "" {
+		verr := &entity.ValidationError{Entity: "fork"}
+		verr.Add("chat_id", "is empty")
+		return nil, verr
+	}
+
+	chat, err := uc.ChatGateway.FindByID(ctx, input.ChatID)
+	if err != nil {
+		if err.Error() == "chat not found" {
+			return nil, ErrChatNotFound
+		}
+		return nil, errors.New("error fetching chat: " + err.Error())
+	}
+	// only the owner shares a chat, other users chats are reported as missing
+	// so their ids do not leak
+	if chat.UserID != input.UserID {
+		return nil, ErrChatNotFound
+	}
+	if model, err := uc.ModelGateway.FindByName(ctx, chat.Configuration.Model.GetName()); err == nil {
+		chat.Configuration.Model = model
+	}
+
+	messageID := input.MessageID
+	if messageID == "" {
+		messageID = chat.ActiveLeafID
+	}
+	ownerID := input.OwnerID
+	if ownerID == "" {
+		ownerID = input.UserID
+	}
+	fork, err := chat.Fork(ownerID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("error forking chat: %w", err)
+	}
+	attachments, err := uc.copyAttachments(ctx, fork)
+	if err != nil {
+		return nil, err
+	}
+	uc.summarize(ctx, fork)
+
+	err = uc.ChatGateway.Create(ctx, fork)
+	if err != nil {
+		return nil, errors.New("error persisting forked chat: " + err.Error())
+	}
+	for _, attachment := range attachments {
+		if err := uc.AttachmentGateway.Create(ctx, attachment); err != nil {
+			return nil, errors.New("error persisting forked attachment: " + err.Error())
+		}
+	}
+	// Create only stores the initial system message
+	err = uc.ChatGateway.Save(ctx, fork)
+	if err != nil {
+		return nil, errors.New("error saving forked chat: " + err.Error())
+	}
+
+	return &ForkChatOutput{
+		ChatID:       fork.ID,
+		SourceChatID: chat.ID,
+		UserID:       fork.UserID,
+		ActiveLeafID: fork.ActiveLeafID,
+	}, nil
+}
+
+// copyAttachments points the messages of the fork to copies of their
+// attachments owned by the fork, which are returned to be stored once the
+// fork exists.
+func (uc *ForkChatUseCase) copyAttachments(ctx context.Context, fork *entity.Chat) ([]*entity.Attachment, error) {
+	copies := map[string]*entity.Attachment{}
+	var attachments []*entity.Attachment
+	for _, message := range fork.AllMessages() {
+		if len(message.Parts) == 0 {
+			continue
+		}
+		// the parts are still shared with the source chat
+		parts := append([]entity.ContentPart(nil), message.Parts...)
+		for i, part := range parts {
+			if part.Type != entity.ContentPartAttachment {
+				continue
+			}
+			copied, ok := copies[part.AttachmentID]
+			if !ok {
+				attachment, err := uc.AttachmentGateway.FindByID(ctx, part.AttachmentID)
+				if err != nil {
+					if err.Error() != "attachment not found" {
+						return nil, errors.New("error fetching attachment: " + err.Error())
+					}
+					continue
+				}
+				copied = attachment.CopyTo(fork.ID, fork.UserID)
+				copies[part.AttachmentID] = copied
+				attachments = append(attachments, copied)
+			}
+			parts[i].AttachmentID = copied.ID
+		}
+		message.Parts = parts
+	}
+	return attachments, nil
+}
+
+// summarize folds the messages the fork erased into its summary when the
+// context strategy summarizes. A failed summary only loses the context of
+// those messages, so it does not fail the fork.
+func (uc *ForkChatUseCase) summarize(ctx context.Context, chat *entity.Chat) {
+	if len(chat.PendingSummary) == 0 {
+		return
+	}
+
+	summary, err := uc.SummaryGateway.Summarize(ctx, chat.Configuration.Model, chat.Summary, chat.PendingSummary)
+	if err != nil {
+		log.Println("error summarizing chat " + chat.ID + ": " + err.Error())
+		return
+	}
+	chat.SetSummary(summary)
+}
@@ -0,0 +1,77 @@
+package getassistant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+var ErrAssistantNotFound = errors.New("assistant not found")
+
+type GetAssistantInput struct {
+	AssistantID string
+}
+
+type GetAssistantOutput struct {
+	AssistantID      string          `json:"assistant_id"`
+	Name             string          `json:"name"`
+	Description      string          `json:"description"`
+	SystemMessage    string          `json:"system_message"`
+	Model            string          `json:"model"`
+	Temperature      float32         `json:"temperature"`
+	TopP             float32         `json:"top_p"`
+	N                int             `json:"n"`
+	Stop             []string        `json:"stop"`
+	MaxTokens        int             `json:"max_tokens"`
+	PresencePenalty  float32         `json:"presence_penalty"`
+	FrequencyPenalty float32         `json:"frequency_penalty"`
+	ContextStrategy  string          `json:"context_strategy"`
+	Tools            []string        `json:"tools"`
+	ResponseSchema   json.RawMessage `json:"response_schema,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+type GetAssistantUseCase struct {
+	AssistantGateway gateway.AssistantGateway
+}
+
+func NewGetAssistantUseCase(assistantGateway gateway.AssistantGateway) *GetAssistantUseCase {
+	return &GetAssistantUseCase{
+		AssistantGateway: assistantGateway,
+	}
+}
+
+func (uc *GetAssistantUseCase) Execute(ctx context.Context, input GetAssistantInput) (*GetAssistantOutput, error) {
+	assistant, err := uc.AssistantGateway.FindByID(ctx, input.AssistantID)
+	if err != nil {
+		if err.Error() == "assistant not found" {
+			return nil, ErrAssistantNotFound
+		}
+		return nil, errors.New("error fetching assistant: " + err.Error())
+	}
+
+	configuration := assistant.Configuration
+	return &GetAssistantOutput{
+		AssistantID:      assistant.ID,
+		Name:             assistant.Name,
+		Description:      assistant.Description,
+		SystemMessage:    assistant.SystemMessage,
+		Model:            configuration.Model.GetName(),
+		Temperature:      configuration.Temperature,
+		TopP:             configuration.TopP,
+		N:                configuration.N,
+		Stop:             configuration.Stop,
+		MaxTokens:        configuration.MaxTokens,
+		PresencePenalty:  configuration.PresencePenalty,
+		FrequencyPenalty: configuration.FrequencyPenalty,
+		ContextStrategy:  configuration.Strategy().Name(),
+		Tools:            configuration.Tools,
+		ResponseSchema:   configuration.ResponseSchema,
+		CreatedAt:        assistant.CreatedAt,
+		UpdatedAt:        assistant.UpdatedAt,
+	}, nil
+}
@@ -0,0 +1,63 @@
+package gettemplate
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+var ErrTemplateNotFound = errors.New("prompt template not found")
+
+type GetTemplateInput struct {
+	Name    string
+	Version int // 0 returns the latest version
+}
+
+type GetTemplateOutput struct {
+	TemplateID  string    `json:"template_id"`
+	Name        string    `json:"name"`
+	Version     int       `json:"version"`
+	Description string    `json:"description"`
+	Content     string    `json:"content"`
+	Variables   []string  `json:"variables"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type GetTemplateUseCase struct {
+	PromptTemplateGateway gateway.PromptTemplateGateway
+}
+
+func NewGetTemplateUseCase(promptTemplateGateway gateway.PromptTemplateGateway) *GetTemplateUseCase {
+	return &GetTemplateUseCase{
+		PromptTemplateGateway: promptTemplateGateway,
+	}
+}
+
+func (uc *GetTemplateUseCase) Execute(ctx context.Context, input GetTemplateInput) (*GetTemplateOutput, error) {
+	var template *entity.PromptTemplate
+	var err error
+	if input.Version > 0 {
+		template, err = uc.PromptTemplateGateway.FindByNameAndVersion(ctx, input.Name, input.Version)
+	} else {
+		template, err = uc.PromptTemplateGateway.FindByName(ctx, input.Name)
+	}
+	if err != nil {
+		if err.Error() == "prompt template not found" {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, errors.New("error fetching template: " + err.Error())
+	}
+
+	return &GetTemplateOutput{
+		TemplateID:  template.ID,
+		Name:        template.Name,
+		Version:     template.Version,
+		Description: template.Description,
+		Content:     template.Content,
+		Variables:   template.Variables(),
+		CreatedAt:   template.CreatedAt,
+	}, nil
+}
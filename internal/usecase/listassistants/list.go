@@ -0,0 +1,54 @@
+package listassistants
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+type ListAssistantsItemOutput struct {
+	AssistantID string    `json:"assistant_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Model       string    `json:"model"`
+	Tools       []string  `json:"tools"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ListAssistantsOutput struct {
+	Assistants []ListAssistantsItemOutput `json:"assistants"`
+}
+
+type ListAssistantsUseCase struct {
+	AssistantGateway gateway.AssistantGateway
+}
+
+func NewListAssistantsUseCase(assistantGateway gateway.AssistantGateway) *ListAssistantsUseCase {
+	return &ListAssistantsUseCase{
+		AssistantGateway: assistantGateway,
+	}
+}
+
+func (uc *ListAssistantsUseCase) Execute(ctx context.Context) (*ListAssistantsOutput, error) {
+	assistants, err := uc.AssistantGateway.FindAll(ctx)
+	if err != nil {
+		return nil, errors.New("error fetching assistants: " + err.Error())
+	}
+
+	output := &ListAssistantsOutput{
+		Assistants: make([]ListAssistantsItemOutput, 0, len(assistants)),
+	}
+	for _, assistant := range assistants {
+		output.Assistants = append(output.Assistants, ListAssistantsItemOutput{
+			AssistantID: assistant.ID,
+			Name:        assistant.Name,
+			Description: assistant.Description,
+			Model:       assistant.Configuration.Model.GetName(),
+			Tools:       assistant.Configuration.Tools,
+			UpdatedAt:   assistant.UpdatedAt,
+		})
+	}
+	return output, nil
+}
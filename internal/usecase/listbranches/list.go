@@ -0,0 +1,88 @@
+package listbranches
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+var ErrChatNotFound = errors.New("chat not found")
+
+type ListBranchesInput struct {
+	ChatID string `json:"chat_id"`
+	UserID string `json:"user_id"`
+}
+
+type ListBranchesItemOutput struct {
+	LeafID    string    `json:"leaf_id"` // continue or switch to the branch through its last message
+	Branch    int       `json:"branch"`
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	Messages  int       `json:"messages"` // length of the path from the initial system message
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ListBranchesOutput struct {
+	ChatID       string                   `json:"chat_id"`
+	ActiveLeafID string                   `json:"active_leaf_id"`
+	Branches     []ListBranchesItemOutput `json:"branches"`
+}
+
+type ListBranchesUseCase struct {
+	ChatGateway gateway.ChatGateway
+}
+
+func NewListBranchesUseCase(chatGateway gateway.ChatGateway) *ListBranchesUseCase {
+	return &ListBranchesUseCase{
+		ChatGateway: chatGateway,
+	}
+}
+
+func (uc *ListBranchesUseCase) Execute(ctx context.Context, input ListBranchesInput) (*ListBranchesOutput, error) {
+	if input.ChatID == "" {
+		verr := &entity.ValidationError{Entity: "branches"}
+		verr.Add("chat_id", "is empty")
+		return nil, verr
+	}
+
+	chat, err := uc.ChatGateway.FindByID(ctx, input.ChatID)
+	if err != nil {
+		if err.Error() == "chat not found" {
+			return nil, ErrChatNotFound
+		}
+		return nil, errors.New("error fetching chat: " + err.Error())
+	}
+	// other users chats are reported as missing so their ids do not leak
+	if chat.UserID != input.UserID {
+		return nil, ErrChatNotFound
+	}
+
+	output := &ListBranchesOutput{
+		ChatID:       chat.ID,
+		ActiveLeafID: chat.ActiveLeafID,
+	}
+	for _, leaf := range chat.Leaves() {
+		path, err := chat.PathTo(leaf.ID)
+		if err != nil {
+			return nil, errors.New("error reading branch: " + err.Error())
+		}
+		output.Branches = append(output.Branches, ListBranchesItemOutput{
+			LeafID:    leaf.ID,
+			Branch:    leaf.Branch,
+			Role:      leaf.Role,
+			Content:   leaf.Content,
+			Messages:  len(path),
+			Active:    leaf.ID == chat.ActiveLeafID,
+			CreatedAt: leaf.CreatedAt,
+		})
+	}
+	sort.SliceStable(output.Branches, func(i, j int) bool {
+		return output.Branches[i].Branch < output.Branches[j].Branch
+	})
+	return output, nil
+}
@@ -0,0 +1,68 @@
+package listchats
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+type ListChatsInput struct {
+	UserID string `json:"user_id"`
+}
+
+type ListChatsItemOutput struct {
+	ChatID      string  `json:"chat_id"`
+	Title       string  `json:"title"`
+	Summary     string  `json:"summary"`
+	Status      string  `json:"status"`
+	Model       string  `json:"model"`
+	Cost        float64 `json:"cost"`
+	AssistantID string  `json:"assistant_id,omitempty"`
+}
+
+type ListChatsOutput struct {
+	UserID string                `json:"user_id"`
+	Chats  []ListChatsItemOutput `json:"chats"`
+}
+
+type ListChatsUseCase struct {
+	ChatGateway gateway.ChatGateway
+}
+
+func NewListChatsUseCase(chatGateway gateway.ChatGateway) *ListChatsUseCase {
+	return &ListChatsUseCase{
+		ChatGateway: chatGateway,
+	}
+}
+
+func (uc *ListChatsUseCase) Execute(ctx context.Context, input ListChatsInput) (*ListChatsOutput, error) {
+	if input.UserID == "" {
+		verr := &entity.ValidationError{Entity: "chats"}
+		verr.Add("user_id", "is empty")
+		return nil, verr
+	}
+
+	chats, err := uc.ChatGateway.FindByUserID(ctx, input.UserID)
+	if err != nil {
+		return nil, errors.New("error fetching chats: " + err.Error())
+	}
+
+	output := &ListChatsOutput{
+		UserID: input.UserID,
+		Chats:  make([]ListChatsItemOutput, 0, len(chats)),
+	}
+	for _, chat := range chats {
+		output.Chats = append(output.Chats, ListChatsItemOutput{
+			ChatID:      chat.ID,
+			Title:       chat.Title,
+			Summary:     chat.Summary,
+			Status:      chat.Status,
+			Model:       chat.Configuration.Model.GetName(),
+			Cost:        chat.Cost,
+			AssistantID: chat.AssistantID,
+		})
+	}
+	return output, nil
+}
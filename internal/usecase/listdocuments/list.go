@@ -0,0 +1,79 @@
+package listdocuments
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+var ErrChatNotFound = errors.New("chat not found")
+
+type ListDocumentsInput struct {
+	ChatID string `json:"chat_id"`
+	UserID string `json:"user_id"`
+}
+
+type ListDocumentsItemOutput struct {
+	DocumentID string    `json:"document_id"`
+	Name       string    `json:"name"`
+	Chunks     int       `json:"chunks"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ListDocumentsOutput struct {
+	ChatID    string                    `json:"chat_id"`
+	Documents []ListDocumentsItemOutput `json:"documents"`
+}
+
+type ListDocumentsUseCase struct {
+	ChatGateway     gateway.ChatGateway
+	DocumentGateway gateway.DocumentGateway
+}
+
+func NewListDocumentsUseCase(chatGateway gateway.ChatGateway, documentGateway gateway.DocumentGateway) *ListDocumentsUseCase {
+	return &ListDocumentsUseCase{
+		ChatGateway:     chatGateway,
+		DocumentGateway: documentGateway,
+	}
+}
+
+func (uc *ListDocumentsUseCase) Execute(ctx context.Context, input ListDocumentsInput) (*ListDocumentsOutput, error) {
+	if input.ChatID == "" {
+		verr := &entity.ValidationError{Entity: "documents"}
+		verr.Add("chat_id", "is empty")
+		return nil, verr
+	}
+
+	chat, err := uc.ChatGateway.FindByID(ctx, input.ChatID)
+	if err != nil {
+		if err.Error() == "chat not found" {
+			return nil, ErrChatNotFound
+		}
+		return nil, errors.New("error fetching chat: " + err.Error())
+	}
+	// other users chats are reported as missing so their ids do not leak
+	if chat.UserID != input.UserID {
+		return nil, ErrChatNotFound
+	}
+
+	documents, err := uc.DocumentGateway.FindByChatID(ctx, chat.ID)
+	if err != nil {
+		return nil, errors.New("error fetching documents: " + err.Error())
+	}
+	output := &ListDocumentsOutput{
+		ChatID:    chat.ID,
+		Documents: make([]ListDocumentsItemOutput, 0, len(documents)),
+	}
+	for _, document := range documents {
+		output.Documents = append(output.Documents, ListDocumentsItemOutput{
+			DocumentID: document.ID,
+			Name:       document.Name,
+			Chunks:     document.ChunkCount,
+			CreatedAt:  document.CreatedAt,
+		})
+	}
+	return output, nil
+}
@@ -0,0 +1,55 @@
+package listtemplates
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+type ListTemplatesItemOutput struct {
+	TemplateID  string    `json:"template_id"`
+	Name        string    `json:"name"`
+	Version     int       `json:"version"`
+	Description string    `json:"description"`
+	Variables   []string  `json:"variables"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ListTemplatesOutput struct {
+	Templates []ListTemplatesItemOutput `json:"templates"`
+}
+
+type ListTemplatesUseCase struct {
+	PromptTemplateGateway gateway.PromptTemplateGateway
+}
+
+func NewListTemplatesUseCase(promptTemplateGateway gateway.PromptTemplateGateway) *ListTemplatesUseCase {
+	return &ListTemplatesUseCase{
+		PromptTemplateGateway: promptTemplateGateway,
+	}
+}
+
+// Execute lists the latest version of every template, without their content.
+func (uc *ListTemplatesUseCase) Execute(ctx context.Context) (*ListTemplatesOutput, error) {
+	templates, err := uc.PromptTemplateGateway.FindAll(ctx)
+	if err != nil {
+		return nil, errors.New("error fetching templates: " + err.Error())
+	}
+
+	output := &ListTemplatesOutput{
+		Templates: make([]ListTemplatesItemOutput, 0, len(templates)),
+	}
+	for _, template := range templates {
+		output.Templates = append(output.Templates, ListTemplatesItemOutput{
+			TemplateID:  template.ID,
+			Name:        template.Name,
+			Version:     template.Version,
+			Description: template.Description,
+			Variables:   template.Variables(),
+			CreatedAt:   template.CreatedAt,
+		})
+	}
+	return output, nil
+}
@@ -0,0 +1,136 @@
+package saveassistant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+var ErrAssistantNotFound = errors.New("assistant not found")
+
+// SaveAssistantInput holds every setting of the assistant, an update
+// replaces all of them. N defaults to 1, a zero temperature, top_p or
+// max_tokens leaves the provider default.
+type SaveAssistantInput struct {
+	AssistantID      string          `json:"-"` // empty creates a new assistant
+	Name             string          `json:"name"`
+	Description      string          `json:"description"`
+	SystemMessage    string          `json:"system_message"`
+	Model            string          `json:"model"`
+	Temperature      float32         `json:"temperature"`
+	TopP             float32         `json:"top_p"`
+	N                int             `json:"n"`
+	Stop             []string        `json:"stop"`
+	MaxTokens        int             `json:"max_tokens"`
+	PresencePenalty  float32         `json:"presence_penalty"`
+	FrequencyPenalty float32         `json:"frequency_penalty"`
+	ContextStrategy  string          `json:"context_strategy"`
+	Tools            []string        `json:"tools"`
+	ResponseSchema   json.RawMessage `json:"response_schema"`
+}
+
+type SaveAssistantOutput struct {
+	AssistantID string    `json:"assistant_id"`
+	Name        string    `json:"name"`
+	Model       string    `json:"model"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type SaveAssistantUseCase struct {
+	AssistantGateway gateway.AssistantGateway
+	ModelGateway     gateway.ModelGateway
+	ToolGateway      gateway.ToolGateway
+}
+
+func NewSaveAssistantUseCase(assistantGateway gateway.AssistantGateway, modelGateway gateway.ModelGateway, toolGateway gateway.ToolGateway) *SaveAssistantUseCase {
+	return &SaveAssistantUseCase{
+		AssistantGateway: assistantGateway,
+		ModelGateway:     modelGateway,
+		ToolGateway:      toolGateway,
+	}
+}
+
+func (uc *SaveAssistantUseCase) Execute(ctx context.Context, input SaveAssistantInput) (*SaveAssistantOutput, error) {
+	configuration, err := uc.configuration(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var assistant *entity.Assistant
+	if input.AssistantID == "" {
+		assistant, err = entity.NewAssistant(input.Name, input.Description, input.SystemMessage, configuration)
+		if err != nil {
+			return nil, err
+		}
+		err = uc.AssistantGateway.Create(ctx, assistant)
+		if err != nil {
+			return nil, errors.New("error persisting assistant: " + err.Error())
+		}
+	} else {
+		assistant, err = uc.AssistantGateway.FindByID(ctx, input.AssistantID)
+		if err != nil {
+			if err.Error() == "assistant not found" {
+				return nil, ErrAssistantNotFound
+			}
+			return nil, errors.New("error fetching assistant: " + err.Error())
+		}
+		err = assistant.Update(input.Name, input.Description, input.SystemMessage, configuration)
+		if err != nil {
+			return nil, err
+		}
+		err = uc.AssistantGateway.Save(ctx, assistant)
+		if err != nil {
+			return nil, errors.New("error persisting assistant: " + err.Error())
+		}
+	}
+
+	return &SaveAssistantOutput{
+		AssistantID: assistant.ID,
+		Name:        assistant.Name,
+		Model:       assistant.Configuration.Model.GetName(),
+		CreatedAt:   assistant.CreatedAt,
+		UpdatedAt:   assistant.UpdatedAt,
+	}, nil
+}
+
+// configuration resolves the model and checks the tools, the other settings
+// are checked by the assistant.
+func (uc *SaveAssistantUseCase) configuration(ctx context.Context, input SaveAssistantInput) (*entity.ChatConfiguration, error) {
+	verr := &entity.ValidationError{Entity: "assistant"}
+	model, err := uc.ModelGateway.FindByName(ctx, input.Model)
+	if err != nil {
+		verr.Add("model", fmt.Sprintf("unknown model %q", input.Model))
+	}
+	for _, name := range input.Tools {
+		if _, err := uc.ToolGateway.FindByName(ctx, name); err != nil {
+			verr.Add("tools", fmt.Sprintf("unknown tool %q", name))
+		}
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	n := input.N
+	if n == 0 {
+		n = 1
+	}
+	return &entity.ChatConfiguration{
+		Model:            model,
+		Temperature:      input.Temperature,
+		TopP:             input.TopP,
+		N:                n,
+		Stop:             input.Stop,
+		MaxTokens:        input.MaxTokens,
+		PresencePenalty:  input.PresencePenalty,
+		FrequencyPenalty: input.FrequencyPenalty,
+		ContextStrategy:  input.ContextStrategy,
+		Tools:            input.Tools,
+		ResponseSchema:   input.ResponseSchema,
+	}, nil
+}
@@ -0,0 +1,66 @@
+package savetemplate
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+type SaveTemplateInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Content     string `json:"content"`
+}
+
+type SaveTemplateOutput struct {
+	TemplateID  string    `json:"template_id"`
+	Name        string    `json:"name"`
+	Version     int       `json:"version"`
+	Description string    `json:"description"`
+	Variables   []string  `json:"variables"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type SaveTemplateUseCase struct {
+	PromptTemplateGateway gateway.PromptTemplateGateway
+}
+
+func NewSaveTemplateUseCase(promptTemplateGateway gateway.PromptTemplateGateway) *SaveTemplateUseCase {
+	return &SaveTemplateUseCase{
+		PromptTemplateGateway: promptTemplateGateway,
+	}
+}
+
+// Execute stores the template as the version after the latest one under the
+// same name, the first save of a name creates version 1. Earlier versions
+// stay available to the chats that ask for them.
+func (uc *SaveTemplateUseCase) Execute(ctx context.Context, input SaveTemplateInput) (*SaveTemplateOutput, error) {
+	version := 1
+	latest, err := uc.PromptTemplateGateway.FindByName(ctx, input.Name)
+	if err == nil {
+		version = latest.Version + 1
+	} else if err.Error() != "prompt template not found" {
+		return nil, errors.New("error fetching template: " + err.Error())
+	}
+
+	template, err := entity.NewPromptTemplate(input.Name, input.Description, input.Content, version)
+	if err != nil {
+		return nil, err
+	}
+	err = uc.PromptTemplateGateway.Create(ctx, template)
+	if err != nil {
+		return nil, errors.New("error persisting template: " + err.Error())
+	}
+
+	return &SaveTemplateOutput{
+		TemplateID:  template.ID,
+		Name:        template.Name,
+		Version:     template.Version,
+		Description: template.Description,
+		Variables:   template.Variables(),
+		CreatedAt:   template.CreatedAt,
+	}, nil
+}
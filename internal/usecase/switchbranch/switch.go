@@ -0,0 +1,97 @@
+package switchbranch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+var ErrChatNotFound = errors.New("chat not found")
+
+type SwitchBranchInput struct {
+	ChatID    string `json:"chat_id"`
+	UserID    string `json:"user_id"`
+	MessageID string `json:"message_id"`
+}
+
+type SwitchBranchOutput struct {
+	ChatID       string `json:"chat_id"`
+	ActiveLeafID string `json:"active_leaf_id"`
+}
+
+type SwitchBranchUseCase struct {
+	ChatGateway    gateway.ChatGateway
+	ModelGateway   gateway.ModelGateway
+	SummaryGateway gateway.SummaryGateway
+}
+
+func NewSwitchBranchUseCase(chatGateway gateway.ChatGateway, modelGateway gateway.ModelGateway, summaryGateway gateway.SummaryGateway) *SwitchBranchUseCase {
+	return &SwitchBranchUseCase{
+		ChatGateway:    chatGateway,
+		ModelGateway:   modelGateway,
+		SummaryGateway: summaryGateway,
+	}
+}
+
+func (uc *SwitchBranchUseCase) Execute(ctx context.Context, input SwitchBranchInput) (*SwitchBranchOutput, error) {
+	verr := &entity.ValidationError{Entity: "branch"}
+	if input.ChatID == "" {
+		verr.Add("chat_id", "is empty")
+	}
+	if input.MessageID == "" {
+		verr.Add("message_id", "is empty")
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	chat, err := uc.ChatGateway.FindByID(ctx, input.ChatID)
+	if err != nil {
+		if err.Error() == "chat not found" {
+			return nil, ErrChatNotFound
+		}
+		return nil, errors.New("error fetching chat: " + err.Error())
+	}
+	// other users chats are reported as missing so their ids do not leak
+	if chat.UserID != input.UserID {
+		return nil, ErrChatNotFound
+	}
+	if model, err := uc.ModelGateway.FindByName(ctx, chat.Configuration.Model.GetName()); err == nil {
+		chat.Configuration.Model = model
+	}
+
+	err = chat.SwitchBranch(input.MessageID)
+	if err != nil {
+		return nil, fmt.Errorf("error switching branch: %w", err)
+	}
+	uc.summarize(ctx, chat)
+
+	err = uc.ChatGateway.Save(ctx, chat)
+	if err != nil {
+		return nil, errors.New("error saving chat: " + err.Error())
+	}
+	return &SwitchBranchOutput{
+		ChatID:       chat.ID,
+		ActiveLeafID: chat.ActiveLeafID,
+	}, nil
+}
+
+// summarize rebuilds the summary of a summarizing context strategy from the
+// messages the new branch erased. A failed summary only loses the context of
+// those messages, so it does not fail the switch.
+func (uc *SwitchBranchUseCase) summarize(ctx context.Context, chat *entity.Chat) {
+	if len(chat.PendingSummary) == 0 {
+		return
+	}
+
+	summary, err := uc.SummaryGateway.Summarize(ctx, chat.Configuration.Model, chat.Summary, chat.PendingSummary)
+	if err != nil {
+		log.Println("error summarizing chat " + chat.ID + ": " + err.Error())
+		return
+	}
+	chat.SetSummary(summary)
+}
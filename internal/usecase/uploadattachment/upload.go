@@ -0,0 +1,88 @@
+package uploadattachment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gabrielmq/chat-service/internal/domain/entity"
+	"github.com/gabrielmq/chat-service/internal/domain/gateway"
+)
+
+var ErrChatNotFound = errors.New("chat not found")
+
+type UploadAttachmentInput struct {
+	ChatID  string
+	UserID  string
+	Name    string
+	Content []byte
+}
+
+type UploadAttachmentOutput struct {
+	AttachmentID string    `json:"attachment_id"`
+	ChatID       string    `json:"chat_id"`
+	Name         string    `json:"name"`
+	ContentType  string    `json:"content_type"` // detected from the content
+	Size         int64     `json:"size"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type UploadAttachmentUseCase struct {
+	ChatGateway              gateway.ChatGateway
+	AttachmentGateway        gateway.AttachmentGateway
+	AttachmentStorageGateway gateway.AttachmentStorageGateway
+}
+
+func NewUploadAttachmentUseCase(chatGateway gateway.ChatGateway, attachmentGateway gateway.AttachmentGateway, attachmentStorageGateway gateway.AttachmentStorageGateway) *UploadAttachmentUseCase {
+	return &UploadAttachmentUseCase{
+		ChatGateway:              chatGateway,
+		AttachmentGateway:        attachmentGateway,
+		AttachmentStorageGateway: attachmentStorageGateway,
+	}
+}
+
+func (uc *UploadAttachmentUseCase) Execute(ctx context.Context, input UploadAttachmentInput) (*UploadAttachmentOutput, error) {
+	if input.ChatID == "" {
+		return nil, errors.New("chat id is empty")
+	}
+
+	chat, err := uc.ChatGateway.FindByID(ctx, input.ChatID)
+	if err != nil {
+		if err.Error() == "chat not found" {
+			return nil, ErrChatNotFound
+		}
+		return nil, errors.New("error fetching chat: " + err.Error())
+	}
+	// other users chats are reported as missing so their ids do not leak
+	if chat.UserID != input.UserID {
+		return nil, ErrChatNotFound
+	}
+
+	attachment, err := entity.NewAttachment(chat.ID, input.UserID, input.Name, input.Content)
+	if err != nil {
+		return nil, fmt.Errorf("error creating attachment: %w", err)
+	}
+	err = uc.AttachmentStorageGateway.Put(ctx, attachment.StorageKey, attachment.ContentType, input.Content)
+	if err != nil {
+		return nil, errors.New("error storing attachment: " + err.Error())
+	}
+	err = uc.AttachmentGateway.Create(ctx, attachment)
+	if err != nil {
+		// the content is unreachable without its metadata
+		if deleteErr := uc.AttachmentStorageGateway.Delete(ctx, attachment.StorageKey); deleteErr != nil {
+			log.Println("error removing attachment " + attachment.ID + ": " + deleteErr.Error())
+		}
+		return nil, errors.New("error persisting attachment: " + err.Error())
+	}
+
+	return &UploadAttachmentOutput{
+		AttachmentID: attachment.ID,
+		ChatID:       attachment.ChatID,
+		Name:         attachment.Name,
+		ContentType:  attachment.ContentType,
+		Size:         attachment.Size,
+		CreatedAt:    attachment.CreatedAt,
+	}, nil
+}
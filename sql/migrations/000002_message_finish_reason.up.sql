@@ -0,0 +1 @@
+ALTER TABLE `messages` ADD COLUMN finish_reason VARCHAR(20) NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE `chats` MODIFY stop VARCHAR(20) NOT NULL;
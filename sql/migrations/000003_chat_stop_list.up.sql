@@ -0,0 +1 @@
+ALTER TABLE `chats` MODIFY stop VARCHAR(255) NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE `chats` ADD COLUMN cost DECIMAL(12,6) NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+START TRANSACTION;
+ALTER TABLE `messages` DROP COLUMN pinned;
+ALTER TABLE `chats` DROP COLUMN summary;
+ALTER TABLE `chats` DROP COLUMN context_strategy;
+COMMIT;
@@ -0,0 +1,5 @@
+START TRANSACTION;
+ALTER TABLE `chats` ADD COLUMN context_strategy VARCHAR(20) NOT NULL DEFAULT 'sliding_window';
+ALTER TABLE `chats` ADD COLUMN summary TEXT NOT NULL;
+ALTER TABLE `messages` ADD COLUMN pinned BOOLEAN NOT NULL DEFAULT 0;
+COMMIT;
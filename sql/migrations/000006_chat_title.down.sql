@@ -0,0 +1,3 @@
+START TRANSACTION;
+ALTER TABLE `chats` DROP COLUMN title;
+COMMIT;
@@ -0,0 +1,3 @@
+START TRANSACTION;
+ALTER TABLE `chats` ADD COLUMN title VARCHAR(255) NOT NULL DEFAULT '';
+COMMIT;
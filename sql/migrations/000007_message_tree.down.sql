@@ -0,0 +1,16 @@
+START TRANSACTION;
+ALTER TABLE `messages` ADD COLUMN order_msg SMALLINT NOT NULL DEFAULT 0;
+
+-- best effort: the messages of every branch are flattened back in creation
+-- order
+UPDATE `messages` m
+JOIN (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY chat_id, erased ORDER BY created_at) - 1 AS order_msg
+    FROM `messages`
+) ordered ON ordered.id = m.id
+SET m.order_msg = ordered.order_msg;
+
+ALTER TABLE `chats` DROP COLUMN active_leaf_id;
+ALTER TABLE `messages` DROP COLUMN branch;
+ALTER TABLE `messages` DROP COLUMN parent_id;
+COMMIT;
@@ -0,0 +1,28 @@
+START TRANSACTION;
+ALTER TABLE `messages` ADD COLUMN parent_id VARCHAR(36) NOT NULL DEFAULT '';
+ALTER TABLE `messages` ADD COLUMN branch SMALLINT NOT NULL DEFAULT 0;
+ALTER TABLE `chats` ADD COLUMN active_leaf_id VARCHAR(36) NOT NULL DEFAULT '';
+
+-- link every message of the flat history to the one before it: the initial
+-- system message first, then the erased messages and the context window
+UPDATE `messages` m
+JOIN (
+    SELECT msg.id, LAG(msg.id) OVER (
+        PARTITION BY msg.chat_id
+        ORDER BY msg.id <> c.initial_message_id, msg.erased DESC, msg.order_msg
+    ) AS parent_id
+    FROM `messages` msg
+    JOIN `chats` c ON c.id = msg.chat_id
+) linked ON linked.id = m.id
+SET m.parent_id = COALESCE(linked.parent_id, '');
+
+UPDATE `chats` c
+SET c.active_leaf_id = (
+    SELECT m.id FROM `messages` m
+    WHERE m.chat_id = c.id AND m.erased = 0
+    ORDER BY m.order_msg DESC
+    LIMIT 1
+);
+
+ALTER TABLE `messages` DROP COLUMN order_msg;
+COMMIT;
@@ -0,0 +1,28 @@
+START TRANSACTION;
+-- older versions know no tool steps: the messages after a tool call or a
+-- tool result follow the nearest message before them that is neither
+UPDATE `messages` m
+JOIN (
+    WITH RECURSIVE up (id, parent_id) AS (
+        SELECT id, parent_id FROM `messages`
+        UNION ALL
+        SELECT up.id, step.parent_id FROM up
+        JOIN `messages` step ON step.id = up.parent_id
+        WHERE step.role = 'tool' OR step.tool_calls <> ''
+    )
+    SELECT up.id, up.parent_id FROM up
+    JOIN `messages` parent ON parent.id = up.parent_id
+    WHERE parent.role <> 'tool' AND parent.tool_calls = ''
+) linked ON linked.id = m.id
+SET m.parent_id = linked.parent_id;
+
+UPDATE `chats` c
+JOIN `messages` m ON m.id = c.active_leaf_id
+SET c.active_leaf_id = m.parent_id
+WHERE m.role = 'tool' OR m.tool_calls <> '';
+
+DELETE FROM `messages` WHERE role = 'tool' OR tool_calls <> '';
+ALTER TABLE `messages` DROP COLUMN tool_call_id;
+ALTER TABLE `messages` DROP COLUMN tool_calls;
+ALTER TABLE `chats` DROP COLUMN tools;
+COMMIT;
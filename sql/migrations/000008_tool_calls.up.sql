@@ -0,0 +1,5 @@
+START TRANSACTION;
+ALTER TABLE `chats` ADD COLUMN tools VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE `messages` ADD COLUMN tool_calls TEXT NOT NULL;
+ALTER TABLE `messages` ADD COLUMN tool_call_id VARCHAR(64) NOT NULL DEFAULT '';
+COMMIT;
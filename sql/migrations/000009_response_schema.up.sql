@@ -0,0 +1,3 @@
+START TRANSACTION;
+ALTER TABLE `chats` ADD COLUMN response_schema TEXT NOT NULL;
+COMMIT;
@@ -0,0 +1,4 @@
+START TRANSACTION;
+DROP TABLE IF EXISTS `document_chunks`;
+DROP TABLE IF EXISTS `documents`;
+COMMIT;
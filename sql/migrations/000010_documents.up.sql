@@ -0,0 +1,23 @@
+START TRANSACTION;
+CREATE TABLE IF NOT EXISTS `documents` (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    chat_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    chunks INT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    INDEX (chat_id),
+    FOREIGN KEY (chat_id) REFERENCES chats (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS `document_chunks` (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    document_id VARCHAR(36) NOT NULL,
+    chat_id VARCHAR(36) NOT NULL,
+    position INT NOT NULL,
+    content TEXT NOT NULL,
+    embedding MEDIUMBLOB NOT NULL,
+    INDEX (chat_id),
+    FOREIGN KEY (document_id) REFERENCES documents (id) ON DELETE CASCADE
+);
+COMMIT;
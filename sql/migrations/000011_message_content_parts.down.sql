@@ -0,0 +1,3 @@
+START TRANSACTION;
+ALTER TABLE `messages` DROP COLUMN content_parts;
+COMMIT;
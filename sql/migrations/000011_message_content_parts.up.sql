@@ -0,0 +1,3 @@
+START TRANSACTION;
+ALTER TABLE `messages` ADD COLUMN content_parts MEDIUMTEXT NOT NULL;
+COMMIT;
@@ -0,0 +1,3 @@
+START TRANSACTION;
+DROP TABLE IF EXISTS `attachments`;
+COMMIT;
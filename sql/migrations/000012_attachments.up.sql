@@ -0,0 +1,14 @@
+START TRANSACTION;
+CREATE TABLE IF NOT EXISTS `attachments` (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    chat_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    INDEX (chat_id),
+    FOREIGN KEY (chat_id) REFERENCES chats (id) ON DELETE CASCADE
+);
+COMMIT;
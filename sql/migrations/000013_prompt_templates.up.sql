@@ -0,0 +1,11 @@
+START TRANSACTION;
+CREATE TABLE IF NOT EXISTS `prompt_templates` (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    version INT NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (name, version)
+);
+COMMIT;
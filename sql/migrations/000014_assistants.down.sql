@@ -0,0 +1,4 @@
+START TRANSACTION;
+ALTER TABLE `chats` DROP COLUMN assistant_id;
+DROP TABLE IF EXISTS `assistants`;
+COMMIT;
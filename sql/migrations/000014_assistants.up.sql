@@ -0,0 +1,23 @@
+START TRANSACTION;
+CREATE TABLE IF NOT EXISTS `assistants` (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    system_message TEXT NOT NULL,
+    model VARCHAR(64) NOT NULL,
+    temperature DECIMAL(3,2) NOT NULL,
+    top_p DECIMAL(3,2) NOT NULL,
+    n SMALLINT NOT NULL,
+    stop VARCHAR(255) NOT NULL,
+    max_tokens INT NOT NULL,
+    presence_penalty DECIMAL(3,2) NOT NULL,
+    frequency_penalty DECIMAL(3,2) NOT NULL,
+    context_strategy VARCHAR(20) NOT NULL,
+    tools VARCHAR(255) NOT NULL,
+    response_schema TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+ALTER TABLE `chats` ADD COLUMN assistant_id VARCHAR(36) NOT NULL DEFAULT '';
+COMMIT;
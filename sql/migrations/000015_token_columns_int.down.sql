@@ -0,0 +1,6 @@
+START TRANSACTION;
+ALTER TABLE `messages` MODIFY tokens SMALLINT NOT NULL;
+ALTER TABLE `chats` MODIFY max_tokens SMALLINT NOT NULL;
+ALTER TABLE `chats` MODIFY model_max_tokens SMALLINT NOT NULL;
+ALTER TABLE `chats` MODIFY token_usage SMALLINT NOT NULL;
+COMMIT;
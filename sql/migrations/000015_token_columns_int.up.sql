@@ -0,0 +1,6 @@
+START TRANSACTION;
+ALTER TABLE `chats` MODIFY token_usage INT NOT NULL;
+ALTER TABLE `chats` MODIFY model_max_tokens INT NOT NULL;
+ALTER TABLE `chats` MODIFY max_tokens INT NOT NULL;
+ALTER TABLE `messages` MODIFY tokens INT NOT NULL;
+COMMIT;
@@ -0,0 +1,5 @@
+START TRANSACTION;
+-- the chats created before keep the retrieval of the shipped configuration
+ALTER TABLE `chats` ADD COLUMN retrieval_top_k INT NOT NULL DEFAULT 4;
+ALTER TABLE `chats` ALTER COLUMN retrieval_top_k SET DEFAULT 0;
+COMMIT;
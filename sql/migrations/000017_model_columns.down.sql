@@ -0,0 +1,4 @@
+START TRANSACTION;
+ALTER TABLE `messages` MODIFY model VARCHAR(20) NOT NULL;
+ALTER TABLE `chats` MODIFY model VARCHAR(20) NOT NULL;
+COMMIT;
@@ -0,0 +1,4 @@
+START TRANSACTION;
+ALTER TABLE `chats` MODIFY model VARCHAR(64) NOT NULL;
+ALTER TABLE `messages` MODIFY model VARCHAR(64) NOT NULL;
+COMMIT;
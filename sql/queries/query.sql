@@ -1,27 +1,97 @@
 -- name: Create :exec
 INSERT INTO chats
-(id,user_id,initial_message_id,status,token_usage,model,model_max_tokens,temperature,top_p,n,stop,max_tokens,presence_penalty,frequency_penalty,created_at,updated_at)
-VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?);
+(id,user_id,initial_message_id,status,token_usage,model,model_max_tokens,temperature,top_p,n,stop,max_tokens,presence_penalty,frequency_penalty,created_at,updated_at,cost,context_strategy,summary,active_leaf_id,tools,response_schema,assistant_id,retrieval_top_k)
+VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?);
 
 -- name: AddMessage :exec
 INSERT INTO messages
-(id,chat_id,role,content,tokens,model,erased,order_msg,created_at)
-VALUES(?,?,?,?,?,?,?,?,?);
+(id,chat_id,role,content,tokens,model,erased,created_at,finish_reason,pinned,parent_id,branch,tool_calls,tool_call_id,content_parts)
+VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?);
 
 -- name: FindByID :one
 SELECT * FROM chats WHERE id = ?;
 
--- name: FindMessagesByChatID :many
-SELECT * FROM messages WHERE erased=0 and chat_id = ? order by order_msg asc;
+-- name: FindByUserID :many
+SELECT * FROM chats WHERE user_id = ? order by updated_at desc;
+
+-- name: UpdateTitle :exec
+UPDATE chats SET title = ? WHERE id = ?;
 
--- name: FindErasedMessagesByChatID :many
-SELECT * FROM messages WHERE erased=1 and chat_id = ? order by order_msg asc;
+-- name: UpdateSummary :exec
+UPDATE chats SET summary = ? WHERE id = ?;
+
+-- name: FindMessagesByChatID :many
+SELECT * FROM messages WHERE chat_id = ? order by created_at asc;
 
 -- name: Save :exec
-UPDATE chats SET user_id = ?, initial_message_id = ?, status = ?, token_usage = ?, model = ?, model_max_tokens=?, temperature = ?, top_p = ?, n = ?, stop = ?, max_tokens = ?, presence_penalty = ?, frequency_penalty = ?, updated_at = ? WHERE id = ?;
+UPDATE chats SET user_id = ?, initial_message_id = ?, status = ?, token_usage = ?, model = ?, model_max_tokens=?, temperature = ?, top_p = ?, n = ?, stop = ?, max_tokens = ?, presence_penalty = ?, frequency_penalty = ?, cost = ?, context_strategy = ?, active_leaf_id = ?, tools = ?, response_schema = ?, updated_at = ? WHERE id = ?;
 
 -- name: DeleteChatMessages :exec
 DELETE FROM messages WHERE chat_id = ?;
 
 -- name: DeleteErasedChatMessages :exec
-DELETE FROM messages WHERE erased=1 and chat_id = ?;
\ No newline at end of file
+DELETE FROM messages WHERE erased=1 and chat_id = ?;
+
+-- name: CreateAttachment :exec
+INSERT INTO attachments
+(id,chat_id,user_id,name,content_type,size,storage_key,created_at)
+VALUES(?,?,?,?,?,?,?,?);
+
+-- name: FindAttachmentByID :one
+SELECT * FROM attachments WHERE id = ?;
+
+-- name: CreateDocument :exec
+INSERT INTO documents
+(id,chat_id,user_id,name,chunks,created_at)
+VALUES(?,?,?,?,?,?);
+
+-- name: AddDocumentChunk :exec
+INSERT INTO document_chunks
+(id,document_id,chat_id,position,content,embedding)
+VALUES(?,?,?,?,?,?);
+
+-- name: FindDocumentsByChatID :many
+SELECT * FROM documents WHERE chat_id = ? order by created_at asc;
+
+-- name: FindDocumentChunksByChatID :many
+SELECT document_chunks.id, document_chunks.document_id, documents.name, document_chunks.position, document_chunks.content, document_chunks.embedding
+FROM document_chunks JOIN documents ON documents.id = document_chunks.document_id
+WHERE document_chunks.chat_id = ? order by documents.created_at asc, document_chunks.position asc;
+
+-- name: CreatePromptTemplate :exec
+INSERT INTO prompt_templates
+(id,name,version,description,content,created_at)
+VALUES(?,?,?,?,?,?);
+
+-- name: FindLatestPromptTemplate :one
+SELECT * FROM prompt_templates WHERE name = ? order by version desc limit 1;
+
+-- name: FindPromptTemplateByVersion :one
+SELECT * FROM prompt_templates WHERE name = ? AND version = ?;
+
+-- name: ListLatestPromptTemplates :many
+SELECT prompt_templates.* FROM prompt_templates
+JOIN (SELECT name, MAX(version) AS version FROM prompt_templates GROUP BY name) latest
+ON latest.name = prompt_templates.name AND latest.version = prompt_templates.version
+order by prompt_templates.name asc;
+
+-- name: DeletePromptTemplates :execrows
+DELETE FROM prompt_templates WHERE name = ?;
+
+-- name: CreateAssistant :exec
+INSERT INTO assistants
+(id,name,description,system_message,model,temperature,top_p,n,stop,max_tokens,presence_penalty,frequency_penalty,context_strategy,tools,response_schema,created_at,updated_at)
+VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?);
+
+-- name: UpdateAssistant :exec
+UPDATE assistants SET name = ?, description = ?, system_message = ?, model = ?, temperature = ?, top_p = ?, n = ?, stop = ?, max_tokens = ?, presence_penalty = ?, frequency_penalty = ?, context_strategy = ?, tools = ?, response_schema = ?, updated_at = ?
+WHERE id = ?;
+
+-- name: FindAssistantByID :one
+SELECT * FROM assistants WHERE id = ?;
+
+-- name: ListAssistants :many
+SELECT * FROM assistants order by name asc;
+
+-- name: DeleteAssistant :execrows
+DELETE FROM assistants WHERE id = ?;